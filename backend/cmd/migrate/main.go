@@ -26,14 +26,8 @@ func main() {
 	}
 	defer db.Close()
 
-	// Read migration file
-	migrationSQL, err := os.ReadFile("migrations/001_initial_schema.sql")
-	if err != nil {
-		log.Fatalf("Failed to read migration file: %v", err)
-	}
-
 	// Run migrations
-	if err := db.RunMigrations(string(migrationSQL)); err != nil {
+	if err := db.RunMigrationsDir("migrations"); err != nil {
 		log.Fatalf("Migration failed: %v", err)
 	}
 
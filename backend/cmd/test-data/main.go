@@ -7,11 +7,14 @@ import (
 	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/minsix/backend/internal/address"
 	"github.com/minsix/backend/internal/database"
 	"github.com/minsix/backend/internal/detector"
 	"github.com/minsix/backend/internal/models"
 )
 
+const testChainID = 1
+
 func main() {
 	// Load environment variables
 	if err := godotenv.Load(); err != nil {
@@ -30,8 +33,9 @@ func main() {
 	}
 	defer db.Close()
 
-	// Initialize fraud detector
+	// Initialize fraud detector; test data is recorded as mainnet
 	fraudDetector := detector.NewFraudDetector(db)
+	fraudDetector.SetChainID(testChainID)
 
 	log.Println("Creating test transactions...")
 
@@ -39,8 +43,8 @@ func main() {
 	createTestTransaction(db, fraudDetector, &models.Transaction{
 		TxHash:      "0xtest_large_transfer_123",
 		BlockNumber: 18000000,
-		FromAddress: "0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb",
-		ToAddress:   stringPtr("0x1234567890123456789012345678901234567890"),
+		FromAddress: address.FromHex("0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb"),
+		ToAddress:   address.FromHex("0x1234567890123456789012345678901234567890").Ptr(),
 		Value:       "50000000000000000000", // 50 ETH
 		GasPrice:    "30000000000",
 		GasUsed:     21000,
@@ -51,8 +55,8 @@ func main() {
 	createTestTransaction(db, fraudDetector, &models.Transaction{
 		TxHash:      "0xtest_null_address_456",
 		BlockNumber: 18000001,
-		FromAddress: "0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb",
-		ToAddress:   stringPtr("0x0000000000000000000000000000000000000000"),
+		FromAddress: address.FromHex("0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb"),
+		ToAddress:   address.FromHex("0x0000000000000000000000000000000000000000").Ptr(),
 		Value:       "5000000000000000000", // 5 ETH
 		GasPrice:    "25000000000",
 		GasUsed:     21000,
@@ -63,8 +67,8 @@ func main() {
 	createTestTransaction(db, fraudDetector, &models.Transaction{
 		TxHash:      "0xtest_burn_address_789",
 		BlockNumber: 18000002,
-		FromAddress: "0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb",
-		ToAddress:   stringPtr("0x000000000000000000000000000000000000dead"),
+		FromAddress: address.FromHex("0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb"),
+		ToAddress:   address.FromHex("0x000000000000000000000000000000000000dead").Ptr(),
 		Value:       "3000000000000000000", // 3 ETH
 		GasPrice:    "28000000000",
 		GasUsed:     21000,
@@ -78,7 +82,7 @@ func main() {
 			TxHash:      fmt.Sprintf("0xtest_rapid_%d", i),
 			BlockNumber: 18000003 + int64(i),
 			FromAddress: "0xRapidSender1234567890123456789012345678",
-			ToAddress:   stringPtr("0x1234567890123456789012345678901234567890"),
+			ToAddress:   address.FromHex("0x1234567890123456789012345678901234567890").Ptr(),
 			Value:       "1000000000000000000", // 1 ETH
 			GasPrice:    "30000000000",
 			GasUsed:     21000,
@@ -90,8 +94,8 @@ func main() {
 	createTestTransaction(db, fraudDetector, &models.Transaction{
 		TxHash:      "0xtest_high_gas_abc",
 		BlockNumber: 18000010,
-		FromAddress: "0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb",
-		ToAddress:   stringPtr("0x1234567890123456789012345678901234567890"),
+		FromAddress: address.FromHex("0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb"),
+		ToAddress:   address.FromHex("0x1234567890123456789012345678901234567890").Ptr(),
 		Value:       "2000000000000000000", // 2 ETH
 		GasPrice:    "200000000000",        // Very high gas
 		GasUsed:     21000,
@@ -102,8 +106,8 @@ func main() {
 	createTestTransaction(db, fraudDetector, &models.Transaction{
 		TxHash:      "0xtest_token_transfer_def",
 		BlockNumber: 18000011,
-		FromAddress: "0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb",
-		ToAddress:   stringPtr("0xdAC17F958D2ee523a2206206994597C13D831ec7"), // USDT contract
+		FromAddress: address.FromHex("0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb"),
+		ToAddress:   address.FromHex("0xdAC17F958D2ee523a2206206994597C13D831ec7").Ptr(), // USDT contract
 		Value:       "0",
 		GasPrice:    "30000000000",
 		GasUsed:     65000,
@@ -111,12 +115,25 @@ func main() {
 		Timestamp:   time.Now(),
 	}, "Token transfer")
 
-	// Test Case 7: Normal transaction (should NOT be flagged)
+	// Test Case 7: Unlimited approval to an unknown spender (should be flagged)
 	createTestTransaction(db, fraudDetector, &models.Transaction{
-		TxHash:      "0xtest_normal_ghi",
+		TxHash:      "0xtest_unlimited_approval_jkl",
 		BlockNumber: 18000012,
-		FromAddress: "0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb",
-		ToAddress:   stringPtr("0x1234567890123456789012345678901234567890"),
+		FromAddress: address.FromHex("0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb"),
+		ToAddress:   address.FromHex("0xdAC17F958D2ee523a2206206994597C13D831ec7").Ptr(), // USDT contract
+		Value:       "0",
+		GasPrice:    "30000000000",
+		GasUsed:     46000,
+		InputData:   stringPtr("0x095ea7b30000000000000000000000001234567890123456789012345678901234567890ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"),
+		Timestamp:   time.Now(),
+	}, "Unlimited approval")
+
+	// Test Case 8: Normal transaction (should NOT be flagged)
+	createTestTransaction(db, fraudDetector, &models.Transaction{
+		TxHash:      "0xtest_normal_ghi",
+		BlockNumber: 18000013,
+		FromAddress: address.FromHex("0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb"),
+		ToAddress:   address.FromHex("0x1234567890123456789012345678901234567890").Ptr(),
 		Value:       "500000000000000000", // 0.5 ETH
 		GasPrice:    "25000000000",
 		GasUsed:     21000,
@@ -124,8 +141,8 @@ func main() {
 	}, "Normal transaction")
 
 	// Update statistics
-	db.UpdateStatistic("total_transactions", 14)
-	db.IncrementStatistic("total_flagged", 0) // Will be incremented by flagged txs
+	db.UpdateStatistic(testChainID, "total_transactions", 15)
+	db.IncrementStatistic(testChainID, "total_flagged", 0) // Will be incremented by flagged txs
 
 	log.Println("Test data created successfully")
 	log.Println("")
@@ -136,6 +153,8 @@ func main() {
 }
 
 func createTestTransaction(db *database.DB, detector *detector.FraudDetector, tx *models.Transaction, description string) {
+	tx.ChainID = testChainID
+
 	// Save transaction
 	if err := db.SaveTransaction(tx); err != nil {
 		log.Printf("ERROR: Failed to save %s: %v", description, err)
@@ -0,0 +1,127 @@
+package address
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	// ErrInvalid is returned for strings that aren't 20-byte hex addresses
+	ErrInvalid = errors.New("invalid address")
+
+	// ErrChecksum is returned for mixed-case addresses whose EIP-55 checksum
+	// doesn't match, which are likely mistyped
+	ErrChecksum = errors.New("invalid address checksum")
+)
+
+// Address is an account or contract address in canonical form: 0x followed
+// by 40 lowercase hex digits. Addresses are stored, compared and served in
+// this form so lookups match whatever casing a node, feed or user supplied.
+type Address string
+
+// Parse validates an address from an untrusted source such as an API request
+// or a feed and returns its canonical form. The 0x prefix is required;
+// all-lowercase and all-uppercase addresses are accepted as is, mixed-case
+// ones must carry a valid EIP-55 checksum.
+func Parse(s string) (Address, error) {
+	s = strings.TrimSpace(s)
+	if len(s) != 42 || (!strings.HasPrefix(s, "0x") && !strings.HasPrefix(s, "0X")) || !common.IsHexAddress(s) {
+		return "", fmt.Errorf("%w %q", ErrInvalid, s)
+	}
+	digits := s[2:]
+	mixedCase := digits != strings.ToLower(digits) && digits != strings.ToUpper(digits)
+	if mixedCase && digits != common.HexToAddress(s).Hex()[2:] {
+		return "", fmt.Errorf("%w %q", ErrChecksum, s)
+	}
+	return Address("0x" + strings.ToLower(digits)), nil
+}
+
+// MustParse is Parse for addresses known to be valid, such as constants
+func MustParse(s string) Address {
+	a, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+// FromCommon returns the canonical form of a go-ethereum address
+func FromCommon(a common.Address) Address {
+	return Address(strings.ToLower(a.Hex()))
+}
+
+// Normalize returns the canonical form of an address from a trusted source
+// such as a node or a stored row without validating it
+func Normalize(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+// FromHex is Normalize returning an Address, for trusted sources such as
+// decoded calldata and event topics
+func FromHex(s string) Address {
+	return Address(Normalize(s))
+}
+
+// Of returns a pointer to the canonical form of a trusted address, or nil
+// for nil, for optional fields
+func Of(s *string) *Address {
+	if s == nil {
+		return nil
+	}
+	a := FromHex(*s)
+	return &a
+}
+
+// String returns the canonical form
+func (a Address) String() string {
+	return string(a)
+}
+
+// Checksum returns the EIP-55 mixed-case form, for display
+func (a Address) Checksum() string {
+	return common.HexToAddress(string(a)).Hex()
+}
+
+// Common converts the address for use with go-ethereum
+func (a Address) Common() common.Address {
+	return common.HexToAddress(string(a))
+}
+
+// Ptr returns a pointer to a copy of the address, for optional fields
+func (a Address) Ptr() *Address {
+	return &a
+}
+
+// Scan reads an address column, normalizing it, so rows stored before
+// addresses were canonical still compare equal
+func (a *Address) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case string:
+		*a = FromHex(v)
+	case []byte:
+		*a = FromHex(string(v))
+	default:
+		return fmt.Errorf("cannot scan %T into an address", src)
+	}
+	return nil
+}
+
+// Value stores the address in canonical form
+func (a Address) Value() (driver.Value, error) {
+	return Normalize(string(a)), nil
+}
+
+// UnmarshalText parses the address, so malformed addresses in JSON and YAML
+// documents are rejected while decoding
+func (a *Address) UnmarshalText(text []byte) error {
+	parsed, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
@@ -0,0 +1,64 @@
+package address
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestParse(t *testing.T) {
+	const canonical = "0x098b716b8aaf21512996dc57eb0615e2383e2f96"
+	tests := []struct {
+		input string
+		want  Address
+		err   error
+	}{
+		{"0x098b716b8aaf21512996dc57eb0615e2383e2f96", canonical, nil},
+		{"0x098B716B8AAF21512996DC57EB0615E2383E2F96", canonical, nil},
+		{"0X098B716B8AAF21512996DC57EB0615E2383E2F96", canonical, nil},
+		{"0x098B716B8Aaf21512996dC57EB0615e2383E2f96", canonical, nil},
+		{" 0x098b716b8aaf21512996dc57eb0615e2383e2f96\n", canonical, nil},
+		{"0x098B716B8Aaf21512996dC57EB0615e2383E2F96", "", ErrChecksum},
+		{"098b716b8aaf21512996dc57eb0615e2383e2f96", "", ErrInvalid},
+		{"0x098b716b8aaf21512996dc57eb0615e2383e2f", "", ErrInvalid},
+		{"0x098b716b8aaf21512996dc57eb0615e2383e2fzz", "", ErrInvalid},
+		{"", "", ErrInvalid},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.input)
+		if !errors.Is(err, tt.err) || got != tt.want {
+			t.Errorf("Parse(%q) = %q, %v, want %q, %v", tt.input, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestConversions(t *testing.T) {
+	checksummed := "0x098B716B8Aaf21512996dC57EB0615e2383E2f96"
+	a := FromCommon(common.HexToAddress(checksummed))
+	if a != "0x098b716b8aaf21512996dc57eb0615e2383e2f96" {
+		t.Errorf("FromCommon() = %q", a)
+	}
+	if a.Checksum() != checksummed {
+		t.Errorf("Checksum() = %q, want %q", a.Checksum(), checksummed)
+	}
+	if Normalize(checksummed) != a.String() {
+		t.Errorf("Normalize() = %q", Normalize(checksummed))
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	var body struct {
+		Address Address `json:"address"`
+	}
+	if err := json.Unmarshal([]byte(`{"address": "0x098B716B8Aaf21512996dC57EB0615e2383E2f96"}`), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Address != "0x098b716b8aaf21512996dc57eb0615e2383e2f96" {
+		t.Errorf("unmarshaled %q", body.Address)
+	}
+	if err := json.Unmarshal([]byte(`{"address": "0xabc"}`), &body); !errors.Is(err, ErrInvalid) {
+		t.Errorf("unmarshal of a short address: %v", err)
+	}
+}
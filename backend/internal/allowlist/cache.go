@@ -0,0 +1,98 @@
+package allowlist
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/minsix/backend/internal/address"
+	"github.com/minsix/backend/internal/database"
+	"github.com/minsix/backend/internal/models"
+)
+
+// Source loads the allowlist; chainID 0 returns every entry. Implemented by
+// database.DB.
+type Source interface {
+	GetAllowlist(chainID int64) ([]*models.AllowlistedAddress, error)
+}
+
+// Cache keeps the allowlist in memory so the detector can check every
+// finding without querying the database. It implements
+// detector.AllowlistChecker and is safe for concurrent use.
+type Cache struct {
+	source Source
+
+	mu      sync.RWMutex
+	entries map[address.Address][]*models.AllowlistedAddress // address -> entries, replaced on reload
+}
+
+// NewCache creates an empty cache; call Load before the first lookup
+func NewCache(source Source) *Cache {
+	return &Cache{
+		source:  source,
+		entries: map[address.Address][]*models.AllowlistedAddress{},
+	}
+}
+
+// Load replaces the cached allowlist with the source's current one
+func (c *Cache) Load() error {
+	entries, err := c.source.GetAllowlist(0)
+	if err != nil {
+		return fmt.Errorf("failed to load allowlist: %w", err)
+	}
+
+	next := make(map[address.Address][]*models.AllowlistedAddress, len(entries))
+	for _, entry := range entries {
+		next[entry.Address] = append(next[entry.Address], entry)
+	}
+
+	c.mu.Lock()
+	c.entries = next
+	c.mu.Unlock()
+	return nil
+}
+
+// Match returns the entry covering an address for a rule on a chain, either
+// for that chain alone or for every chain, or nil if there is none; chainID 0
+// matches any chain. When several entries apply, the one with the lowest
+// score factor is returned.
+func (c *Cache) Match(chainID int64, addr address.Address, rule string) *models.AllowlistedAddress {
+	c.mu.RLock()
+	entries := c.entries[addr]
+	c.mu.RUnlock()
+
+	var match *models.AllowlistedAddress
+	for _, entry := range entries {
+		if chainID != 0 && entry.ChainID != nil && *entry.ChainID != chainID {
+			continue
+		}
+		if !entry.Covers(rule) {
+			continue
+		}
+		if match == nil || entry.ScoreFactor < match.ScoreFactor {
+			match = entry
+		}
+	}
+	return match
+}
+
+// Len returns the number of distinct cached addresses
+func (c *Cache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.entries)
+}
+
+// Watch reloads the allowlist whenever allowlisted_addresses changes, and
+// every database.RefreshInterval in case a notification was lost, until ctx
+// is cancelled
+func (c *Cache) Watch(ctx context.Context, notifier database.Notifier) error {
+	return database.WatchReload(ctx, notifier, database.AllowlistChannel, func() error {
+		if err := c.Load(); err != nil {
+			return err
+		}
+		log.Printf("Allowlist refreshed, %d addresses", c.Len())
+		return nil
+	})
+}
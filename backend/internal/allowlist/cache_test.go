@@ -0,0 +1,70 @@
+package allowlist
+
+import (
+	"context"
+	"testing"
+
+	"github.com/minsix/backend/internal/address"
+	"github.com/minsix/backend/internal/database"
+	"github.com/minsix/backend/internal/database/dbtest"
+	"github.com/minsix/backend/internal/models"
+)
+
+type fakeSource struct {
+	*dbtest.List[*models.AllowlistedAddress]
+}
+
+func (f fakeSource) GetAllowlist(chainID int64) ([]*models.AllowlistedAddress, error) {
+	return f.Load()
+}
+
+func TestCacheMatch(t *testing.T) {
+	cache := NewCache(fakeSource{dbtest.NewList(
+		&models.AllowlistedAddress{ID: 1, Address: "0xabc0000000000000000000000000000000000001", ScoreFactor: 0.5},
+		&models.AllowlistedAddress{ID: 2, Address: "0xabc0000000000000000000000000000000000001", ChainID: dbtest.ChainID(137), Rules: []string{"large_transfer"}},
+		&models.AllowlistedAddress{ID: 3, Address: "0xabc0000000000000000000000000000000000002", Rules: []string{"unusual_gas_price"}},
+	)})
+	if err := cache.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		chainID int64
+		address address.Address
+		rule    string
+		want    int // entry ID, 0 for none
+	}{
+		{1, "0xabc0000000000000000000000000000000000001", "large_transfer", 1},
+		{137, address.FromHex("0xABC0000000000000000000000000000000000001"), "large_transfer", 2},
+		{137, "0xabc0000000000000000000000000000000000001", "rapid_transactions", 1},
+		{1, "0xabc0000000000000000000000000000000000002", "unusual_gas_price", 3},
+		{1, "0xabc0000000000000000000000000000000000002", "large_transfer", 0},
+		{1, "0xabc0000000000000000000000000000000000003", "large_transfer", 0},
+	}
+	for _, tt := range tests {
+		got := 0
+		if entry := cache.Match(tt.chainID, tt.address, tt.rule); entry != nil {
+			got = entry.ID
+		}
+		if got != tt.want {
+			t.Errorf("Match(%d, %s, %s) = entry %d, want %d", tt.chainID, tt.address, tt.rule, got, tt.want)
+		}
+	}
+}
+
+func TestCacheWatch(t *testing.T) {
+	source := fakeSource{dbtest.NewList[*models.AllowlistedAddress]()}
+	cache := NewCache(source)
+	notifier := &dbtest.Notifier{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := cache.Watch(ctx, notifier); err != nil {
+		t.Fatal(err)
+	}
+
+	source.Set(&models.AllowlistedAddress{Address: "0xabc0000000000000000000000000000000000001"})
+	notifier.Notify(database.AllowlistChannel, "INSERT")
+
+	dbtest.Eventually(t, func() bool { return cache.Len() == 1 }, "allowlist was not reloaded after a notification")
+}
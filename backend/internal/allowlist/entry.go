@@ -0,0 +1,62 @@
+package allowlist
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/minsix/backend/internal/address"
+	"github.com/minsix/backend/internal/detector"
+	"github.com/minsix/backend/internal/models"
+)
+
+// MaxLabelLength is the column size of allowlisted_addresses.label
+const MaxLabelLength = 255
+
+// Validate checks an entry submitted to the allowlist and normalizes it: the
+// address to its canonical form and the rules to a sorted set of built-in
+// rule names. Entries need a label naming the counterparty, and a score
+// factor of at least 0, which zeroes the rules out, and below 1.
+func Validate(entry *models.AllowlistedAddress) error {
+	listed, err := address.Parse(entry.Address.String())
+	if err != nil {
+		return err
+	}
+	entry.Address = listed
+	if entry.ChainID != nil && *entry.ChainID <= 0 {
+		return fmt.Errorf("invalid chain_id %d", *entry.ChainID)
+	}
+
+	entry.Label = strings.TrimSpace(entry.Label)
+	if entry.Label == "" {
+		return fmt.Errorf("label is required")
+	}
+	if len(entry.Label) > MaxLabelLength {
+		return fmt.Errorf("label must be at most %d characters", MaxLabelLength)
+	}
+	entry.Reason = strings.TrimSpace(entry.Reason)
+
+	if entry.ScoreFactor < 0 || entry.ScoreFactor >= 1 {
+		return fmt.Errorf("score_factor must be at least 0 and below 1")
+	}
+
+	known := make(map[string]bool, len(detector.BuiltinRuleNames))
+	for _, name := range detector.BuiltinRuleNames {
+		known[name] = true
+	}
+	seen := make(map[string]bool, len(entry.Rules))
+	rules := []string{}
+	for _, rule := range entry.Rules {
+		rule = strings.TrimSpace(rule)
+		if !known[rule] {
+			return fmt.Errorf("unknown rule %q", rule)
+		}
+		if !seen[rule] {
+			seen[rule] = true
+			rules = append(rules, rule)
+		}
+	}
+	sort.Strings(rules)
+	entry.Rules = rules
+	return nil
+}
@@ -0,0 +1,50 @@
+package allowlist
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/minsix/backend/internal/models"
+)
+
+func TestValidate(t *testing.T) {
+	zero := int64(0)
+
+	tests := []struct {
+		name    string
+		entry   models.AllowlistedAddress
+		wantErr bool
+	}{
+		{"whole address", models.AllowlistedAddress{Address: "0x28C6c06298d514Db089934071355E5743bf21d60", Label: "Binance 14"}, false},
+		{"some rules", models.AllowlistedAddress{Address: "0x28c6c06298d514db089934071355e5743bf21d60", Label: "Binance 14", Rules: []string{"large_transfer", "unusual_gas_price"}, ScoreFactor: 0.5}, false},
+		{"bad address", models.AllowlistedAddress{Address: "0x28c6", Label: "Binance 14"}, true},
+		{"no label", models.AllowlistedAddress{Address: "0x28c6c06298d514db089934071355e5743bf21d60", Label: " "}, true},
+		{"long label", models.AllowlistedAddress{Address: "0x28c6c06298d514db089934071355e5743bf21d60", Label: strings.Repeat("a", MaxLabelLength+1)}, true},
+		{"unknown rule", models.AllowlistedAddress{Address: "0x28c6c06298d514db089934071355e5743bf21d60", Label: "Binance 14", Rules: []string{"large_transfers"}}, true},
+		{"negative factor", models.AllowlistedAddress{Address: "0x28c6c06298d514db089934071355e5743bf21d60", Label: "Binance 14", ScoreFactor: -0.1}, true},
+		{"factor of one", models.AllowlistedAddress{Address: "0x28c6c06298d514db089934071355e5743bf21d60", Label: "Binance 14", ScoreFactor: 1}, true},
+		{"bad chain", models.AllowlistedAddress{Address: "0x28c6c06298d514db089934071355e5743bf21d60", Label: "Binance 14", ChainID: &zero}, true},
+	}
+	for _, tt := range tests {
+		entry := tt.entry
+		err := Validate(&entry)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	entry := models.AllowlistedAddress{
+		Address: "0x28C6c06298d514Db089934071355E5743bf21d60",
+		Label:   " Binance 14 ",
+		Rules:   []string{"unusual_gas_price", " large_transfer", "unusual_gas_price"},
+	}
+	if err := Validate(&entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry.Address != "0x28c6c06298d514db089934071355e5743bf21d60" || entry.Label != "Binance 14" {
+		t.Errorf("entry not normalized: %+v", entry)
+	}
+	if strings.Join(entry.Rules, ",") != "large_transfer,unusual_gas_price" {
+		t.Errorf("rules = %v, want sorted without duplicates", entry.Rules)
+	}
+}
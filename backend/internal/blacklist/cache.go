@@ -0,0 +1,129 @@
+package blacklist
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/minsix/backend/internal/address"
+	"github.com/minsix/backend/internal/database"
+	"github.com/minsix/backend/internal/models"
+)
+
+const (
+	// BloomMinEntries is the list size from which lookups go through a bloom
+	// filter first; smaller lists are answered by the map alone
+	BloomMinEntries = 1024
+
+	// BloomFalsePositiveRate is the share of unlisted addresses the bloom
+	// filter lets through to the map
+	BloomFalsePositiveRate = 0.01
+)
+
+// Source loads the full blacklist, implemented by database.DB
+type Source interface {
+	GetBlacklist() ([]*models.BlacklistedAddress, error)
+}
+
+// scope is the chain an entry applies to, 0 for every chain, and when it expires
+type scope struct {
+	chainID   int64
+	expiresAt *time.Time
+}
+
+// snapshot is an immutable copy of the list; reloads swap in a new one
+type snapshot struct {
+	chains map[address.Address][]scope // where each address is listed
+	filter *bloomFilter                // nil below BloomMinEntries
+}
+
+// Cache keeps the blacklist in memory so the detector can check every
+// transaction without querying the database. It implements
+// detector.BlacklistChecker and is safe for concurrent use.
+type Cache struct {
+	source Source
+
+	mu      sync.RWMutex
+	current *snapshot
+}
+
+// NewCache creates an empty cache; call Load before the first lookup
+func NewCache(source Source) *Cache {
+	return &Cache{
+		source:  source,
+		current: &snapshot{chains: map[address.Address][]scope{}},
+	}
+}
+
+// Load replaces the cached list with the source's current one
+func (c *Cache) Load() error {
+	entries, err := c.source.GetBlacklist()
+	if err != nil {
+		return fmt.Errorf("failed to load blacklist: %w", err)
+	}
+
+	next := &snapshot{chains: make(map[address.Address][]scope, len(entries))}
+	for _, entry := range entries {
+		s := scope{expiresAt: entry.ExpiresAt}
+		if entry.ChainID != nil {
+			s.chainID = *entry.ChainID
+		}
+		next.chains[entry.Address] = append(next.chains[entry.Address], s)
+	}
+	if len(next.chains) >= BloomMinEntries {
+		next.filter = newBloomFilter(len(next.chains), BloomFalsePositiveRate)
+		for listed := range next.chains {
+			next.filter.add(listed.String())
+		}
+	}
+
+	c.mu.Lock()
+	c.current = next
+	c.mu.Unlock()
+	return nil
+}
+
+// IsBlacklisted reports whether an address is blacklisted on a chain, either
+// for that chain alone or for every chain; chainID 0 matches any chain.
+// Entries stop matching once they expire, without waiting for a reload.
+func (c *Cache) IsBlacklisted(chainID int64, addr address.Address) (bool, error) {
+	c.mu.RLock()
+	current := c.current
+	c.mu.RUnlock()
+
+	if current.filter != nil && !current.filter.mayContain(addr.String()) {
+		return false, nil
+	}
+	now := time.Now()
+	for _, s := range current.chains[addr] {
+		if s.expiresAt != nil && !s.expiresAt.After(now) {
+			continue
+		}
+		if chainID == 0 || s.chainID == 0 || s.chainID == chainID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Len returns the number of distinct cached addresses
+func (c *Cache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.current.chains)
+}
+
+// Watch reloads the list whenever blacklisted_addresses changes, and every
+// database.RefreshInterval in case a notification was lost, until ctx is
+// cancelled
+func (c *Cache) Watch(ctx context.Context, notifier database.Notifier) error {
+	return database.WatchReload(ctx, notifier, database.BlacklistChannel, func() error {
+		if err := c.Load(); err != nil {
+			return err
+		}
+		log.Printf("Blacklist refreshed, %d addresses", c.Len())
+		return nil
+	})
+}
@@ -0,0 +1,117 @@
+package blacklist
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/minsix/backend/internal/address"
+	"github.com/minsix/backend/internal/database"
+	"github.com/minsix/backend/internal/database/dbtest"
+	"github.com/minsix/backend/internal/models"
+)
+
+type fakeSource struct {
+	*dbtest.List[*models.BlacklistedAddress]
+}
+
+func (f fakeSource) GetBlacklist() ([]*models.BlacklistedAddress, error) {
+	return f.Load()
+}
+
+func TestCacheIsBlacklisted(t *testing.T) {
+	expired, expires := time.Now().Add(-time.Minute), time.Now().Add(time.Hour)
+	source := fakeSource{dbtest.NewList(
+		&models.BlacklistedAddress{Address: "0xabc0000000000000000000000000000000000001"},
+		&models.BlacklistedAddress{Address: "0xabc0000000000000000000000000000000000002", ChainID: dbtest.ChainID(137)},
+		&models.BlacklistedAddress{Address: "0xabc0000000000000000000000000000000000002", ChainID: dbtest.ChainID(8453)},
+		&models.BlacklistedAddress{Address: "0xabc0000000000000000000000000000000000004", ExpiresAt: &expired},
+		&models.BlacklistedAddress{Address: "0xabc0000000000000000000000000000000000005", ExpiresAt: &expires},
+	)}
+	cache := NewCache(source)
+	if err := cache.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		chainID int64
+		address address.Address
+		want    bool
+	}{
+		{1, "0xabc0000000000000000000000000000000000001", true},
+		{137, "0xabc0000000000000000000000000000000000001", true},
+		{137, "0xabc0000000000000000000000000000000000002", true},
+		{8453, "0xabc0000000000000000000000000000000000002", true},
+		{1, "0xabc0000000000000000000000000000000000002", false},
+		{0, "0xabc0000000000000000000000000000000000002", true},
+		{1, "0xabc0000000000000000000000000000000000003", false},
+		{1, "0xabc0000000000000000000000000000000000004", false},
+		{1, "0xabc0000000000000000000000000000000000005", true},
+	}
+	for _, tt := range tests {
+		got, err := cache.IsBlacklisted(tt.chainID, tt.address)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("IsBlacklisted(%d, %s) = %v, want %v", tt.chainID, tt.address, got, tt.want)
+		}
+	}
+}
+
+func TestCacheLargeList(t *testing.T) {
+	var entries []*models.BlacklistedAddress
+	for i := 0; i < 5000; i++ {
+		entries = append(entries, &models.BlacklistedAddress{Address: address.FromHex(fmt.Sprintf("0x%040x", i))})
+	}
+	cache := NewCache(fakeSource{dbtest.NewList(entries...)})
+	if err := cache.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if cache.current.filter == nil {
+		t.Fatal("large list has no bloom filter")
+	}
+
+	for i := 0; i < 5000; i++ {
+		if listed, _ := cache.IsBlacklisted(1, address.FromHex(fmt.Sprintf("0x%040X", i))); !listed {
+			t.Fatalf("address %d not found", i)
+		}
+	}
+	falsePositives := 0
+	for i := 5000; i < 15000; i++ {
+		if listed, _ := cache.IsBlacklisted(1, address.FromHex(fmt.Sprintf("0x%040x", i))); listed {
+			t.Fatalf("unlisted address %d found", i)
+		}
+		if cache.current.filter.mayContain(fmt.Sprintf("0x%040x", i)) {
+			falsePositives++
+		}
+	}
+	if falsePositives > 300 {
+		t.Errorf("bloom filter let %d of 10000 unlisted addresses through", falsePositives)
+	}
+}
+
+func TestCacheWatch(t *testing.T) {
+	source := fakeSource{dbtest.NewList[*models.BlacklistedAddress]()}
+	cache := NewCache(source)
+	if err := cache.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	notifier := &dbtest.Notifier{}
+	if err := cache.Watch(ctx, notifier); err != nil {
+		t.Fatal(err)
+	}
+
+	addr := address.FromHex("0xabc0000000000000000000000000000000000001")
+	source.Set(&models.BlacklistedAddress{Address: addr})
+	notifier.Notify(database.BlacklistChannel, "INSERT")
+
+	dbtest.Eventually(t, func() bool {
+		listed, _ := cache.IsBlacklisted(1, addr)
+		return listed
+	}, "cache not reloaded after notification")
+}
@@ -0,0 +1,61 @@
+package blacklist
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/minsix/backend/internal/address"
+	"github.com/minsix/backend/internal/models"
+)
+
+// Column sizes of blacklisted_addresses
+const (
+	MaxSourceLength   = 100
+	MaxCategoryLength = 50
+)
+
+var severities = map[string]bool{
+	models.SeverityLow:      true,
+	models.SeverityMedium:   true,
+	models.SeverityHigh:     true,
+	models.SeverityCritical: true,
+}
+
+// Validate checks an entry submitted to the blacklist and normalizes it: the
+// address to its canonical form, the category to lowercase, and severity
+// defaults to high
+func Validate(entry *models.BlacklistedAddress) error {
+	listed, err := address.Parse(entry.Address.String())
+	if err != nil {
+		return err
+	}
+	entry.Address = listed
+	if entry.ChainID != nil && *entry.ChainID <= 0 {
+		return fmt.Errorf("invalid chain_id %d", *entry.ChainID)
+	}
+
+	entry.Reason = strings.TrimSpace(entry.Reason)
+	if entry.Reason == "" {
+		return fmt.Errorf("reason is required")
+	}
+	entry.Source = strings.TrimSpace(entry.Source)
+	if len(entry.Source) > MaxSourceLength {
+		return fmt.Errorf("source must be at most %d characters", MaxSourceLength)
+	}
+	entry.Category = strings.ToLower(strings.TrimSpace(entry.Category))
+	if len(entry.Category) > MaxCategoryLength {
+		return fmt.Errorf("category must be at most %d characters", MaxCategoryLength)
+	}
+
+	if entry.Severity == "" {
+		entry.Severity = models.SeverityHigh
+	}
+	if !severities[entry.Severity] {
+		return fmt.Errorf("unknown severity %q", entry.Severity)
+	}
+	if entry.ExpiresAt != nil && !entry.ExpiresAt.After(time.Now()) {
+		return fmt.Errorf("expires_at must be in the future")
+	}
+	return nil
+}
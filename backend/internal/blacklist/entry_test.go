@@ -0,0 +1,44 @@
+package blacklist
+
+import (
+	"testing"
+	"time"
+
+	"github.com/minsix/backend/internal/models"
+)
+
+func TestValidate(t *testing.T) {
+	past, future := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	zero := int64(0)
+
+	tests := []struct {
+		name    string
+		entry   models.BlacklistedAddress
+		wantErr bool
+	}{
+		{"valid", models.BlacklistedAddress{Address: "0xABC0000000000000000000000000000000000001", Reason: "Phishing", Category: " Phishing "}, false},
+		{"expiring", models.BlacklistedAddress{Address: "0xabc0000000000000000000000000000000000001", Reason: "Phishing", ExpiresAt: &future}, false},
+		{"bad checksum", models.BlacklistedAddress{Address: "0x098B716B8Aaf21512996dC57EB0615e2383E2F96", Reason: "Phishing"}, true},
+		{"checksummed", models.BlacklistedAddress{Address: "0x098B716B8Aaf21512996dC57EB0615e2383E2f96", Reason: "Phishing"}, false},
+		{"bad address", models.BlacklistedAddress{Address: "0xabc", Reason: "Phishing"}, true},
+		{"no reason", models.BlacklistedAddress{Address: "0xabc0000000000000000000000000000000000001", Reason: "  "}, true},
+		{"bad severity", models.BlacklistedAddress{Address: "0xabc0000000000000000000000000000000000001", Reason: "Phishing", Severity: "extreme"}, true},
+		{"bad chain", models.BlacklistedAddress{Address: "0xabc0000000000000000000000000000000000001", Reason: "Phishing", ChainID: &zero}, true},
+		{"expired", models.BlacklistedAddress{Address: "0xabc0000000000000000000000000000000000001", Reason: "Phishing", ExpiresAt: &past}, true},
+	}
+	for _, tt := range tests {
+		entry := tt.entry
+		err := Validate(&entry)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	entry := models.BlacklistedAddress{Address: "0xABC0000000000000000000000000000000000001", Reason: " Phishing ", Category: " Phishing "}
+	if err := Validate(&entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry.Address != "0xabc0000000000000000000000000000000000001" || entry.Reason != "Phishing" || entry.Category != "phishing" || entry.Severity != models.SeverityHigh {
+		t.Errorf("Validate() normalized to %+v", entry)
+	}
+}
@@ -0,0 +1,264 @@
+package blacklist
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Feed formats accepted by Import
+const (
+	FormatOFACXML = "ofac-xml" // OFAC SDN list, sdn.xml
+	FormatOFACCSV = "ofac-csv" // OFAC SDN list, sdn.csv
+	FormatList    = "list"     // one address per line, optionally followed by a reason
+	FormatJSON    = "json"     // address arrays or maps such as CryptoScamDB exports
+)
+
+// Formats lists the feed formats accepted by Import
+var Formats = []string{FormatOFACXML, FormatOFACCSV, FormatList, FormatJSON}
+
+// feedRecord is an address read from a feed, not yet validated
+type feedRecord struct {
+	Address  string
+	Reason   string
+	Category string
+}
+
+// DetectFormat guesses a feed's format from its file name; sdn.xml and
+// sdn.csv are taken to be OFAC lists
+func DetectFormat(fileName string) (string, error) {
+	base := strings.ToLower(filepath.Base(fileName))
+	switch filepath.Ext(base) {
+	case ".xml":
+		return FormatOFACXML, nil
+	case ".csv":
+		if strings.Contains(base, "sdn") {
+			return FormatOFACCSV, nil
+		}
+		return FormatList, nil
+	case ".json":
+		return FormatJSON, nil
+	case ".txt", ".list", "":
+		return FormatList, nil
+	}
+	return "", fmt.Errorf("can't tell the format of %s; set it explicitly", fileName)
+}
+
+// parseFeed reads the address records of a feed
+func parseFeed(format string, data []byte) ([]feedRecord, error) {
+	switch format {
+	case FormatOFACXML:
+		return parseOFACXML(data)
+	case FormatOFACCSV:
+		return parseOFACCSV(data)
+	case FormatList:
+		return parseList(data)
+	case FormatJSON:
+		return parseJSON(data)
+	}
+	return nil, fmt.Errorf("unknown feed format %q", format)
+}
+
+// sdnList is the part of OFAC's sdn.xml carrying digital currency addresses
+type sdnList struct {
+	Entries []struct {
+		LastName  string   `xml:"lastName"`
+		FirstName string   `xml:"firstName"`
+		Programs  []string `xml:"programList>program"`
+		IDs       []struct {
+			Type   string `xml:"idType"`
+			Number string `xml:"idNumber"`
+		} `xml:"idList>id"`
+	} `xml:"sdnEntry"`
+}
+
+// sdnAddressType prefixes the idType of digital currency addresses
+const sdnAddressType = "Digital Currency Address - "
+
+func parseOFACXML(data []byte) ([]feedRecord, error) {
+	var list sdnList
+	if err := xml.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse OFAC SDN XML: %w", err)
+	}
+
+	var records []feedRecord
+	for _, entry := range list.Entries {
+		name := strings.TrimSpace(strings.TrimSpace(entry.FirstName) + " " + strings.TrimSpace(entry.LastName))
+		for _, id := range entry.IDs {
+			// Only EVM addresses; the list also carries BTC, XMR, TRX and others
+			number := strings.TrimSpace(id.Number)
+			if !strings.HasPrefix(id.Type, sdnAddressType) || !strings.HasPrefix(number, "0x") {
+				continue
+			}
+			records = append(records, feedRecord{
+				Address:  number,
+				Reason:   sdnReason(name, entry.Programs),
+				Category: CategorySanctions,
+			})
+		}
+	}
+	return records, nil
+}
+
+// sdnRemarkAddress finds digital currency addresses in the remarks of sdn.csv
+var sdnRemarkAddress = regexp.MustCompile(`Digital Currency Address - [A-Za-z0-9]+ (0x[0-9A-Za-z]+)`)
+
+func parseOFACCSV(data []byte) ([]feedRecord, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse OFAC SDN CSV: %w", err)
+	}
+
+	// ent_num, SDN_Name, SDN_Type, Program, Title, Call_Sign, Vess_type,
+	// Tonnage, GRT, Vess_flag, Vess_owner, Remarks
+	var records []feedRecord
+	for _, row := range rows {
+		if len(row) < 12 {
+			continue
+		}
+		for _, match := range sdnRemarkAddress.FindAllStringSubmatch(row[11], -1) {
+			records = append(records, feedRecord{
+				Address:  match[1],
+				Reason:   sdnReason(row[1], strings.Split(row[3], "] [")),
+				Category: CategorySanctions,
+			})
+		}
+	}
+	return records, nil
+}
+
+func sdnReason(name string, programs []string) string {
+	var cleaned []string
+	for _, program := range programs {
+		if program = strings.Trim(strings.TrimSpace(program), "[]"); program != "" && program != "-0-" {
+			cleaned = append(cleaned, program)
+		}
+	}
+	reason := "OFAC SDN"
+	if name = strings.TrimSpace(name); name != "" && name != "-0-" {
+		reason += ": " + name
+	}
+	if len(cleaned) > 0 {
+		reason += " (" + strings.Join(cleaned, ", ") + ")"
+	}
+	return reason
+}
+
+// parseList reads one address per line; text after the address is the
+// reason, and blank lines and lines starting with # are skipped
+func parseList(data []byte) ([]feedRecord, error) {
+	var records []feedRecord
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		address, reason, _ := strings.Cut(strings.Replace(line, ",", " ", 1), " ")
+		records = append(records, feedRecord{Address: address, Reason: strings.TrimSpace(reason)})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read address list: %w", err)
+	}
+	return records, nil
+}
+
+// parseJSON accepts an array of addresses, an array of objects with an
+// address field, or an object keyed by address as exported by CryptoScamDB,
+// optionally wrapped in a result, data or addresses field
+func parseJSON(data []byte) ([]feedRecord, error) {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON feed: %w", err)
+	}
+
+	for {
+		wrapper, ok := value.(map[string]interface{})
+		if !ok {
+			break
+		}
+		unwrapped := false
+		for _, key := range []string{"result", "data", "addresses"} {
+			if inner, ok := wrapper[key]; ok {
+				value, unwrapped = inner, true
+				break
+			}
+		}
+		if !unwrapped {
+			break
+		}
+	}
+
+	var records []feedRecord
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			switch item := item.(type) {
+			case string:
+				records = append(records, feedRecord{Address: item})
+			case map[string]interface{}:
+				record := jsonRecord(item)
+				record.Address = jsonString(item, "address")
+				records = append(records, record)
+			}
+		}
+	case map[string]interface{}:
+		// Sorted so the first reason for an address is stable
+		addresses := make([]string, 0, len(v))
+		for address := range v {
+			addresses = append(addresses, address)
+		}
+		sort.Strings(addresses)
+		for _, address := range addresses {
+			record := feedRecord{}
+			switch details := v[address].(type) {
+			case map[string]interface{}:
+				record = jsonRecord(details)
+			case []interface{}:
+				if len(details) > 0 {
+					if first, ok := details[0].(map[string]interface{}); ok {
+						record = jsonRecord(first)
+					}
+				}
+			}
+			record.Address = address
+			records = append(records, record)
+		}
+	default:
+		return nil, fmt.Errorf("JSON feed holds no addresses")
+	}
+	return records, nil
+}
+
+// jsonRecord reads the reason and category of a JSON feed item
+func jsonRecord(item map[string]interface{}) feedRecord {
+	record := feedRecord{
+		Reason:   jsonString(item, "reason", "description", "name"),
+		Category: jsonString(item, "category", "type"),
+	}
+	if url := jsonString(item, "url"); url != "" && record.Reason != "" {
+		record.Reason += " (" + url + ")"
+	}
+	return record
+}
+
+// jsonString returns the first of the keys holding a non-empty string
+func jsonString(item map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		if s, ok := item[key].(string); ok && strings.TrimSpace(s) != "" {
+			return strings.TrimSpace(s)
+		}
+	}
+	return ""
+}
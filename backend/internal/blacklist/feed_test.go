@@ -0,0 +1,114 @@
+package blacklist
+
+import (
+	"testing"
+)
+
+const sdnXML = `<?xml version="1.0" standalone="yes"?>
+<sdnList xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns="https://sanctionslistservice.ofac.treas.gov/api/PublicationPreview/exports/XML">
+  <sdnEntry>
+    <uid>36000</uid>
+    <lastName>LAZARUS GROUP</lastName>
+    <sdnType>Entity</sdnType>
+    <programList><program>DPRK3</program><program>CYBER2</program></programList>
+    <idList>
+      <id><uid>1</uid><idType>Digital Currency Address - ETH</idType><idNumber>0x098B716B8Aaf21512996dC57EB0615e2383E2f96</idNumber></id>
+      <id><uid>2</uid><idType>Digital Currency Address - XBT</idType><idNumber>bc1qxy2kgdygjrsqtzq2n0yrf2493p83kkfjhx0wlh</idNumber></id>
+      <id><uid>3</uid><idType>Passport</idType><idNumber>0x123</idNumber></id>
+    </idList>
+  </sdnEntry>
+  <sdnEntry>
+    <uid>36001</uid>
+    <firstName>Roman</firstName>
+    <lastName>SEMENOV</lastName>
+    <programList><program>CYBER2</program></programList>
+    <idList>
+      <id><uid>4</uid><idType>Digital Currency Address - USDT</idType><idNumber> 0xdcbEfFBECcE100cCE9E4b153C4e15cB885643193</idNumber></id>
+    </idList>
+  </sdnEntry>
+</sdnList>`
+
+const sdnCSV = `36000,"LAZARUS GROUP","-0- ","DPRK3] [CYBER2",-0- ,-0- ,-0- ,-0- ,-0- ,-0- ,-0- ,"Digital Currency Address - ETH 0x098B716B8Aaf21512996dC57EB0615e2383E2f96; Digital Currency Address - XBT bc1qxy2kgdygjrsqtzq2n0yrf2493p83kkfjhx0wlh; alt. Digital Currency Address - ETH 0xa0e1c89Ef1a489c9C7dE96311eD5Ce5D32c20E4B."
+36002,"SOMEONE ELSE","individual","SDGT",-0- ,-0- ,-0- ,-0- ,-0- ,-0- ,-0- ,"DOB 1970."
+`
+
+func TestParseFeed(t *testing.T) {
+	tests := []struct {
+		format string
+		data   string
+		want   []feedRecord
+	}{
+		{FormatOFACXML, sdnXML, []feedRecord{
+			{"0x098B716B8Aaf21512996dC57EB0615e2383E2f96", "OFAC SDN: LAZARUS GROUP (DPRK3, CYBER2)", CategorySanctions},
+			{"0xdcbEfFBECcE100cCE9E4b153C4e15cB885643193", "OFAC SDN: Roman SEMENOV (CYBER2)", CategorySanctions},
+		}},
+		{FormatOFACCSV, sdnCSV, []feedRecord{
+			{"0x098B716B8Aaf21512996dC57EB0615e2383E2f96", "OFAC SDN: LAZARUS GROUP (DPRK3, CYBER2)", CategorySanctions},
+			{"0xa0e1c89Ef1a489c9C7dE96311eD5Ce5D32c20E4B", "OFAC SDN: LAZARUS GROUP (DPRK3, CYBER2)", CategorySanctions},
+		}},
+		{FormatList, "# scam wallets\n0xabc0000000000000000000000000000000000001 fake airdrop\n\n0xabc0000000000000000000000000000000000002,drainer\n0xabc0000000000000000000000000000000000003\n", []feedRecord{
+			{"0xabc0000000000000000000000000000000000001", "fake airdrop", ""},
+			{"0xabc0000000000000000000000000000000000002", "drainer", ""},
+			{"0xabc0000000000000000000000000000000000003", "", ""},
+		}},
+		{FormatJSON, `["0xabc0000000000000000000000000000000000001"]`, []feedRecord{
+			{"0xabc0000000000000000000000000000000000001", "", ""},
+		}},
+		{FormatJSON, `{"data": [{"address": "0xabc0000000000000000000000000000000000001", "description": "Phishing site", "category": "Phishing"}]}`, []feedRecord{
+			{"0xabc0000000000000000000000000000000000001", "Phishing site", "Phishing"},
+		}},
+		{FormatJSON, `{"success": true, "result": {
+			"0xabc0000000000000000000000000000000000002": [{"name": "fake-uniswap.io", "category": "Phishing", "url": "https://fake-uniswap.io"}],
+			"0xabc0000000000000000000000000000000000001": {"name": "scam"}
+		}}`, []feedRecord{
+			{"0xabc0000000000000000000000000000000000001", "scam", ""},
+			{"0xabc0000000000000000000000000000000000002", "fake-uniswap.io (https://fake-uniswap.io)", "Phishing"},
+		}},
+	}
+	for _, tt := range tests {
+		got, err := parseFeed(tt.format, []byte(tt.data))
+		if err != nil {
+			t.Errorf("parseFeed(%s) error: %v", tt.format, err)
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("parseFeed(%s) = %+v, want %+v", tt.format, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("parseFeed(%s)[%d] = %+v, want %+v", tt.format, i, got[i], tt.want[i])
+			}
+		}
+	}
+}
+
+func TestParseFeedInvalid(t *testing.T) {
+	for format, data := range map[string]string{
+		FormatOFACXML: "<sdnList><sdnEntry>",
+		FormatJSON:    `"0xabc"`,
+		"yaml":        "- 0xabc",
+	} {
+		if _, err := parseFeed(format, []byte(data)); err == nil {
+			t.Errorf("parseFeed(%s) accepted %q", format, data)
+		}
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := map[string]string{
+		"sdn.xml":           FormatOFACXML,
+		"/feeds/SDN.CSV":    FormatOFACCSV,
+		"scams.csv":         FormatList,
+		"addresses.txt":     FormatList,
+		"cryptoscamdb.json": FormatJSON,
+	}
+	for name, want := range tests {
+		if got, err := DetectFormat(name); err != nil || got != want {
+			t.Errorf("DetectFormat(%q) = %q, %v, want %q", name, got, err, want)
+		}
+	}
+	if _, err := DetectFormat("feed.pdf"); err == nil {
+		t.Error("DetectFormat accepted a .pdf")
+	}
+}
@@ -0,0 +1,173 @@
+package blacklist
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/minsix/backend/internal/address"
+	"github.com/minsix/backend/internal/models"
+)
+
+// CategorySanctions is the category of entries from sanctions lists
+const CategorySanctions = "sanctions"
+
+// ErrInvalidFeed wraps the errors of feeds or options that can't be imported
+var ErrInvalidFeed = errors.New("invalid feed")
+
+// MaxInvalidReported caps the malformed addresses listed in an import result
+const MaxInvalidReported = 20
+
+// ImportStore records feed imports, implemented by database.DB
+type ImportStore interface {
+	GetFeedAddresses(source string, chainID *int64) (map[address.Address]bool, error)
+	ApplyBlacklistImport(result *models.BlacklistImport, entries, added []*models.BlacklistedAddress, removed []address.Address, prune bool) error
+}
+
+// FeedOptions describes a feed to import
+type FeedOptions struct {
+	Source   string // feed name recorded on its entries, e.g. ofac-sdn
+	Format   string // detected from FileName when empty
+	FileName string
+	ChainID  *int64 // nil lists the addresses on every chain
+	Category string // for entries the feed doesn't categorize
+	Severity string // high by default, critical for sanctions lists
+	Prune    bool   // remove entries the feed no longer lists from the blacklist
+	DryRun   bool   // report the changes without applying them
+}
+
+// Import reads a feed, normalizes and deduplicates its addresses and diffs
+// them against the feed's previous import for the same chain. New addresses are blacklisted
+// unless already listed; addresses the feed dropped are removed when Prune
+// is set and no other feed still lists them.
+func Import(store ImportStore, r io.Reader, opts FeedOptions) (*models.BlacklistImport, error) {
+	opts.Source = strings.TrimSpace(opts.Source)
+	if opts.Source == "" {
+		return nil, fmt.Errorf("%w: source is required", ErrInvalidFeed)
+	}
+	if len(opts.Source) > MaxSourceLength {
+		return nil, fmt.Errorf("%w: source must be at most %d characters", ErrInvalidFeed, MaxSourceLength)
+	}
+	if opts.Severity != "" && !severities[opts.Severity] {
+		return nil, fmt.Errorf("%w: unknown severity %q", ErrInvalidFeed, opts.Severity)
+	}
+	if opts.Format == "" {
+		format, err := DetectFormat(opts.FileName)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidFeed, err)
+		}
+		opts.Format = format
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read feed: %w", err)
+	}
+	records, err := parseFeed(opts.Format, data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFeed, err)
+	}
+	sum := sha256.Sum256(data)
+
+	result := &models.BlacklistImport{
+		Source:   opts.Source,
+		Format:   opts.Format,
+		FileName: opts.FileName,
+		Checksum: hex.EncodeToString(sum[:]),
+		ChainID:  opts.ChainID,
+		DryRun:   opts.DryRun,
+	}
+	entries := feedEntries(records, opts, result)
+
+	previous, err := store.GetFeedAddresses(opts.Source, opts.ChainID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load previous import of %s: %w", opts.Source, err)
+	}
+	added, removed := diffFeed(previous, entries)
+	result.Total = len(entries)
+	result.Added = len(added)
+	result.Removed = len(removed)
+	result.Unchanged = len(entries) - len(added)
+	for _, entry := range added {
+		result.AddedAddresses = append(result.AddedAddresses, entry.Address)
+	}
+	result.RemovedAddresses = removed
+
+	if opts.DryRun {
+		return result, nil
+	}
+	if err := store.ApplyBlacklistImport(result, entries, added, removed, opts.Prune); err != nil {
+		return nil, fmt.Errorf("failed to import %s: %w", opts.Source, err)
+	}
+	return result, nil
+}
+
+// feedEntries validates feed records into blacklist entries, keeping the first
+// record of each address and counting the malformed ones in result
+func feedEntries(records []feedRecord, opts FeedOptions, result *models.BlacklistImport) []*models.BlacklistedAddress {
+	seen := make(map[address.Address]bool, len(records))
+	entries := make([]*models.BlacklistedAddress, 0, len(records))
+	for _, record := range records {
+		listed, err := address.Parse(record.Address)
+		entry := &models.BlacklistedAddress{
+			ChainID:  opts.ChainID,
+			Address:  listed,
+			Reason:   record.Reason,
+			Source:   opts.Source,
+			Category: record.Category,
+			Severity: opts.Severity,
+		}
+		if entry.Reason == "" {
+			entry.Reason = "Listed by " + opts.Source
+		}
+		if entry.Category == "" {
+			entry.Category = opts.Category
+		}
+		if entry.Severity == "" && entry.Category == CategorySanctions {
+			entry.Severity = models.SeverityCritical
+		}
+
+		if err == nil {
+			err = Validate(entry)
+		}
+		if err != nil {
+			result.Invalid++
+			if len(result.InvalidAddresses) < MaxInvalidReported {
+				result.InvalidAddresses = append(result.InvalidAddresses, record.Address)
+			}
+			continue
+		}
+		if seen[entry.Address] {
+			continue
+		}
+		seen[entry.Address] = true
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// diffFeed returns the entries missing from the previous import and the
+// previously imported addresses missing from entries
+func diffFeed(previous map[address.Address]bool, entries []*models.BlacklistedAddress) ([]*models.BlacklistedAddress, []address.Address) {
+	var added []*models.BlacklistedAddress
+	current := make(map[address.Address]bool, len(entries))
+	for _, entry := range entries {
+		current[entry.Address] = true
+		if !previous[entry.Address] {
+			added = append(added, entry)
+		}
+	}
+
+	var removed []address.Address
+	for listed := range previous {
+		if !current[listed] {
+			removed = append(removed, listed)
+		}
+	}
+	sort.Slice(removed, func(i, j int) bool { return removed[i] < removed[j] })
+	return added, removed
+}
@@ -0,0 +1,117 @@
+package blacklist
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/minsix/backend/internal/address"
+	"github.com/minsix/backend/internal/models"
+)
+
+type fakeImportStore struct {
+	previous map[address.Address]bool
+	chainID  *int64 // chain the previous import was loaded for
+	entries  []*models.BlacklistedAddress
+	added    []*models.BlacklistedAddress
+	removed  []address.Address
+	applied  bool
+}
+
+func (f *fakeImportStore) GetFeedAddresses(source string, chainID *int64) (map[address.Address]bool, error) {
+	f.chainID = chainID
+	return f.previous, nil
+}
+
+func (f *fakeImportStore) ApplyBlacklistImport(result *models.BlacklistImport, entries, added []*models.BlacklistedAddress, removed []address.Address, prune bool) error {
+	f.entries, f.added, f.removed, f.applied = entries, added, removed, true
+	return nil
+}
+
+func TestImport(t *testing.T) {
+	store := &fakeImportStore{previous: map[address.Address]bool{
+		"0xabc0000000000000000000000000000000000001": true,
+		"0xabc0000000000000000000000000000000000009": true,
+	}}
+	feed := strings.Join([]string{
+		"0xABC0000000000000000000000000000000000001",
+		"0xabc0000000000000000000000000000000000002 drainer",
+		"0xabc0000000000000000000000000000000000002 duplicate",
+		"0x098b716b8aaf21512996dc57eb0615e2383e2f96",
+		"0x098B716B8Aaf21512996dC57EB0615e2383E2F96", // bad checksum
+		"not-an-address",
+	}, "\n")
+
+	result, err := Import(store, strings.NewReader(feed), FeedOptions{Source: "community", FileName: "scams.txt", Category: "scam"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Format != FormatList || len(result.Checksum) != 64 {
+		t.Errorf("result format %q checksum %q", result.Format, result.Checksum)
+	}
+	if result.Total != 3 || result.Added != 2 || result.Unchanged != 1 || result.Removed != 1 || result.Invalid != 2 {
+		t.Errorf("result = %+v, want 3 total, 2 added, 1 unchanged, 1 removed, 2 invalid", result)
+	}
+	if !store.applied || len(store.entries) != 3 || len(store.added) != 2 {
+		t.Fatalf("applied %v with %d entries and %d added", store.applied, len(store.entries), len(store.added))
+	}
+	if len(store.removed) != 1 || store.removed[0] != "0xabc0000000000000000000000000000000000009" {
+		t.Errorf("removed = %v", store.removed)
+	}
+
+	added := store.added[0]
+	if added.Address != "0xabc0000000000000000000000000000000000002" || added.Reason != "drainer" || added.Source != "community" ||
+		added.Category != "scam" || added.Severity != models.SeverityHigh {
+		t.Errorf("added entry = %+v", added)
+	}
+	if store.added[1].Reason != "Listed by community" {
+		t.Errorf("default reason = %q", store.added[1].Reason)
+	}
+}
+
+func TestImportDryRun(t *testing.T) {
+	store := &fakeImportStore{}
+	result, err := Import(store, strings.NewReader(sdnXML), FeedOptions{Source: "ofac-sdn", Format: FormatOFACXML, DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if store.applied {
+		t.Error("dry run applied the import")
+	}
+	if result.Added != 2 || len(result.AddedAddresses) != 2 {
+		t.Errorf("result = %+v, want 2 added", result)
+	}
+}
+
+func TestImportSanctionsSeverity(t *testing.T) {
+	store := &fakeImportStore{}
+	if _, err := Import(store, strings.NewReader(sdnCSV), FeedOptions{Source: "ofac-sdn", FileName: "sdn.csv"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range store.entries {
+		if entry.Category != CategorySanctions || entry.Severity != models.SeverityCritical {
+			t.Errorf("entry %s has category %q and severity %q", entry.Address, entry.Category, entry.Severity)
+		}
+	}
+}
+
+func TestImportChainScoped(t *testing.T) {
+	store := &fakeImportStore{}
+	chainID := int64(137)
+	if _, err := Import(store, strings.NewReader(sdnXML), FeedOptions{Source: "ofac-sdn", Format: FormatOFACXML, ChainID: &chainID}); err != nil {
+		t.Fatal(err)
+	}
+	if store.chainID == nil || *store.chainID != 137 {
+		t.Errorf("diffed against the import for chain %v, want 137", store.chainID)
+	}
+	for _, entry := range store.entries {
+		if entry.ChainID == nil || *entry.ChainID != 137 {
+			t.Errorf("entry %s on chain %v, want 137", entry.Address, entry.ChainID)
+		}
+	}
+}
+
+func TestImportRequiresSource(t *testing.T) {
+	if _, err := Import(&fakeImportStore{}, strings.NewReader(""), FeedOptions{Format: FormatList}); err == nil {
+		t.Error("Import accepted a feed without a source")
+	}
+}
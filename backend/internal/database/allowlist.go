@@ -0,0 +1,115 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/minsix/backend/internal/models"
+)
+
+// AllowlistChannel is notified whenever allowlisted_addresses changes
+const AllowlistChannel = "allowlist_changed"
+
+// ErrAllowlisted is returned when adding an address that is already allowlisted
+var ErrAllowlisted = errors.New("address is already allowlisted")
+
+const allowlistColumns = `id, chain_id, address, label, rules, score_factor, reason, added_at, updated_at`
+
+// GetAllowlist returns the allowlisted addresses on a chain, including those
+// for every chain, oldest first; chainID 0 returns every entry
+func (db *DB) GetAllowlist(chainID int64) ([]*models.AllowlistedAddress, error) {
+	query := `SELECT ` + allowlistColumns + ` FROM allowlisted_addresses
+		WHERE $1::bigint = 0 OR chain_id IS NULL OR chain_id = $1
+		ORDER BY added_at, id`
+	rows, err := db.Query(query, chainID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []*models.AllowlistedAddress{}
+	for rows.Next() {
+		entry, err := scanAllowlistEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// GetAllowlistEntry returns an entry by ID, or nil if there is none
+func (db *DB) GetAllowlistEntry(id int) (*models.AllowlistedAddress, error) {
+	entry, err := scanAllowlistEntry(db.QueryRow(`SELECT `+allowlistColumns+` FROM allowlisted_addresses WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return entry, err
+}
+
+// AddAllowlistEntry allowlists an address; it returns ErrAllowlisted if the
+// address is already allowlisted for the same chain scope
+func (db *DB) AddAllowlistEntry(entry *models.AllowlistedAddress) error {
+	query := `
+		INSERT INTO allowlisted_addresses (chain_id, address, label, rules, score_factor, reason)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT ((COALESCE(chain_id, 0)), address) DO NOTHING
+		RETURNING ` + allowlistColumns
+	saved, err := scanAllowlistEntry(db.QueryRow(query, entry.ChainID, entry.Address, entry.Label, pq.Array(entry.Rules), entry.ScoreFactor, entry.Reason))
+	if err == sql.ErrNoRows {
+		return ErrAllowlisted
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save allowlist entry: %w", err)
+	}
+
+	*entry = *saved
+	return nil
+}
+
+// UpdateAllowlistEntry changes the label, rules, score factor and reason of
+// an entry; the address and chain stay as they are. It reports false if there
+// is no such entry.
+func (db *DB) UpdateAllowlistEntry(entry *models.AllowlistedAddress) (bool, error) {
+	query := `
+		UPDATE allowlisted_addresses
+		SET label = $2, rules = $3, score_factor = $4, reason = $5, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING ` + allowlistColumns
+	saved, err := scanAllowlistEntry(db.QueryRow(query, entry.ID, entry.Label, pq.Array(entry.Rules), entry.ScoreFactor, entry.Reason))
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to update allowlist entry: %w", err)
+	}
+
+	*entry = *saved
+	return true, nil
+}
+
+// DeleteAllowlistEntry removes an entry, reporting whether it existed
+func (db *DB) DeleteAllowlistEntry(id int) (bool, error) {
+	result, err := db.Exec(`DELETE FROM allowlisted_addresses WHERE id = $1`, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete allowlist entry: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	return deleted > 0, err
+}
+
+func scanAllowlistEntry(row interface{ Scan(...interface{}) error }) (*models.AllowlistedAddress, error) {
+	entry := &models.AllowlistedAddress{Rules: []string{}}
+	var chainID sql.NullInt64
+	err := row.Scan(&entry.ID, &chainID, &entry.Address, &entry.Label, pq.Array(&entry.Rules), &entry.ScoreFactor, &entry.Reason,
+		&entry.AddedAt, &entry.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if chainID.Valid {
+		entry.ChainID = &chainID.Int64
+	}
+	return entry, nil
+}
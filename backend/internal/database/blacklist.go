@@ -0,0 +1,437 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/minsix/backend/internal/address"
+	"github.com/minsix/backend/internal/models"
+)
+
+// BlacklistChannel is notified whenever blacklisted_addresses changes
+const BlacklistChannel = "blacklist_changed"
+
+// ErrBlacklisted is returned when adding an address that is already listed
+var ErrBlacklisted = errors.New("address is already blacklisted")
+
+// BlacklistFilter selects the blacklist entries to list
+type BlacklistFilter struct {
+	ChainID        int64  // 0 for every chain; entries for every chain always match
+	Search         string // substring of the address, reason or source
+	Category       string
+	Severity       string
+	IncludeDeleted bool // also list removed and expired entries
+	Limit          int
+	Offset         int
+}
+
+const blacklistColumns = `id, chain_id, address, reason, COALESCE(source, ''), category, severity, expires_at, added_at, COALESCE(updated_at, added_at), deleted_at`
+
+// activeBlacklist matches entries that are neither removed nor expired
+const activeBlacklist = `deleted_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())`
+
+// GetBlacklist returns every active blacklisted address on every chain
+func (db *DB) GetBlacklist() ([]*models.BlacklistedAddress, error) {
+	rows, err := db.Query(`SELECT ` + blacklistColumns + ` FROM blacklisted_addresses WHERE ` + activeBlacklist)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*models.BlacklistedAddress
+	for rows.Next() {
+		entry, err := scanBlacklistEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// ListBlacklist returns a page of blacklist entries, newest first, and the
+// number of entries matching the filter
+func (db *DB) ListBlacklist(filter BlacklistFilter) ([]*models.BlacklistedAddress, int, error) {
+	search := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(filter.Search)
+	where := `
+		WHERE ($1::bigint = 0 OR chain_id IS NULL OR chain_id = $1)
+			AND ($2::text = '' OR address ILIKE '%' || $2 || '%' OR reason ILIKE '%' || $2 || '%' OR source ILIKE '%' || $2 || '%')
+			AND ($3::text = '' OR category = $3)
+			AND ($4::text = '' OR severity = $4)
+			AND ($5::boolean OR (` + activeBlacklist + `))
+	`
+	args := []interface{}{filter.ChainID, search, filter.Category, filter.Severity, filter.IncludeDeleted}
+
+	var total int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM blacklisted_addresses `+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT ` + blacklistColumns + ` FROM blacklisted_addresses ` + where + ` ORDER BY added_at DESC, id DESC LIMIT $6 OFFSET $7`
+	rows, err := db.Query(query, append(args, filter.Limit, filter.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	entries := []*models.BlacklistedAddress{}
+	for rows.Next() {
+		entry, err := scanBlacklistEntry(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, total, rows.Err()
+}
+
+// GetBlacklistEntry returns an entry by ID, removed or not, or nil if there is none
+func (db *DB) GetBlacklistEntry(id int) (*models.BlacklistedAddress, error) {
+	entry, err := scanBlacklistEntry(db.QueryRow(`SELECT `+blacklistColumns+` FROM blacklisted_addresses WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return entry, err
+}
+
+// AddBlacklistEntry blacklists an address, restoring its entry if it was
+// removed or has expired. It returns ErrBlacklisted if the address is already
+// actively listed for the same chain scope.
+func (db *DB) AddBlacklistEntry(entry *models.BlacklistedAddress) error {
+	dbTx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	query := `
+		INSERT INTO blacklisted_addresses (chain_id, address, reason, source, category, severity, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT ((COALESCE(chain_id, 0)), address) DO UPDATE SET
+			reason = EXCLUDED.reason,
+			source = EXCLUDED.source,
+			category = EXCLUDED.category,
+			severity = EXCLUDED.severity,
+			expires_at = EXCLUDED.expires_at,
+			added_at = CURRENT_TIMESTAMP,
+			updated_at = CURRENT_TIMESTAMP,
+			deleted_at = NULL
+		WHERE blacklisted_addresses.deleted_at IS NOT NULL OR blacklisted_addresses.expires_at <= NOW()
+		RETURNING ` + blacklistColumns
+	saved, err := scanBlacklistEntry(dbTx.QueryRow(query, entry.ChainID, entry.Address, entry.Reason, entry.Source, entry.Category, entry.Severity, entry.ExpiresAt))
+	if err == sql.ErrNoRows {
+		return ErrBlacklisted
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save blacklist entry: %w", err)
+	}
+	if err := recordBlacklistChange(dbTx, saved.ID, models.BlacklistAdded); err != nil {
+		return err
+	}
+	if err := dbTx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit blacklist entry: %w", err)
+	}
+
+	*entry = *saved
+	return nil
+}
+
+// UpdateBlacklistEntry changes the provenance, severity and expiry of an
+// entry that hasn't been removed; the address and chain stay as they are.
+// It reports false if there is no such entry.
+func (db *DB) UpdateBlacklistEntry(entry *models.BlacklistedAddress) (bool, error) {
+	dbTx, err := db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	query := `
+		UPDATE blacklisted_addresses
+		SET reason = $2, source = $3, category = $4, severity = $5, expires_at = $6, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING ` + blacklistColumns
+	saved, err := scanBlacklistEntry(dbTx.QueryRow(query, entry.ID, entry.Reason, entry.Source, entry.Category, entry.Severity, entry.ExpiresAt))
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to update blacklist entry: %w", err)
+	}
+	if err := recordBlacklistChange(dbTx, saved.ID, models.BlacklistUpdated); err != nil {
+		return false, err
+	}
+	if err := dbTx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit blacklist entry: %w", err)
+	}
+
+	*entry = *saved
+	return true, nil
+}
+
+// DeleteBlacklistEntry removes an entry from the blacklist, keeping it and
+// its history; it reports false if there is no such entry
+func (db *DB) DeleteBlacklistEntry(id int) (bool, error) {
+	dbTx, err := db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	result, err := dbTx.Exec(`UPDATE blacklisted_addresses SET deleted_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP WHERE id = $1 AND deleted_at IS NULL`, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove blacklist entry: %w", err)
+	}
+	removed, err := result.RowsAffected()
+	if err != nil || removed == 0 {
+		return false, err
+	}
+	if err := recordBlacklistChange(dbTx, id, models.BlacklistRemoved); err != nil {
+		return false, err
+	}
+	if err := dbTx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit blacklist removal: %w", err)
+	}
+	return true, nil
+}
+
+// GetBlacklistHistory returns the changes to an entry, oldest first
+func (db *DB) GetBlacklistHistory(id int) ([]*models.BlacklistChange, error) {
+	rows, err := db.Query(`SELECT id, entry_id, action, entry, changed_at FROM blacklist_history WHERE entry_id = $1 ORDER BY changed_at, id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []*models.BlacklistChange{}
+	for rows.Next() {
+		change := &models.BlacklistChange{}
+		if err := rows.Scan(&change.ID, &change.EntryID, &change.Action, &change.Entry, &change.ChangedAt); err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	return changes, rows.Err()
+}
+
+// GetFeedAddresses returns the addresses a feed listed in its last import
+// for a chain, or for every chain when chainID is nil
+func (db *DB) GetFeedAddresses(source string, chainID *int64) (map[address.Address]bool, error) {
+	rows, err := db.Query(`
+		SELECT address FROM blacklist_feed_entries
+		WHERE source = $1 AND COALESCE(chain_id, 0) = COALESCE($2, 0) AND removed_at IS NULL
+	`, source, chainID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	addresses := make(map[address.Address]bool)
+	for rows.Next() {
+		var listed address.Address
+		if err := rows.Scan(&listed); err != nil {
+			return nil, err
+		}
+		addresses[listed] = true
+	}
+	return addresses, rows.Err()
+}
+
+// ApplyBlacklistImport records a feed import in one transaction: the feed's
+// current addresses, the added ones blacklisted unless already listed or
+// removed by an analyst, and with prune the removed ones taken off the
+// blacklist when it came from this feed and no other feed lists them
+func (db *DB) ApplyBlacklistImport(result *models.BlacklistImport, entries, added []*models.BlacklistedAddress, removed []address.Address, prune bool) error {
+	dbTx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	members := make([][]interface{}, 0, len(entries))
+	for _, entry := range entries {
+		members = append(members, []interface{}{result.Source, result.ChainID, entry.Address, entry.Reason})
+	}
+	err = insertRows(dbTx,
+		`INSERT INTO blacklist_feed_entries (source, chain_id, address, reason)`,
+		`ON CONFLICT (source, (COALESCE(chain_id, 0)), address) DO UPDATE SET reason = EXCLUDED.reason, last_seen_at = CURRENT_TIMESTAMP, removed_at = NULL`,
+		members, nil)
+	if err != nil {
+		return fmt.Errorf("failed to save feed entries: %w", err)
+	}
+	if len(removed) > 0 {
+		_, err := dbTx.Exec(`
+			UPDATE blacklist_feed_entries SET removed_at = CURRENT_TIMESTAMP
+			WHERE source = $1 AND COALESCE(chain_id, 0) = COALESCE($2, 0) AND address = ANY($3) AND removed_at IS NULL
+		`, result.Source, result.ChainID, pq.Array(removed))
+		if err != nil {
+			return fmt.Errorf("failed to mark removed feed entries: %w", err)
+		}
+	}
+
+	// Expired entries are renewed; ones an analyst removed stay removed
+	rows := make([][]interface{}, 0, len(added))
+	for _, entry := range added {
+		rows = append(rows, []interface{}{entry.ChainID, entry.Address, entry.Reason, entry.Source, entry.Category, entry.Severity})
+	}
+	var listed []int
+	err = insertRows(dbTx,
+		`INSERT INTO blacklisted_addresses (chain_id, address, reason, source, category, severity)`,
+		`ON CONFLICT ((COALESCE(chain_id, 0)), address) DO UPDATE SET
+			reason = EXCLUDED.reason,
+			source = EXCLUDED.source,
+			category = EXCLUDED.category,
+			severity = EXCLUDED.severity,
+			expires_at = NULL,
+			added_at = CURRENT_TIMESTAMP,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE blacklisted_addresses.deleted_at IS NULL AND blacklisted_addresses.expires_at <= NOW()
+		RETURNING id`,
+		rows, func(r *sql.Rows) error {
+			var id int
+			if err := r.Scan(&id); err != nil {
+				return err
+			}
+			listed = append(listed, id)
+			return nil
+		})
+	if err != nil {
+		return fmt.Errorf("failed to blacklist feed addresses: %w", err)
+	}
+	if err := recordBlacklistChanges(dbTx, listed, models.BlacklistAdded); err != nil {
+		return err
+	}
+	result.Listed = len(listed)
+
+	if prune && len(removed) > 0 {
+		pruned, err := queryIDs(dbTx, `
+			UPDATE blacklisted_addresses b
+			SET deleted_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+			WHERE b.source = $1 AND COALESCE(b.chain_id, 0) = COALESCE($2, 0) AND b.address = ANY($3) AND b.deleted_at IS NULL
+				AND NOT EXISTS (
+					SELECT 1 FROM blacklist_feed_entries f
+					WHERE f.address = b.address AND COALESCE(f.chain_id, 0) = COALESCE(b.chain_id, 0)
+						AND f.source <> $1 AND f.removed_at IS NULL
+				)
+			RETURNING b.id
+		`, result.Source, result.ChainID, pq.Array(removed))
+		if err != nil {
+			return fmt.Errorf("failed to prune blacklist: %w", err)
+		}
+		if err := recordBlacklistChanges(dbTx, pruned, models.BlacklistRemoved); err != nil {
+			return err
+		}
+		result.Pruned = len(pruned)
+	}
+
+	err = dbTx.QueryRow(`
+		INSERT INTO blacklist_imports (source, format, file_name, checksum, chain_id, total, added, removed, unchanged, invalid, listed, pruned)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING id, imported_at
+	`, result.Source, result.Format, result.FileName, result.Checksum, result.ChainID, result.Total, result.Added, result.Removed,
+		result.Unchanged, result.Invalid, result.Listed, result.Pruned).Scan(&result.ID, &result.ImportedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record import: %w", err)
+	}
+
+	if err := dbTx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit import: %w", err)
+	}
+	return nil
+}
+
+// GetBlacklistImports returns the latest feed imports, of one feed when
+// source is set
+func (db *DB) GetBlacklistImports(source string, limit int) ([]*models.BlacklistImport, error) {
+	query := `
+		SELECT id, source, format, file_name, checksum, chain_id, total, added, removed, unchanged, invalid, listed, pruned, imported_at
+		FROM blacklist_imports
+		WHERE ($1::text = '' OR source = $1)
+		ORDER BY imported_at DESC, id DESC
+		LIMIT $2
+	`
+	rows, err := db.Query(query, source, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	imports := []*models.BlacklistImport{}
+	for rows.Next() {
+		imp := &models.BlacklistImport{}
+		var chainID sql.NullInt64
+		err := rows.Scan(&imp.ID, &imp.Source, &imp.Format, &imp.FileName, &imp.Checksum, &chainID, &imp.Total, &imp.Added,
+			&imp.Removed, &imp.Unchanged, &imp.Invalid, &imp.Listed, &imp.Pruned, &imp.ImportedAt)
+		if err != nil {
+			return nil, err
+		}
+		if chainID.Valid {
+			imp.ChainID = &chainID.Int64
+		}
+		imports = append(imports, imp)
+	}
+	return imports, rows.Err()
+}
+
+// recordBlacklistChange snapshots an entry into its history
+func recordBlacklistChange(dbTx *sql.Tx, id int, action string) error {
+	return recordBlacklistChanges(dbTx, []int{id}, action)
+}
+
+// recordBlacklistChanges snapshots entries into their history
+func recordBlacklistChanges(dbTx *sql.Tx, ids []int, action string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := dbTx.Exec(`
+		INSERT INTO blacklist_history (entry_id, action, entry)
+		SELECT id, $2, to_jsonb(b) FROM blacklisted_addresses b WHERE id = ANY($1)
+	`, pq.Array(ids), action)
+	if err != nil {
+		return fmt.Errorf("failed to record blacklist history: %w", err)
+	}
+	return nil
+}
+
+// queryIDs runs a statement returning one id column
+func queryIDs(dbTx *sql.Tx, query string, args ...interface{}) ([]int, error) {
+	rows, err := dbTx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func scanBlacklistEntry(row interface{ Scan(...interface{}) error }) (*models.BlacklistedAddress, error) {
+	entry := &models.BlacklistedAddress{}
+	var chainID sql.NullInt64
+	var expiresAt, deletedAt sql.NullTime
+	err := row.Scan(&entry.ID, &chainID, &entry.Address, &entry.Reason, &entry.Source, &entry.Category, &entry.Severity,
+		&expiresAt, &entry.AddedAt, &entry.UpdatedAt, &deletedAt)
+	if err != nil {
+		return nil, err
+	}
+	if chainID.Valid {
+		entry.ChainID = &chainID.Int64
+	}
+	if expiresAt.Valid {
+		entry.ExpiresAt = &expiresAt.Time
+	}
+	if deletedAt.Valid {
+		entry.DeletedAt = &deletedAt.Time
+	}
+	return entry, nil
+}
@@ -1,16 +1,21 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
+	"os"
+	"path/filepath"
+	"sort"
 	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 type DB struct {
 	*sql.DB
+	connStr string // for LISTEN connections, which can't come from the pool
 }
 
 func NewDatabase(connStr string) (*DB, error) {
@@ -30,7 +35,7 @@ func NewDatabase(connStr string) (*DB, error) {
 	}
 
 	log.Println("Database connection established")
-	return &DB{db}, nil
+	return &DB{DB: db, connStr: connStr}, nil
 }
 
 func (db *DB) RunMigrations(migrationSQL string) error {
@@ -41,3 +46,190 @@ func (db *DB) RunMigrations(migrationSQL string) error {
 	log.Println("Database migrations completed")
 	return nil
 }
+
+// migrationLock is the advisory lock key held while migrating, so processes
+// starting together don't apply the same migration twice
+const migrationLock = 727368
+
+// RunMigrationsDir runs the .sql files in dir not yet recorded in
+// schema_migrations, in lexical order, each in its own database transaction.
+// Migrations must still be idempotent: databases migrated before they were
+// recorded apply every migration once more.
+func (db *DB) RunMigrationsDir(dir string) error {
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get migration connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLock); err != nil {
+		return fmt.Errorf("failed to lock migrations: %w", err)
+	}
+	defer conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, migrationLock)
+
+	_, err = conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version VARCHAR(255) PRIMARY KEY,
+			applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	applied, err := appliedMigrations(ctx, conn)
+	if err != nil {
+		return err
+	}
+	files, err := pendingMigrations(dir, applied)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		version := filepath.Base(file)
+		migrationSQL, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read migration %s: %w", file, err)
+		}
+		if err := applyMigration(ctx, conn, version, string(migrationSQL)); err != nil {
+			return fmt.Errorf("failed to run migration %s: %w", file, err)
+		}
+		log.Printf("Applied migration %s", version)
+	}
+	log.Println("Database migrations completed")
+	return nil
+}
+
+// pendingMigrations lists the .sql files in dir whose version, the file
+// name, is not in applied, in the order they must run
+func pendingMigrations(dir string, applied map[string]bool) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.sql"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list migrations: %w", err)
+	}
+	sort.Strings(files)
+
+	pending := files[:0]
+	for _, file := range files {
+		if !applied[filepath.Base(file)] {
+			pending = append(pending, file)
+		}
+	}
+	return pending, nil
+}
+
+// appliedMigrations returns the versions recorded in schema_migrations
+func appliedMigrations(ctx context.Context, conn *sql.Conn) (map[string]bool, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[string]bool)
+	for rows.Next() {
+		var version string
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		applied[version] = true
+	}
+	return applied, rows.Err()
+}
+
+// applyMigration runs a migration and records it in one database transaction
+func applyMigration(ctx context.Context, conn *sql.Conn, version, migrationSQL string) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, migrationSQL); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version) VALUES ($1)`, version); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Listen calls fn with the payload of every NOTIFY on channel until ctx is
+// cancelled. After the connection is re-established fn is called with an
+// empty payload, since notifications may have been missed meanwhile.
+func (db *DB) Listen(ctx context.Context, channel string, fn func(payload string)) error {
+	listener := pq.NewListener(db.connStr, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("Listener on %s: %v", channel, err)
+		}
+	})
+	if err := listener.Listen(channel); err != nil {
+		listener.Close()
+		return fmt.Errorf("failed to listen on %s: %w", channel, err)
+	}
+
+	go func() {
+		defer listener.Close()
+		for {
+			select {
+			case notification := <-listener.Notify:
+				// A nil notification signals a reconnect
+				if notification == nil {
+					fn("")
+					continue
+				}
+				fn(notification.Extra)
+			case <-time.After(90 * time.Second):
+				// Detect dead connections that haven't reported an error
+				go listener.Ping()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// RefreshInterval is how often a list kept in memory by WatchReload is
+// reloaded in case a change notification was lost
+const RefreshInterval = 10 * time.Minute
+
+// Notifier delivers change notifications, implemented by DB
+type Notifier interface {
+	Listen(ctx context.Context, channel string, fn func(payload string)) error
+}
+
+// WatchReload keeps an in-memory copy of a table current: it calls reload
+// whenever channel is notified, and every RefreshInterval in case a
+// notification was lost, until ctx is cancelled. Notifications arriving
+// during a reload are coalesced into one more reload; failed reloads are
+// logged and retried on the next notification or tick.
+func WatchReload(ctx context.Context, notifier Notifier, channel string, reload func() error) error {
+	pending := make(chan struct{}, 1)
+	err := notifier.Listen(ctx, channel, func(string) {
+		select {
+		case pending <- struct{}{}:
+		default:
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		ticker := time.NewTicker(RefreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-pending:
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+			if err := reload(); err != nil {
+				log.Printf("Failed to reload on %s: %v", channel, err)
+			}
+		}
+	}()
+	return nil
+}
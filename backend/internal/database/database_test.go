@@ -0,0 +1,74 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/minsix/backend/internal/database/dbtest"
+)
+
+func TestWatchReload(t *testing.T) {
+	notifier := &dbtest.Notifier{}
+	release := make(chan struct{})
+	var reloads atomic.Int32
+	reload := func() error {
+		reloads.Add(1)
+		<-release
+		return errors.New("reload failed")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := WatchReload(ctx, notifier, BlacklistChannel, reload); err != nil {
+		t.Fatal(err)
+	}
+
+	// Notifications arriving during a reload add a single reload
+	notifier.Notify(BlacklistChannel, "INSERT")
+	dbtest.Eventually(t, func() bool { return reloads.Load() == 1 }, "no reload after a notification")
+	for i := 0; i < 3; i++ {
+		notifier.Notify(BlacklistChannel, "UPDATE")
+	}
+	notifier.Notify(AllowlistChannel, "INSERT")
+	release <- struct{}{}
+
+	// A failed reload doesn't stop watching
+	dbtest.Eventually(t, func() bool { return reloads.Load() == 2 }, "notifications during a reload were dropped")
+	release <- struct{}{}
+
+	cancel()
+	time.Sleep(20 * time.Millisecond)
+	notifier.Notify(BlacklistChannel, "DELETE")
+	time.Sleep(20 * time.Millisecond)
+	if n := reloads.Load(); n != 2 {
+		t.Errorf("reloaded %d times, want 2 and none after cancel", n)
+	}
+}
+
+func TestPendingMigrations(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"002_b.sql", "001_a.sql", "010_c.sql", "README.md"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := pendingMigrations(dir, map[string]bool{"001_a.sql": true})
+	if err != nil {
+		t.Fatalf("pendingMigrations() error = %v", err)
+	}
+	want := []string{filepath.Join(dir, "002_b.sql"), filepath.Join(dir, "010_c.sql")}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("pendingMigrations() = %v, want %v", files, want)
+	}
+
+	files, err = pendingMigrations(dir, map[string]bool{"001_a.sql": true, "002_b.sql": true, "010_c.sql": true})
+	if err != nil || len(files) != 0 {
+		t.Errorf("pendingMigrations() with every migration applied = %v, %v, want none", files, err)
+	}
+}
@@ -0,0 +1,90 @@
+// Package dbtest provides fakes of database.DB for testing the caches that
+// keep a table in memory and reload it on change notifications
+package dbtest
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// List is a fake source of a table's rows; tests replace the rows between
+// loads
+type List[T any] struct {
+	mu    sync.Mutex
+	rows  []T
+	loads int
+}
+
+// NewList creates a source holding rows
+func NewList[T any](rows ...T) *List[T] {
+	return &List[T]{rows: rows}
+}
+
+// Set replaces the rows returned by later loads
+func (l *List[T]) Set(rows ...T) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rows = rows
+}
+
+// Load returns the current rows
+func (l *List[T]) Load() ([]T, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.loads++
+	return l.rows, nil
+}
+
+// Loads returns how many times the rows were loaded
+func (l *List[T]) Loads() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.loads
+}
+
+// Notifier is a fake database.Notifier whose notifications tests send
+type Notifier struct {
+	mu        sync.Mutex
+	listeners map[string][]func(payload string)
+}
+
+// Listen registers fn for the notifications on channel; ctx is ignored
+func (n *Notifier) Listen(ctx context.Context, channel string, fn func(payload string)) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.listeners == nil {
+		n.listeners = make(map[string][]func(string))
+	}
+	n.listeners[channel] = append(n.listeners[channel], fn)
+	return nil
+}
+
+// Notify delivers a notification to the listeners on channel
+func (n *Notifier) Notify(channel, payload string) {
+	n.mu.Lock()
+	listeners := n.listeners[channel]
+	n.mu.Unlock()
+	for _, fn := range listeners {
+		fn(payload)
+	}
+}
+
+// ChainID returns a pointer to id, for rows scoped to one chain
+func ChainID(id int64) *int64 {
+	return &id
+}
+
+// Eventually fails the test unless cond holds within a second, as it does
+// once a reload triggered by a notification has completed
+func Eventually(t *testing.T, cond func() bool, msg string) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal(msg)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -2,51 +2,337 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"strings"
+
 	"github.com/lib/pq"
+	"github.com/minsix/backend/internal/address"
 	"github.com/minsix/backend/internal/models"
 )
 
 // SaveTransaction inserts a new transaction
 func (db *DB) SaveTransaction(tx *models.Transaction) error {
 	query := `
-		INSERT INTO transactions (tx_hash, block_number, from_address, to_address, value, gas_price, gas_used, input_data, timestamp)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-		ON CONFLICT (tx_hash) DO NOTHING
+		INSERT INTO transactions (chain_id, tx_hash, block_number, block_hash, from_address, to_address, nonce, value, gas_price, gas_used, status, effective_gas_price, contract_address, input_data, finality, timestamp)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+		ON CONFLICT (chain_id, tx_hash) DO NOTHING
 		RETURNING id
 	`
-	err := db.QueryRow(query, tx.TxHash, tx.BlockNumber, tx.FromAddress, tx.ToAddress, tx.Value, tx.GasPrice, tx.GasUsed, tx.InputData, tx.Timestamp).Scan(&tx.ID)
+	if tx.Finality == "" {
+		tx.Finality = models.FinalityConfirmed
+	}
+	err := db.QueryRow(query, tx.ChainID, tx.TxHash, tx.BlockNumber, tx.BlockHash, tx.FromAddress, tx.ToAddress, tx.Nonce, tx.Value, tx.GasPrice, tx.GasUsed,
+		tx.Status, tx.EffectiveGasPrice, tx.ContractAddress, tx.InputData, tx.Finality, tx.Timestamp).Scan(&tx.ID)
 	if err != nil && err != sql.ErrNoRows {
 		return fmt.Errorf("failed to save transaction: %w", err)
 	}
 	return nil
 }
 
+// maxBindParams is the most bind parameters Postgres accepts in one statement
+const maxBindParams = 65535
+
+// SaveTransactions inserts a block's transactions with their logs and token
+// transfers in one database transaction, using multi-row inserts instead of
+// a round trip per row. Newly stored transactions get their IDs; ones that
+// were already stored keep ID 0 and their logs and transfers are skipped.
+func (db *DB) SaveTransactions(txs []*models.Transaction) error {
+	if len(txs) == 0 {
+		return nil
+	}
+
+	dbTx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	rows := make([][]interface{}, 0, len(txs))
+	byHash := make(map[string]*models.Transaction, len(txs))
+	for _, tx := range txs {
+		if tx.Finality == "" {
+			tx.Finality = models.FinalityConfirmed
+		}
+		tx.ID = 0
+		if _, dup := byHash[tx.TxHash]; dup {
+			continue
+		}
+		byHash[tx.TxHash] = tx
+		rows = append(rows, []interface{}{tx.ChainID, tx.TxHash, tx.BlockNumber, tx.BlockHash, tx.FromAddress, tx.ToAddress, tx.Nonce, tx.Value, tx.GasPrice, tx.GasUsed,
+			tx.Status, tx.EffectiveGasPrice, tx.ContractAddress, tx.InputData, tx.Finality, tx.Timestamp})
+	}
+	err = insertRows(dbTx,
+		`INSERT INTO transactions (chain_id, tx_hash, block_number, block_hash, from_address, to_address, nonce, value, gas_price, gas_used, status, effective_gas_price, contract_address, input_data, finality, timestamp)`,
+		`ON CONFLICT (chain_id, tx_hash) DO NOTHING RETURNING id, tx_hash`,
+		rows, func(r *sql.Rows) error {
+			var id int
+			var hash string
+			if err := r.Scan(&id, &hash); err != nil {
+				return err
+			}
+			byHash[hash].ID = id
+			return nil
+		})
+	if err != nil {
+		return fmt.Errorf("failed to save transactions: %w", err)
+	}
+
+	var logRows, transferRows [][]interface{}
+	logs := make(map[string]*models.TransactionLog)
+	transfers := make(map[string]*models.TokenTransfer)
+	for _, tx := range byHash {
+		if tx.ID == 0 {
+			continue
+		}
+		transactionID := tx.ID
+		for _, l := range tx.Logs {
+			var decoded interface{}
+			if l.Decoded != nil {
+				data, err := json.Marshal(l.Decoded)
+				if err != nil {
+					return fmt.Errorf("failed to encode decoded log: %w", err)
+				}
+				decoded = string(data)
+			}
+			l.TransactionID = &transactionID
+			logs[logKey(l.TxHash, l.LogIndex)] = l
+			logRows = append(logRows, []interface{}{tx.ChainID, transactionID, l.TxHash, l.LogIndex, l.BlockNumber, l.Address, pq.Array(l.Topics), l.Data, l.Event, decoded})
+		}
+		for _, tt := range tx.TokenTransfers {
+			tt.TransactionID = &transactionID
+			transfers[logKey(tt.TxHash, tt.LogIndex)] = tt
+			transferRows = append(transferRows, []interface{}{tx.ChainID, transactionID, tt.TxHash, tt.LogIndex, tt.BlockNumber, tt.Token, tt.FromAddress, tt.ToAddress, tt.Amount, tt.Timestamp})
+		}
+	}
+
+	err = insertRows(dbTx,
+		`INSERT INTO transaction_logs (chain_id, transaction_id, tx_hash, log_index, block_number, address, topics, data, event, decoded)`,
+		`ON CONFLICT (chain_id, tx_hash, log_index) DO NOTHING RETURNING id, tx_hash, log_index`,
+		logRows, func(r *sql.Rows) error {
+			var id, index int
+			var hash string
+			if err := r.Scan(&id, &hash, &index); err != nil {
+				return err
+			}
+			logs[logKey(hash, index)].ID = id
+			return nil
+		})
+	if err != nil {
+		return fmt.Errorf("failed to save transaction logs: %w", err)
+	}
+
+	err = insertRows(dbTx,
+		`INSERT INTO token_transfers (chain_id, transaction_id, tx_hash, log_index, block_number, token, from_address, to_address, amount, timestamp)`,
+		`ON CONFLICT (chain_id, tx_hash, log_index) DO NOTHING RETURNING id, tx_hash, log_index`,
+		transferRows, func(r *sql.Rows) error {
+			var id, index int
+			var hash string
+			if err := r.Scan(&id, &hash, &index); err != nil {
+				return err
+			}
+			transfers[logKey(hash, index)].ID = id
+			return nil
+		})
+	if err != nil {
+		return fmt.Errorf("failed to save token transfers: %w", err)
+	}
+
+	if err := dbTx.Commit(); err != nil {
+		// Nothing was stored; don't hand out IDs of rolled back rows
+		for _, tx := range txs {
+			tx.ID = 0
+		}
+		return fmt.Errorf("failed to commit transactions: %w", err)
+	}
+	return nil
+}
+
+func logKey(txHash string, logIndex int) string {
+	return fmt.Sprintf("%s/%d", txHash, logIndex)
+}
+
+// insertRows runs insert VALUES (...), (...) suffix over rows, splitting them
+// into statements that stay under the bind parameter limit, and passes each
+// returned row to scan, which may be nil when nothing is returned
+func insertRows(dbTx *sql.Tx, insert, suffix string, rows [][]interface{}, scan func(*sql.Rows) error) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	perStatement := maxBindParams / len(rows[0])
+
+	for start := 0; start < len(rows); start += perStatement {
+		end := start + perStatement
+		if end > len(rows) {
+			end = len(rows)
+		}
+
+		var query strings.Builder
+		query.WriteString(insert)
+		query.WriteString(" VALUES ")
+		args := make([]interface{}, 0, (end-start)*len(rows[0]))
+		for i, row := range rows[start:end] {
+			if i > 0 {
+				query.WriteString(", ")
+			}
+			query.WriteString("(")
+			for j, value := range row {
+				if j > 0 {
+					query.WriteString(", ")
+				}
+				args = append(args, value)
+				fmt.Fprintf(&query, "$%d", len(args))
+			}
+			query.WriteString(")")
+		}
+		query.WriteString(" ")
+		query.WriteString(suffix)
+
+		result, err := dbTx.Query(query.String(), args...)
+		if err != nil {
+			return err
+		}
+		for result.Next() && scan != nil {
+			if err := scan(result); err != nil {
+				result.Close()
+				return err
+			}
+		}
+		if err := result.Err(); err != nil {
+			result.Close()
+			return err
+		}
+		result.Close()
+	}
+	return nil
+}
+
+// SaveTransactionLogs inserts the receipt logs of a saved transaction
+func (db *DB) SaveTransactionLogs(tx *models.Transaction) error {
+	if len(tx.Logs) == 0 {
+		return nil
+	}
+
+	dbTx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	stmt, err := dbTx.Prepare(`
+		INSERT INTO transaction_logs (chain_id, transaction_id, tx_hash, log_index, block_number, address, topics, data, event, decoded)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (chain_id, tx_hash, log_index) DO NOTHING
+		RETURNING id
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare log insert: %w", err)
+	}
+	defer stmt.Close()
+
+	var transactionID *int
+	if tx.ID > 0 {
+		transactionID = &tx.ID
+	}
+
+	for _, l := range tx.Logs {
+		var decoded interface{}
+		if l.Decoded != nil {
+			data, err := json.Marshal(l.Decoded)
+			if err != nil {
+				return fmt.Errorf("failed to encode decoded log: %w", err)
+			}
+			decoded = string(data)
+		}
+
+		l.TransactionID = transactionID
+		err := stmt.QueryRow(tx.ChainID, transactionID, l.TxHash, l.LogIndex, l.BlockNumber, l.Address, pq.Array(l.Topics), l.Data, l.Event, decoded).Scan(&l.ID)
+		if err != nil && err != sql.ErrNoRows {
+			return fmt.Errorf("failed to save transaction log: %w", err)
+		}
+	}
+
+	if err := dbTx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction logs: %w", err)
+	}
+	return nil
+}
+
+// SaveTokenTransfers inserts the ERC-20 transfers of a saved transaction
+func (db *DB) SaveTokenTransfers(tx *models.Transaction) error {
+	if len(tx.TokenTransfers) == 0 {
+		return nil
+	}
+
+	dbTx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	stmt, err := dbTx.Prepare(`
+		INSERT INTO token_transfers (chain_id, transaction_id, tx_hash, log_index, block_number, token, from_address, to_address, amount, timestamp)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (chain_id, tx_hash, log_index) DO NOTHING
+		RETURNING id
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare token transfer insert: %w", err)
+	}
+	defer stmt.Close()
+
+	var transactionID *int
+	if tx.ID > 0 {
+		transactionID = &tx.ID
+	}
+
+	for _, tt := range tx.TokenTransfers {
+		tt.TransactionID = transactionID
+		err := stmt.QueryRow(tx.ChainID, transactionID, tt.TxHash, tt.LogIndex, tt.BlockNumber, tt.Token, tt.FromAddress, tt.ToAddress, tt.Amount, tt.Timestamp).Scan(&tt.ID)
+		if err != nil && err != sql.ErrNoRows {
+			return fmt.Errorf("failed to save token transfer: %w", err)
+		}
+	}
+
+	if err := dbTx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit token transfers: %w", err)
+	}
+	return nil
+}
+
 // FlagTransaction creates a flagged transaction record
 func (db *DB) FlagTransaction(flag *models.FlaggedTransaction) error {
+	details, err := json.Marshal(reasonDetails(flag.ReasonDetails))
+	if err != nil {
+		return fmt.Errorf("failed to encode reason details: %w", err)
+	}
+
 	query := `
-		INSERT INTO flagged_transactions (transaction_id, tx_hash, risk_score, reasons, status)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO flagged_transactions (chain_id, transaction_id, tx_hash, risk_score, reasons, reason_details, status, watched_wallets)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, COALESCE($8, '{}'::text[]))
 		RETURNING id, flagged_at
 	`
-	err := db.QueryRow(query, flag.TransactionID, flag.TxHash, flag.RiskScore, pq.Array(flag.Reasons), flag.Status).Scan(&flag.ID, &flag.FlaggedAt)
+	err = db.QueryRow(query, flag.ChainID, flag.TransactionID, flag.TxHash, flag.RiskScore, pq.Array(flag.Reasons), string(details), flag.Status,
+		pq.Array(flag.WatchedWallets)).Scan(&flag.ID, &flag.FlaggedAt)
 	if err != nil {
 		return fmt.Errorf("failed to flag transaction: %w", err)
 	}
 	return nil
 }
 
-// GetFlaggedTransactions retrieves flagged transactions with optional limit
-func (db *DB) GetFlaggedTransactions(limit int) ([]*models.FlaggedTransaction, error) {
+// GetFlaggedTransactions retrieves flagged transactions with optional limit;
+// chainID 0 includes every chain
+func (db *DB) GetFlaggedTransactions(chainID int64, limit int) ([]*models.FlaggedTransaction, error) {
 	query := `
-		SELECT ft.id, ft.transaction_id, ft.tx_hash, ft.risk_score, ft.reasons, ft.flagged_at, ft.status,
+		SELECT ft.id, ft.chain_id, ft.transaction_id, ft.tx_hash, ft.risk_score, ft.reasons, ft.reason_details, ft.flagged_at, ft.status, ft.watched_wallets,
 		       t.block_number, t.from_address, t.to_address, t.value, t.gas_price, t.timestamp
 		FROM flagged_transactions ft
 		LEFT JOIN transactions t ON ft.transaction_id = t.id
+		WHERE $1::bigint = 0 OR ft.chain_id = $1
 		ORDER BY ft.flagged_at DESC
-		LIMIT $1
+		LIMIT $2
 	`
-	rows, err := db.Query(query, limit)
+	rows, err := db.Query(query, chainID, limit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get flagged transactions: %w", err)
 	}
@@ -54,41 +340,78 @@ func (db *DB) GetFlaggedTransactions(limit int) ([]*models.FlaggedTransaction, e
 
 	var results []*models.FlaggedTransaction
 	for rows.Next() {
-		ft := &models.FlaggedTransaction{Transaction: &models.Transaction{}}
+		ft := &models.FlaggedTransaction{}
 		var reasons pq.StringArray
+		var details []byte
+		// Transaction columns are NULL for flags whose transaction was rolled back
+		var blockNumber sql.NullInt64
+		var value, gasPrice sql.NullString
+		var fromAddress, toAddress *address.Address
+		var timestamp sql.NullTime
 		err := rows.Scan(
-			&ft.ID, &ft.TransactionID, &ft.TxHash, &ft.RiskScore, &reasons, &ft.FlaggedAt, &ft.Status,
-			&ft.Transaction.BlockNumber, &ft.Transaction.FromAddress, &ft.Transaction.ToAddress,
-			&ft.Transaction.Value, &ft.Transaction.GasPrice, &ft.Transaction.Timestamp,
+			&ft.ID, &ft.ChainID, &ft.TransactionID, &ft.TxHash, &ft.RiskScore, &reasons, &details, &ft.FlaggedAt, &ft.Status, pq.Array(&ft.WatchedWallets),
+			&blockNumber, &fromAddress, &toAddress, &value, &gasPrice, &timestamp,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan flagged transaction: %w", err)
 		}
 		ft.Reasons = reasons
-		ft.Transaction.TxHash = ft.TxHash
+		if err := json.Unmarshal(details, &ft.ReasonDetails); err != nil {
+			return nil, fmt.Errorf("failed to decode reason details: %w", err)
+		}
+		if blockNumber.Valid {
+			ft.Transaction = &models.Transaction{
+				ChainID:     ft.ChainID,
+				TxHash:      ft.TxHash,
+				BlockNumber: blockNumber.Int64,
+				FromAddress: *fromAddress,
+				ToAddress:   toAddress,
+				Value:       value.String,
+				GasPrice:    gasPrice.String,
+				Timestamp:   timestamp.Time,
+			}
+		}
 		results = append(results, ft)
 	}
 	return results, nil
 }
 
-// IsBlacklisted checks if an address is blacklisted
-func (db *DB) IsBlacklisted(address string) (bool, error) {
+// IsBlacklisted checks if an address is blacklisted on a chain, either for
+// that chain alone or for every chain; chainID 0 matches any chain
+func (db *DB) IsBlacklisted(chainID int64, wallet address.Address) (bool, error) {
 	var exists bool
-	query := `SELECT EXISTS(SELECT 1 FROM blacklisted_addresses WHERE address = $1)`
-	err := db.QueryRow(query, address).Scan(&exists)
+	query := `
+		SELECT EXISTS(
+			SELECT 1 FROM blacklisted_addresses
+			WHERE address = $2 AND ($1::bigint = 0 OR chain_id IS NULL OR chain_id = $1)
+				AND deleted_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())
+		)
+	`
+	err := db.QueryRow(query, chainID, wallet).Scan(&exists)
 	return exists, err
 }
 
-// GetWalletTransactions gets transactions for a specific wallet
-func (db *DB) GetWalletTransactions(address string, limit int) ([]*models.Transaction, error) {
+// HasTransaction reports whether a transaction is stored
+func (db *DB) HasTransaction(chainID int64, txHash string) (bool, error) {
+	var stored bool
+	err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM transactions WHERE chain_id = $1 AND tx_hash = $2)`, chainID, txHash).Scan(&stored)
+	if err != nil {
+		return false, fmt.Errorf("failed to look up transaction: %w", err)
+	}
+	return stored, nil
+}
+
+// GetWalletTransactions gets transactions for a specific wallet; chainID 0
+// includes every chain
+func (db *DB) GetWalletTransactions(chainID int64, wallet address.Address, limit int) ([]*models.Transaction, error) {
 	query := `
-		SELECT id, tx_hash, block_number, from_address, to_address, value, gas_price, gas_used, timestamp
+		SELECT id, chain_id, tx_hash, block_number, block_hash, from_address, to_address, nonce, value, gas_price, gas_used, status, effective_gas_price, contract_address, finality, timestamp
 		FROM transactions
-		WHERE from_address = $1 OR to_address = $1
+		WHERE (from_address = $2 OR to_address = $2) AND ($1::bigint = 0 OR chain_id = $1)
 		ORDER BY timestamp DESC
-		LIMIT $2
+		LIMIT $3
 	`
-	rows, err := db.Query(query, address, limit)
+	rows, err := db.Query(query, chainID, wallet, limit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get wallet transactions: %w", err)
 	}
@@ -97,7 +420,8 @@ func (db *DB) GetWalletTransactions(address string, limit int) ([]*models.Transa
 	var results []*models.Transaction
 	for rows.Next() {
 		tx := &models.Transaction{}
-		err := rows.Scan(&tx.ID, &tx.TxHash, &tx.BlockNumber, &tx.FromAddress, &tx.ToAddress, &tx.Value, &tx.GasPrice, &tx.GasUsed, &tx.Timestamp)
+		err := rows.Scan(&tx.ID, &tx.ChainID, &tx.TxHash, &tx.BlockNumber, &tx.BlockHash, &tx.FromAddress, &tx.ToAddress, &tx.Nonce, &tx.Value, &tx.GasPrice, &tx.GasUsed,
+			&tx.Status, &tx.EffectiveGasPrice, &tx.ContractAddress, &tx.Finality, &tx.Timestamp)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan transaction: %w", err)
 		}
@@ -106,21 +430,55 @@ func (db *DB) GetWalletTransactions(address string, limit int) ([]*models.Transa
 	return results, nil
 }
 
-// UpdateStatistic updates a platform statistic
-func (db *DB) UpdateStatistic(name string, value float64) error {
+// GetWalletTokenTransfers gets ERC-20 transfers sent or received by a wallet;
+// chainID 0 includes every chain
+func (db *DB) GetWalletTokenTransfers(chainID int64, wallet address.Address, limit int) ([]*models.TokenTransfer, error) {
 	query := `
-		INSERT INTO statistics (metric_name, metric_value, updated_at)
-		VALUES ($1, $2, NOW())
-		ON CONFLICT (metric_name) DO UPDATE SET metric_value = $2, updated_at = NOW()
+		SELECT id, chain_id, transaction_id, tx_hash, log_index, block_number, token, from_address, to_address, amount, timestamp
+		FROM token_transfers
+		WHERE (from_address = $2 OR to_address = $2) AND ($1::bigint = 0 OR chain_id = $1)
+		ORDER BY timestamp DESC, log_index DESC
+		LIMIT $3
 	`
-	_, err := db.Exec(query, name, value)
+	rows, err := db.Query(query, chainID, wallet, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wallet token transfers: %w", err)
+	}
+	defer rows.Close()
+
+	var results []*models.TokenTransfer
+	for rows.Next() {
+		tt := &models.TokenTransfer{}
+		err := rows.Scan(&tt.ID, &tt.ChainID, &tt.TransactionID, &tt.TxHash, &tt.LogIndex, &tt.BlockNumber, &tt.Token, &tt.FromAddress, &tt.ToAddress, &tt.Amount, &tt.Timestamp)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan token transfer: %w", err)
+		}
+		results = append(results, tt)
+	}
+	return results, nil
+}
+
+// UpdateStatistic updates a platform statistic of a chain
+func (db *DB) UpdateStatistic(chainID int64, name string, value float64) error {
+	query := `
+		INSERT INTO statistics (chain_id, metric_name, metric_value, updated_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (chain_id, metric_name) DO UPDATE SET metric_value = $3, updated_at = NOW()
+	`
+	_, err := db.Exec(query, chainID, name, value)
 	return err
 }
 
-// GetStatistics retrieves all platform statistics
-func (db *DB) GetStatistics() (map[string]float64, error) {
-	query := `SELECT metric_name, metric_value FROM statistics`
-	rows, err := db.Query(query)
+// GetStatistics retrieves the platform statistics of a chain; chainID 0 sums
+// them across chains
+func (db *DB) GetStatistics(chainID int64) (map[string]float64, error) {
+	query := `
+		SELECT metric_name, SUM(metric_value)
+		FROM statistics
+		WHERE $1::bigint = 0 OR chain_id = $1
+		GROUP BY metric_name
+	`
+	rows, err := db.Query(query, chainID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get statistics: %w", err)
 	}
@@ -138,15 +496,334 @@ func (db *DB) GetStatistics() (map[string]float64, error) {
 	return stats, nil
 }
 
-// IncrementStatistic increments a statistic by a delta value
-func (db *DB) IncrementStatistic(name string, delta float64) error {
+// IncrementStatistic increments a statistic of a chain by a delta value
+func (db *DB) IncrementStatistic(chainID int64, name string, delta float64) error {
 	query := `
-		INSERT INTO statistics (metric_name, metric_value, updated_at)
-		VALUES ($1, $2, NOW())
-		ON CONFLICT (metric_name) DO UPDATE SET 
-			metric_value = statistics.metric_value + $2,
+		INSERT INTO statistics (chain_id, metric_name, metric_value, updated_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (chain_id, metric_name) DO UPDATE SET 
+			metric_value = statistics.metric_value + $3,
 			updated_at = NOW()
 	`
-	_, err := db.Exec(query, name, delta)
+	_, err := db.Exec(query, chainID, name, delta)
 	return err
 }
+
+// reasonDetails ensures nil details are stored as an empty JSON array
+func reasonDetails(details []models.FlagReason) []models.FlagReason {
+	if details == nil {
+		return []models.FlagReason{}
+	}
+	return details
+}
+
+// SaveSuspectedDrainer records an operator escalated to the suspected-drainer list
+func (db *DB) SaveSuspectedDrainer(drainer *models.SuspectedDrainer) error {
+	query := `
+		INSERT INTO suspected_drainers (chain_id, address, victim_count, reason, escalated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (chain_id, address) DO UPDATE SET victim_count = GREATEST(suspected_drainers.victim_count, $3)
+		RETURNING id
+	`
+	err := db.QueryRow(query, drainer.ChainID, drainer.Address, drainer.VictimCount, drainer.Reason, drainer.EscalatedAt).Scan(&drainer.ID)
+	if err != nil {
+		return fmt.Errorf("failed to save suspected drainer: %w", err)
+	}
+	return nil
+}
+
+// GetSuspectedDrainers retrieves suspected drainers, most recently escalated
+// first; chainID 0 includes every chain
+func (db *DB) GetSuspectedDrainers(chainID int64, limit int) ([]*models.SuspectedDrainer, error) {
+	query := `
+		SELECT id, chain_id, address, victim_count, reason, escalated_at
+		FROM suspected_drainers
+		WHERE $1::bigint = 0 OR chain_id = $1
+		ORDER BY escalated_at DESC
+		LIMIT $2
+	`
+	rows, err := db.Query(query, chainID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get suspected drainers: %w", err)
+	}
+	defer rows.Close()
+
+	var results []*models.SuspectedDrainer
+	for rows.Next() {
+		d := &models.SuspectedDrainer{}
+		if err := rows.Scan(&d.ID, &d.ChainID, &d.Address, &d.VictimCount, &d.Reason, &d.EscalatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan suspected drainer: %w", err)
+		}
+		results = append(results, d)
+	}
+	return results, nil
+}
+
+// SeedTokens inserts bundled token metadata for a chain without overwriting
+// rows edited through the API
+func (db *DB) SeedTokens(chainID int64, tokens []*models.Token) error {
+	dbTx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	stmt, err := dbTx.Prepare(`
+		INSERT INTO tokens (chain_id, address, symbol, name, decimals)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (chain_id, address) DO NOTHING
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare token insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, token := range tokens {
+		token.ChainID = chainID
+		if _, err := stmt.Exec(chainID, token.Address, token.Symbol, token.Name, token.Decimals); err != nil {
+			return fmt.Errorf("failed to seed token %s: %w", token.Address, err)
+		}
+	}
+
+	if err := dbTx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit tokens: %w", err)
+	}
+	return nil
+}
+
+// UpsertToken creates or replaces token metadata
+func (db *DB) UpsertToken(token *models.Token) error {
+	query := `
+		INSERT INTO tokens (chain_id, address, symbol, name, decimals)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (chain_id, address) DO UPDATE
+		SET symbol = $3, name = $4, decimals = $5, updated_at = CURRENT_TIMESTAMP
+		RETURNING created_at, updated_at
+	`
+	err := db.QueryRow(query, token.ChainID, token.Address, token.Symbol, token.Name, token.Decimals).Scan(&token.CreatedAt, &token.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save token: %w", err)
+	}
+	return nil
+}
+
+// DeleteToken removes token metadata, reporting whether the token existed
+func (db *DB) DeleteToken(chainID int64, token address.Address) (bool, error) {
+	result, err := db.Exec(`DELETE FROM tokens WHERE chain_id = $1 AND address = $2`, chainID, token)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete token: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete token: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// GetTokens retrieves token metadata ordered by symbol; chainID 0 includes every chain
+func (db *DB) GetTokens(chainID int64) ([]*models.Token, error) {
+	query := `
+		SELECT chain_id, address, symbol, name, decimals, created_at, updated_at
+		FROM tokens
+		WHERE $1::bigint = 0 OR chain_id = $1
+		ORDER BY chain_id, symbol, address
+	`
+	rows, err := db.Query(query, chainID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tokens: %w", err)
+	}
+	defer rows.Close()
+
+	var results []*models.Token
+	for rows.Next() {
+		token := &models.Token{}
+		if err := rows.Scan(&token.ChainID, &token.Address, &token.Symbol, &token.Name, &token.Decimals, &token.CreatedAt, &token.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan token: %w", err)
+		}
+		results = append(results, token)
+	}
+	return results, nil
+}
+
+// GetSyncState returns the last fully processed block for a chain; ok is
+// false when the chain has never been synced
+func (db *DB) GetSyncState(chainID int64) (uint64, bool, error) {
+	var last uint64
+	err := db.QueryRow(`SELECT last_block FROM sync_state WHERE chain_id = $1`, chainID).Scan(&last)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get sync state: %w", err)
+	}
+	return last, true, nil
+}
+
+// SaveSyncState records the last fully processed block for a chain
+func (db *DB) SaveSyncState(chainID int64, block uint64) error {
+	query := `
+		INSERT INTO sync_state (chain_id, last_block, updated_at)
+		VALUES ($1, $2, CURRENT_TIMESTAMP)
+		ON CONFLICT (chain_id) DO UPDATE SET last_block = $2, updated_at = CURRENT_TIMESTAMP
+	`
+	if _, err := db.Exec(query, chainID, block); err != nil {
+		return fmt.Errorf("failed to save sync state: %w", err)
+	}
+	return nil
+}
+
+// SaveBlock records a processed block, replacing any block previously stored at its height
+func (db *DB) SaveBlock(block *models.Block) error {
+	query := `
+		INSERT INTO blocks (chain_id, number, hash, parent_hash, timestamp)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (chain_id, number) DO UPDATE SET hash = $3, parent_hash = $4, timestamp = $5
+	`
+	if _, err := db.Exec(query, block.ChainID, block.Number, block.Hash, block.ParentHash, block.Timestamp); err != nil {
+		return fmt.Errorf("failed to save block: %w", err)
+	}
+	return nil
+}
+
+// PruneBlocks drops blocks below a height, which are too deep to be reorganized
+func (db *DB) PruneBlocks(chainID int64, below uint64) error {
+	if _, err := db.Exec(`DELETE FROM blocks WHERE chain_id = $1 AND number < $2`, chainID, below); err != nil {
+		return fmt.Errorf("failed to prune blocks: %w", err)
+	}
+	return nil
+}
+
+// GetRecentBlocks returns up to limit of the highest processed blocks of a chain
+func (db *DB) GetRecentBlocks(chainID int64, limit int) ([]*models.Block, error) {
+	query := `
+		SELECT chain_id, number, hash, parent_hash, timestamp
+		FROM blocks
+		WHERE chain_id = $1
+		ORDER BY number DESC
+		LIMIT $2
+	`
+	rows, err := db.Query(query, chainID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recent blocks: %w", err)
+	}
+	defer rows.Close()
+
+	var results []*models.Block
+	for rows.Next() {
+		b := &models.Block{}
+		if err := rows.Scan(&b.ChainID, &b.Number, &b.Hash, &b.ParentHash, &b.Timestamp); err != nil {
+			return nil, fmt.Errorf("failed to scan block: %w", err)
+		}
+		results = append(results, b)
+	}
+	return results, nil
+}
+
+// GetOrphanedTransactions gets the transactions stored for orphaned blocks,
+// before RollbackBlocks removes them
+func (db *DB) GetOrphanedTransactions(chainID int64, orphaned []*models.Block) ([]*models.Transaction, error) {
+	hashes := make([]string, 0, len(orphaned))
+	for _, block := range orphaned {
+		hashes = append(hashes, block.Hash)
+	}
+
+	query := `
+		SELECT id, chain_id, tx_hash, block_number, block_hash, from_address, to_address, input_data, timestamp
+		FROM transactions
+		WHERE chain_id = $1 AND block_hash = ANY($2)
+	`
+	rows, err := db.Query(query, chainID, pq.Array(hashes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get orphaned transactions: %w", err)
+	}
+	defer rows.Close()
+
+	var results []*models.Transaction
+	for rows.Next() {
+		tx := &models.Transaction{}
+		if err := rows.Scan(&tx.ID, &tx.ChainID, &tx.TxHash, &tx.BlockNumber, &tx.BlockHash, &tx.FromAddress, &tx.ToAddress, &tx.InputData, &tx.Timestamp); err != nil {
+			return nil, fmt.Errorf("failed to scan transaction: %w", err)
+		}
+		results = append(results, tx)
+	}
+	return results, nil
+}
+
+// RollbackBlocks removes the transactions of orphaned blocks, together with
+// their logs and token transfers, and marks their flags as reorged. Flags are
+// kept but detached from the deleted transactions. It returns the number of
+// transactions removed and flags marked.
+func (db *DB) RollbackBlocks(chainID int64, orphaned []*models.Block) (int, int, error) {
+	hashes := make([]string, 0, len(orphaned))
+	for _, block := range orphaned {
+		hashes = append(hashes, block.Hash)
+	}
+
+	dbTx, err := db.Begin()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	flags, err := dbTx.Exec(`
+		UPDATE flagged_transactions SET status = 'reorged', transaction_id = NULL
+		WHERE transaction_id IN (SELECT id FROM transactions WHERE chain_id = $1 AND block_hash = ANY($2))
+	`, chainID, pq.Array(hashes))
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to mark reorged flags: %w", err)
+	}
+	txs, err := dbTx.Exec(`DELETE FROM transactions WHERE chain_id = $1 AND block_hash = ANY($2)`, chainID, pq.Array(hashes))
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to delete orphaned transactions: %w", err)
+	}
+	if _, err := dbTx.Exec(`DELETE FROM blocks WHERE chain_id = $1 AND hash = ANY($2)`, chainID, pq.Array(hashes)); err != nil {
+		return 0, 0, fmt.Errorf("failed to delete orphaned blocks: %w", err)
+	}
+
+	if err := dbTx.Commit(); err != nil {
+		return 0, 0, fmt.Errorf("failed to commit rollback: %w", err)
+	}
+
+	flagCount, _ := flags.RowsAffected()
+	txCount, _ := txs.RowsAffected()
+	return int(txCount), int(flagCount), nil
+}
+
+// ConfirmTransactions marks a chain's tentative transactions up to a block as
+// confirmed and returns the live flags of the newly confirmed transactions
+func (db *DB) ConfirmTransactions(chainID int64, upTo uint64) ([]*models.FlaggedTransaction, error) {
+	query := `
+		WITH confirmed AS (
+			UPDATE transactions SET finality = 'confirmed'
+			WHERE finality = 'tentative' AND chain_id = $1 AND block_number <= $2
+			RETURNING id
+		)
+		SELECT ft.id, ft.chain_id, ft.transaction_id, ft.tx_hash, ft.risk_score, ft.reasons, ft.reason_details, ft.flagged_at, ft.status, ft.watched_wallets
+		FROM flagged_transactions ft
+		JOIN confirmed c ON ft.transaction_id = c.id
+		WHERE ft.status <> 'reorged'
+		ORDER BY ft.id
+	`
+	rows, err := db.Query(query, chainID, upTo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to confirm transactions: %w", err)
+	}
+	defer rows.Close()
+
+	var results []*models.FlaggedTransaction
+	for rows.Next() {
+		ft := &models.FlaggedTransaction{}
+		var reasons pq.StringArray
+		var details []byte
+		err := rows.Scan(&ft.ID, &ft.ChainID, &ft.TransactionID, &ft.TxHash, &ft.RiskScore, &reasons, &details, &ft.FlaggedAt, &ft.Status, pq.Array(&ft.WatchedWallets))
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan confirmed flag: %w", err)
+		}
+		ft.Reasons = reasons
+		if err := json.Unmarshal(details, &ft.ReasonDetails); err != nil {
+			return nil, fmt.Errorf("failed to decode reason details: %w", err)
+		}
+		results = append(results, ft)
+	}
+	return results, rows.Err()
+}
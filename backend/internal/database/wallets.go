@@ -0,0 +1,135 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/minsix/backend/internal/models"
+)
+
+// WalletsChannel is notified whenever wallets are added to, relabeled in or
+// removed from monitored_wallets
+const WalletsChannel = "wallets_changed"
+
+// ErrWalletMonitored is returned when adding a wallet that is already monitored
+var ErrWalletMonitored = errors.New("wallet is already monitored")
+
+const walletColumns = `id, chain_id, address, label, added_at, last_checked`
+
+// GetMonitoredWallets returns the wallets monitored on a chain, including
+// those monitored on every chain, oldest first; chainID 0 returns every wallet
+func (db *DB) GetMonitoredWallets(chainID int64) ([]*models.MonitoredWallet, error) {
+	query := `SELECT ` + walletColumns + ` FROM monitored_wallets
+		WHERE $1::bigint = 0 OR chain_id IS NULL OR chain_id = $1
+		ORDER BY added_at, id`
+	rows, err := db.Query(query, chainID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get monitored wallets: %w", err)
+	}
+	defer rows.Close()
+
+	wallets := []*models.MonitoredWallet{}
+	for rows.Next() {
+		wallet, err := scanMonitoredWallet(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan monitored wallet: %w", err)
+		}
+		wallets = append(wallets, wallet)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get monitored wallets: %w", err)
+	}
+	return wallets, nil
+}
+
+// GetMonitoredWallet returns a wallet by ID, or nil if there is none
+func (db *DB) GetMonitoredWallet(id int) (*models.MonitoredWallet, error) {
+	wallet, err := scanMonitoredWallet(db.QueryRow(`SELECT `+walletColumns+` FROM monitored_wallets WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get monitored wallet: %w", err)
+	}
+	return wallet, nil
+}
+
+// AddMonitoredWallet starts monitoring a wallet; it returns
+// ErrWalletMonitored if the wallet is already monitored for the same chain scope
+func (db *DB) AddMonitoredWallet(wallet *models.MonitoredWallet) error {
+	query := `
+		INSERT INTO monitored_wallets (chain_id, address, label)
+		VALUES ($1, $2, $3)
+		ON CONFLICT ((COALESCE(chain_id, 0)), address) DO NOTHING
+		RETURNING ` + walletColumns
+	saved, err := scanMonitoredWallet(db.QueryRow(query, wallet.ChainID, wallet.Address, wallet.Label))
+	if err == sql.ErrNoRows {
+		return ErrWalletMonitored
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save monitored wallet: %w", err)
+	}
+
+	*wallet = *saved
+	return nil
+}
+
+// UpdateMonitoredWallet changes the label of a wallet, reporting false if
+// there is no such wallet
+func (db *DB) UpdateMonitoredWallet(wallet *models.MonitoredWallet) (bool, error) {
+	query := `UPDATE monitored_wallets SET label = $2 WHERE id = $1 RETURNING ` + walletColumns
+	saved, err := scanMonitoredWallet(db.QueryRow(query, wallet.ID, wallet.Label))
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to update monitored wallet: %w", err)
+	}
+
+	*wallet = *saved
+	return true, nil
+}
+
+// DeleteMonitoredWallet stops monitoring a wallet, reporting whether it was monitored
+func (db *DB) DeleteMonitoredWallet(id int) (bool, error) {
+	result, err := db.Exec(`DELETE FROM monitored_wallets WHERE id = $1`, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete monitored wallet: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete monitored wallet: %w", err)
+	}
+	return deleted > 0, nil
+}
+
+// TouchMonitoredWallets records that activity of the wallets was just analyzed
+func (db *DB) TouchMonitoredWallets(ids []int) error {
+	_, err := db.Exec(`UPDATE monitored_wallets SET last_checked = CURRENT_TIMESTAMP WHERE id = ANY($1)`, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("failed to update monitored wallets: %w", err)
+	}
+	return nil
+}
+
+func scanMonitoredWallet(row interface{ Scan(...interface{}) error }) (*models.MonitoredWallet, error) {
+	wallet := &models.MonitoredWallet{}
+	var chainID sql.NullInt64
+	var label sql.NullString
+	var lastChecked sql.NullTime
+	if err := row.Scan(&wallet.ID, &chainID, &wallet.Address, &label, &wallet.AddedAt, &lastChecked); err != nil {
+		return nil, err
+	}
+	if chainID.Valid {
+		wallet.ChainID = &chainID.Int64
+	}
+	if label.Valid {
+		wallet.Label = &label.String
+	}
+	if lastChecked.Valid {
+		wallet.LastChecked = &lastChecked.Time
+	}
+	return wallet, nil
+}
@@ -0,0 +1,141 @@
+package decoder
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/minsix/backend/internal/address"
+)
+
+// Token methods understood by the decoder
+const (
+	MethodTransfer          = "transfer"
+	MethodTransferFrom      = "transferFrom"
+	MethodApprove           = "approve"
+	MethodIncreaseAllowance = "increaseAllowance"
+	MethodSetApprovalForAll = "setApprovalForAll"
+	MethodPermit            = "permit"
+)
+
+// tokenABI covers the ERC-20, ERC-721 and EIP-2612 methods used by drainers
+const tokenABI = `[
+	{"type":"function","name":"transfer","inputs":[{"name":"to","type":"address"},{"name":"amount","type":"uint256"}]},
+	{"type":"function","name":"transferFrom","inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"amount","type":"uint256"}]},
+	{"type":"function","name":"approve","inputs":[{"name":"spender","type":"address"},{"name":"amount","type":"uint256"}]},
+	{"type":"function","name":"increaseAllowance","inputs":[{"name":"spender","type":"address"},{"name":"addedValue","type":"uint256"}]},
+	{"type":"function","name":"setApprovalForAll","inputs":[{"name":"operator","type":"address"},{"name":"approved","type":"bool"}]},
+	{"type":"function","name":"permit","inputs":[{"name":"owner","type":"address"},{"name":"spender","type":"address"},{"name":"value","type":"uint256"},{"name":"deadline","type":"uint256"},{"name":"v","type":"uint8"},{"name":"r","type":"bytes32"},{"name":"s","type":"bytes32"}]}
+]`
+
+var (
+	// ErrUnknownMethod is returned for calldata whose selector is not a supported token method
+	ErrUnknownMethod = errors.New("unknown method selector")
+
+	parsedABI abi.ABI
+)
+
+func init() {
+	var err error
+	parsedABI, err = abi.JSON(strings.NewReader(tokenABI))
+	if err != nil {
+		panic(fmt.Sprintf("invalid token ABI: %v", err))
+	}
+}
+
+// Call is a decoded token method call. Only the fields relevant to the
+// method are set; for ERC-721 transferFrom, Amount holds the token ID.
+type Call struct {
+	Method    string          `json:"method"`
+	Selector  string          `json:"selector"`
+	Owner     address.Address `json:"owner,omitempty"`
+	From      address.Address `json:"from,omitempty"`
+	Recipient address.Address `json:"recipient,omitempty"`
+	Spender   address.Address `json:"spender,omitempty"`
+	Operator  address.Address `json:"operator,omitempty"`
+	Amount    *big.Int        `json:"amount,omitempty"`
+	Approved  bool            `json:"approved,omitempty"`
+	Deadline  *big.Int        `json:"deadline,omitempty"`
+}
+
+// IsApproval reports whether the call grants an allowance to a spender
+func (c *Call) IsApproval() bool {
+	return c.Method == MethodApprove || c.Method == MethodIncreaseAllowance || c.Method == MethodPermit
+}
+
+// IsTransfer reports whether the call moves tokens directly
+func (c *Call) IsTransfer() bool {
+	return c.Method == MethodTransfer || c.Method == MethodTransferFrom
+}
+
+// Selector returns the lowercase 4-byte selector of hex calldata, or "" if it is too short
+func Selector(input string) string {
+	input = strings.ToLower(strings.TrimPrefix(input, "0x"))
+	if len(input) < 8 {
+		return ""
+	}
+	return "0x" + input[:8]
+}
+
+// Decode parses hex-encoded calldata into a token method call
+func Decode(input string) (*Call, error) {
+	data, err := hex.DecodeString(strings.TrimPrefix(strings.ToLower(input), "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid calldata: %w", err)
+	}
+	if len(data) < 4 {
+		return nil, fmt.Errorf("calldata too short for a method selector")
+	}
+
+	method, err := parsedABI.MethodById(data[:4])
+	if err != nil {
+		return nil, ErrUnknownMethod
+	}
+
+	args, err := method.Inputs.Unpack(data[4:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s arguments: %w", method.Name, err)
+	}
+
+	call := &Call{
+		Method:   method.Name,
+		Selector: "0x" + hex.EncodeToString(data[:4]),
+	}
+
+	switch method.Name {
+	case MethodTransfer:
+		call.Recipient = addressArg(args[0])
+		call.Amount = args[1].(*big.Int)
+	case MethodTransferFrom:
+		call.From = addressArg(args[0])
+		call.Recipient = addressArg(args[1])
+		call.Amount = args[2].(*big.Int)
+	case MethodApprove, MethodIncreaseAllowance:
+		call.Spender = addressArg(args[0])
+		call.Amount = args[1].(*big.Int)
+	case MethodSetApprovalForAll:
+		call.Operator = addressArg(args[0])
+		call.Approved = args[1].(bool)
+	case MethodPermit:
+		call.Owner = addressArg(args[0])
+		call.Spender = addressArg(args[1])
+		call.Amount = args[2].(*big.Int)
+		call.Deadline = args[3].(*big.Int)
+	}
+
+	return call, nil
+}
+
+// IsMaxUint256 reports whether an amount is the conventional "unlimited" allowance
+func IsMaxUint256(amount *big.Int) bool {
+	return amount != nil && amount.Cmp(math.MaxBig256) == 0
+}
+
+func addressArg(arg interface{}) address.Address {
+	return address.FromCommon(arg.(common.Address))
+}
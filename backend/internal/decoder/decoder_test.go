@@ -0,0 +1,103 @@
+package decoder
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/minsix/backend/internal/address"
+)
+
+func pack(t *testing.T, method string, args ...interface{}) string {
+	t.Helper()
+	data, err := parsedABI.Pack(method, args...)
+	if err != nil {
+		t.Fatalf("failed to pack %s: %v", method, err)
+	}
+	return fmt.Sprintf("0x%x", data)
+}
+
+func TestDecode(t *testing.T) {
+	alice := common.HexToAddress("0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb0")
+	bob := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	amount := big.NewInt(1e18)
+
+	tests := []struct {
+		name  string
+		input string
+		check func(*Call) bool
+	}{
+		{
+			name:  "transfer",
+			input: pack(t, MethodTransfer, bob, amount),
+			check: func(c *Call) bool {
+				return c.IsTransfer() && c.Recipient == address.FromCommon(bob) && c.Amount.Cmp(amount) == 0
+			},
+		},
+		{
+			name:  "transferFrom",
+			input: pack(t, MethodTransferFrom, alice, bob, amount),
+			check: func(c *Call) bool {
+				return c.From == address.FromCommon(alice) && c.Recipient == address.FromCommon(bob) && c.Amount.Cmp(amount) == 0
+			},
+		},
+		{
+			name:  "unlimited approve",
+			input: pack(t, MethodApprove, bob, math.MaxBig256),
+			check: func(c *Call) bool {
+				return c.IsApproval() && c.Spender == address.FromCommon(bob) && IsMaxUint256(c.Amount)
+			},
+		},
+		{
+			name:  "increaseAllowance",
+			input: pack(t, MethodIncreaseAllowance, bob, amount),
+			check: func(c *Call) bool {
+				return c.IsApproval() && c.Spender == address.FromCommon(bob) && !IsMaxUint256(c.Amount)
+			},
+		},
+		{
+			name:  "setApprovalForAll",
+			input: pack(t, MethodSetApprovalForAll, bob, true),
+			check: func(c *Call) bool {
+				return c.Operator == address.FromCommon(bob) && c.Approved
+			},
+		},
+		{
+			name:  "permit",
+			input: pack(t, MethodPermit, alice, bob, amount, big.NewInt(1700000000), uint8(27), [32]byte{1}, [32]byte{2}),
+			check: func(c *Call) bool {
+				return c.IsApproval() && c.Owner == address.FromCommon(alice) && c.Spender == address.FromCommon(bob) && c.Deadline.Int64() == 1700000000
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			call, err := Decode(tt.input)
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if call.Method != tt.name && tt.name != "unlimited approve" {
+				t.Errorf("Decode() method = %s, want %s", call.Method, tt.name)
+			}
+			if !tt.check(call) {
+				t.Errorf("Decode() = %+v", call)
+			}
+		})
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	if _, err := Decode("0xdeadbeef"); !errors.Is(err, ErrUnknownMethod) {
+		t.Errorf("Decode() unknown selector error = %v, want ErrUnknownMethod", err)
+	}
+	if _, err := Decode("0xa9059cbb0000"); err == nil || errors.Is(err, ErrUnknownMethod) {
+		t.Errorf("Decode() truncated arguments error = %v, want decode error", err)
+	}
+	if _, err := Decode("0xzz"); err == nil {
+		t.Error("Decode() invalid hex should fail")
+	}
+}
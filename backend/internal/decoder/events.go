@@ -0,0 +1,142 @@
+package decoder
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/minsix/backend/internal/address"
+)
+
+// Token events understood by the decoder
+const (
+	EventTransfer       = "Transfer"
+	EventApproval       = "Approval"
+	EventApprovalForAll = "ApprovalForAll"
+)
+
+// Token standards distinguished by the number of indexed event arguments
+const (
+	StandardERC20  = "erc20"
+	StandardERC721 = "erc721"
+)
+
+var (
+	// ErrUnknownEvent is returned for logs that are not a supported token event
+	ErrUnknownEvent = errors.New("unknown event")
+
+	TopicTransfer       = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)")).Hex()
+	TopicApproval       = crypto.Keccak256Hash([]byte("Approval(address,address,uint256)")).Hex()
+	TopicApprovalForAll = crypto.Keccak256Hash([]byte("ApprovalForAll(address,address,bool)")).Hex()
+)
+
+// Event is a decoded token event. ERC-20 and ERC-721 share the Transfer and
+// Approval signatures; for ERC-721 the token ID is indexed and held in Amount.
+type Event struct {
+	Name     string
+	Standard string
+	From     address.Address
+	To       address.Address
+	Owner    address.Address
+	Spender  address.Address
+	Operator address.Address
+	Amount   *big.Int
+	Approved bool
+}
+
+// Fields returns the decoded arguments as strings, suitable for JSON storage
+// without losing uint256 precision
+func (e *Event) Fields() map[string]string {
+	fields := map[string]string{"standard": e.Standard}
+	set := func(key string, value address.Address) {
+		if value != "" {
+			fields[key] = value.String()
+		}
+	}
+	set("from", e.From)
+	set("to", e.To)
+	set("owner", e.Owner)
+	set("spender", e.Spender)
+	set("operator", e.Operator)
+	if e.Amount != nil {
+		if e.Standard == StandardERC721 {
+			fields["token_id"] = e.Amount.String()
+		} else {
+			fields["amount"] = e.Amount.String()
+		}
+	}
+	if e.Name == EventApprovalForAll {
+		fields["approved"] = strconv.FormatBool(e.Approved)
+	}
+	return fields
+}
+
+// DecodeLog decodes a token event from hex-encoded topics and data
+func DecodeLog(topics []string, data string) (*Event, error) {
+	if len(topics) == 0 {
+		return nil, ErrUnknownEvent
+	}
+
+	payload, err := hex.DecodeString(strings.TrimPrefix(strings.ToLower(data), "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid log data: %w", err)
+	}
+
+	switch strings.ToLower(topics[0]) {
+	case TopicTransfer:
+		event := &Event{Name: EventTransfer}
+		if err := decodeValueEvent(event, topics, payload, &event.From, &event.To); err != nil {
+			return nil, err
+		}
+		return event, nil
+	case TopicApproval:
+		event := &Event{Name: EventApproval}
+		if err := decodeValueEvent(event, topics, payload, &event.Owner, &event.Spender); err != nil {
+			return nil, err
+		}
+		return event, nil
+	case TopicApprovalForAll:
+		if len(topics) != 3 || len(payload) < 32 {
+			return nil, fmt.Errorf("malformed %s log", EventApprovalForAll)
+		}
+		return &Event{
+			Name:     EventApprovalForAll,
+			Standard: StandardERC721,
+			Owner:    topicAddress(topics[1]),
+			Operator: topicAddress(topics[2]),
+			Approved: new(big.Int).SetBytes(payload[:32]).Sign() != 0,
+		}, nil
+	}
+
+	return nil, ErrUnknownEvent
+}
+
+// decodeValueEvent handles the shared (address indexed, address indexed, uint256) layout
+func decodeValueEvent(event *Event, topics []string, payload []byte, first, second *address.Address) error {
+	switch len(topics) {
+	case 3:
+		if len(payload) < 32 {
+			return fmt.Errorf("malformed %s log", event.Name)
+		}
+		event.Standard = StandardERC20
+		event.Amount = new(big.Int).SetBytes(payload[:32])
+	case 4:
+		event.Standard = StandardERC721
+		event.Amount = common.HexToHash(topics[3]).Big()
+	default:
+		return fmt.Errorf("malformed %s log", event.Name)
+	}
+
+	*first = topicAddress(topics[1])
+	*second = topicAddress(topics[2])
+	return nil
+}
+
+func topicAddress(topic string) address.Address {
+	return address.FromCommon(common.BytesToAddress(common.HexToHash(topic).Bytes()))
+}
@@ -0,0 +1,56 @@
+package decoder
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/minsix/backend/internal/address"
+)
+
+func TestDecodeLog(t *testing.T) {
+	from := common.HexToAddress("0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb0")
+	to := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	fromTopic := common.BytesToHash(from.Bytes()).Hex()
+	toTopic := common.BytesToHash(to.Bytes()).Hex()
+
+	erc20, err := DecodeLog(
+		[]string{TopicTransfer, fromTopic, toTopic},
+		"0x0000000000000000000000000000000000000000000000000de0b6b3a7640000",
+	)
+	if err != nil {
+		t.Fatalf("DecodeLog() ERC-20 error = %v", err)
+	}
+	if erc20.Standard != StandardERC20 || erc20.From != address.FromCommon(from) || erc20.To != address.FromCommon(to) || erc20.Amount.String() != "1000000000000000000" {
+		t.Errorf("DecodeLog() ERC-20 = %+v", erc20)
+	}
+
+	erc721, err := DecodeLog(
+		[]string{TopicTransfer, fromTopic, toTopic, common.BigToHash(common.Big3).Hex()},
+		"0x",
+	)
+	if err != nil {
+		t.Fatalf("DecodeLog() ERC-721 error = %v", err)
+	}
+	if erc721.Standard != StandardERC721 || erc721.Fields()["token_id"] != "3" {
+		t.Errorf("DecodeLog() ERC-721 = %+v", erc721)
+	}
+
+	approvalForAll, err := DecodeLog(
+		[]string{TopicApprovalForAll, fromTopic, toTopic},
+		"0x0000000000000000000000000000000000000000000000000000000000000001",
+	)
+	if err != nil {
+		t.Fatalf("DecodeLog() ApprovalForAll error = %v", err)
+	}
+	if approvalForAll.Owner != address.FromCommon(from) || approvalForAll.Operator != address.FromCommon(to) || !approvalForAll.Approved {
+		t.Errorf("DecodeLog() ApprovalForAll = %+v", approvalForAll)
+	}
+
+	if _, err := DecodeLog([]string{"0x01"}, "0x"); !errors.Is(err, ErrUnknownEvent) {
+		t.Errorf("DecodeLog() unknown topic error = %v, want ErrUnknownEvent", err)
+	}
+	if _, err := DecodeLog([]string{TopicTransfer, fromTopic, toTopic}, "0x"); err == nil {
+		t.Error("DecodeLog() truncated ERC-20 transfer should fail")
+	}
+}
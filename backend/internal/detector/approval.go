@@ -0,0 +1,146 @@
+package detector
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/minsix/backend/internal/address"
+	"github.com/minsix/backend/internal/decoder"
+	"github.com/minsix/backend/internal/models"
+)
+
+// Spender reputations used to score unlimited approvals
+const (
+	SpenderKnownRouter   = "known_router"
+	SpenderBlacklisted   = "blacklisted"
+	SpenderFreshContract = "fresh_contract"
+	SpenderEOA           = "eoa"
+	SpenderUnknown       = "unknown"
+)
+
+// Score contributed by an unlimited approval for each spender reputation
+var approvalScores = map[string]int{
+	SpenderBlacklisted:   60,
+	SpenderFreshContract: 45,
+	SpenderEOA:           35,
+	SpenderUnknown:       25,
+}
+
+const (
+	spenderCacheTTL  = 10 * time.Minute
+	spenderCacheSize = 10000
+	inspectTimeout   = 5 * time.Second
+)
+
+// ContractInspector provides on-chain facts about approval spenders
+type ContractInspector interface {
+	// InspectContract reports whether address has code and whether that code
+	// was deployed within the last maxAgeBlocks blocks
+	InspectContract(ctx context.Context, contract address.Address, maxAgeBlocks uint64) (isContract, fresh bool, err error)
+}
+
+type spenderInfo struct {
+	isContract bool
+	fresh      bool
+	checkedAt  time.Time
+}
+
+// checkUnlimitedApproval returns the decoded call and spender reputation for an
+// unlimited or near-max approval, or a nil call if the transaction is not one
+func (fd *FraudDetector) checkUnlimitedApproval(tx *models.Transaction) (*decoder.Call, string, error) {
+	if tx.InputData == nil || tx.ToAddress == nil {
+		return nil, "", nil
+	}
+
+	call, err := decoder.Decode(*tx.InputData)
+	if err != nil || !call.IsApproval() || !fd.isNearMaxAllowance(call.Amount) {
+		return nil, "", nil
+	}
+
+	reputation, err := fd.spenderReputation(call.Spender, tx.Finality == models.FinalityPending)
+	if err != nil {
+		return nil, "", err
+	}
+	return call, reputation, nil
+}
+
+// isNearMaxAllowance reports whether an allowance is large enough to be effectively unlimited
+func (fd *FraudDetector) isNearMaxAllowance(amount *big.Int) bool {
+	if amount == nil {
+		return false
+	}
+	limit := new(big.Int).Lsh(big.NewInt(1), uint(fd.currentThresholds().NearMaxAllowanceBits))
+	return amount.Cmp(limit.Sub(limit, big.NewInt(1))) >= 0
+}
+
+// spenderReputation classifies an approval spender, most severe first. Pending
+// transactions only use spenders already inspected, so the mempool never waits
+// on RPC lookups.
+func (fd *FraudDetector) spenderReputation(spender address.Address, pending bool) (string, error) {
+	if fd.blacklist != nil {
+		blacklisted, err := fd.blacklist.IsBlacklisted(fd.chainID, spender)
+		if err != nil {
+			return "", err
+		}
+		if blacklisted {
+			return SpenderBlacklisted, nil
+		}
+	}
+
+	if fd.isKnownSpender(spender) {
+		return SpenderKnownRouter, nil
+	}
+
+	if fd.inspector == nil {
+		return SpenderUnknown, nil
+	}
+
+	info, ok := fd.cachedSpender(spender)
+	if !ok {
+		if pending {
+			return SpenderUnknown, nil
+		}
+		var err error
+		if info, err = fd.inspectSpender(spender); err != nil {
+			return "", err
+		}
+	}
+	switch {
+	case !info.isContract:
+		return SpenderEOA, nil
+	case info.fresh:
+		return SpenderFreshContract, nil
+	default:
+		return SpenderUnknown, nil
+	}
+}
+
+// cachedSpender returns the facts of a recently inspected spender
+func (fd *FraudDetector) cachedSpender(spender address.Address) (spenderInfo, bool) {
+	fd.spenderMu.Lock()
+	info, ok := fd.spenderCache[spender]
+	fd.spenderMu.Unlock()
+	return info, ok && time.Since(info.checkedAt) < spenderCacheTTL
+}
+
+// inspectSpender looks up a spender on-chain, caching results to limit RPC calls
+func (fd *FraudDetector) inspectSpender(spender address.Address) (spenderInfo, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), inspectTimeout)
+	defer cancel()
+
+	isContract, fresh, err := fd.inspector.InspectContract(ctx, spender, uint64(fd.currentThresholds().FreshContractBlocks))
+	if err != nil {
+		return spenderInfo{}, err
+	}
+	info := spenderInfo{isContract: isContract, fresh: fresh, checkedAt: time.Now()}
+
+	fd.spenderMu.Lock()
+	if len(fd.spenderCache) >= spenderCacheSize {
+		fd.spenderCache = make(map[address.Address]spenderInfo)
+	}
+	fd.spenderCache[spender] = info
+	fd.spenderMu.Unlock()
+
+	return info, nil
+}
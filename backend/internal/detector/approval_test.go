@@ -0,0 +1,125 @@
+package detector
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/minsix/backend/internal/address"
+	"github.com/minsix/backend/internal/models"
+)
+
+type fakeBlacklist map[address.Address]bool
+
+func (f fakeBlacklist) IsBlacklisted(chainID int64, addr address.Address) (bool, error) {
+	return f[addr], nil
+}
+
+type fakeInspector struct {
+	contracts map[address.Address]bool // address -> fresh
+	calls     int
+}
+
+func (f *fakeInspector) InspectContract(ctx context.Context, contract address.Address, maxAgeBlocks uint64) (bool, bool, error) {
+	f.calls++
+	fresh, ok := f.contracts[contract]
+	return ok, fresh, nil
+}
+
+func approvalCalldata(spender, amount string) *string {
+	data := "0x095ea7b3" + strings.Repeat("0", 24) + strings.TrimPrefix(strings.ToLower(spender), "0x") + amount
+	return &data
+}
+
+func TestUnlimitedApproval(t *testing.T) {
+	const (
+		router    = "0x7a250d5630B4cF539739dF2C5dAcb4c659F2488D"
+		drainer   = "0x1111111111111111111111111111111111111111"
+		fresh     = "0x2222222222222222222222222222222222222222"
+		contract  = "0x3333333333333333333333333333333333333333"
+		eoa       = "0x4444444444444444444444444444444444444444"
+		maxUint   = "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"
+		uint96Max = "0000000000000000000000000000000000000000ffffffffffffffffffffffff"
+		oneToken  = "0000000000000000000000000000000000000000000000000de0b6b3a7640000"
+	)
+
+	fd := NewFraudDetector(nil)
+	fd.blacklist = fakeBlacklist{drainer: true}
+	inspector := &fakeInspector{contracts: map[address.Address]bool{fresh: true, contract: false}}
+	fd.SetContractInspector(inspector)
+
+	tests := []struct {
+		name       string
+		input      *string
+		reputation string
+		score      int
+	}{
+		{"Known router", approvalCalldata(router, maxUint), SpenderKnownRouter, 0},
+		{"Blacklisted spender", approvalCalldata(drainer, maxUint), SpenderBlacklisted, 60},
+		{"Fresh contract", approvalCalldata(fresh, maxUint), SpenderFreshContract, 45},
+		{"Established contract", approvalCalldata(contract, maxUint), SpenderUnknown, 25},
+		{"Externally owned account", approvalCalldata(eoa, uint96Max), SpenderEOA, 35},
+		{"Limited approval", approvalCalldata(eoa, oneToken), "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := address.FromHex("0x6B175474E89094C44Da98b954EedeAC495271d0F")
+			tx := &models.Transaction{ToAddress: &token, InputData: tt.input}
+
+			call, reputation, err := fd.checkUnlimitedApproval(tx)
+			if err != nil {
+				t.Fatalf("checkUnlimitedApproval() error = %v", err)
+			}
+			if (call != nil) != (tt.reputation != "") || reputation != tt.reputation {
+				t.Fatalf("checkUnlimitedApproval() reputation = %q, want %q", reputation, tt.reputation)
+			}
+
+			result, err := fd.evalUnlimitedApproval(tx)
+			if err != nil {
+				t.Fatalf("evalUnlimitedApproval() error = %v", err)
+			}
+			score := 0
+			if result != nil {
+				score = result.Score
+			}
+			if score != tt.score {
+				t.Errorf("evalUnlimitedApproval() score = %d, want %d", score, tt.score)
+			}
+		})
+	}
+
+	// Repeated lookups for the same spender are served from the cache
+	calls := inspector.calls
+	token := address.FromHex("0x6B175474E89094C44Da98b954EedeAC495271d0F")
+	fd.checkUnlimitedApproval(&models.Transaction{ToAddress: &token, InputData: approvalCalldata(fresh, maxUint)})
+	if inspector.calls != calls {
+		t.Errorf("expected cached spender lookup, got %d new calls", inspector.calls-calls)
+	}
+}
+
+func TestUnlimitedApprovalPendingSkipsInspection(t *testing.T) {
+	const (
+		fresh   = "0x2222222222222222222222222222222222222222"
+		maxUint = "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"
+	)
+
+	fd := NewFraudDetector(nil)
+	inspector := &fakeInspector{contracts: map[address.Address]bool{fresh: true}}
+	fd.SetContractInspector(inspector)
+
+	token := address.FromHex("0x6B175474E89094C44Da98b954EedeAC495271d0F")
+	tx := &models.Transaction{ToAddress: &token, InputData: approvalCalldata(fresh, maxUint), Finality: models.FinalityPending}
+	if _, reputation, _ := fd.checkUnlimitedApproval(tx); reputation != SpenderUnknown || inspector.calls != 0 {
+		t.Fatalf("pending reputation = %q after %d lookups, want %q without lookups", reputation, inspector.calls, SpenderUnknown)
+	}
+
+	// Once the spender has been inspected for a mined transaction, pending
+	// transactions use the cached result
+	tx.Finality = models.FinalityConfirmed
+	fd.checkUnlimitedApproval(tx)
+	tx.Finality = models.FinalityPending
+	if _, reputation, _ := fd.checkUnlimitedApproval(tx); reputation != SpenderFreshContract || inspector.calls != 1 {
+		t.Errorf("pending reputation = %q after %d lookups, want %q from the cache", reputation, inspector.calls, SpenderFreshContract)
+	}
+}
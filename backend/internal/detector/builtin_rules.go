@@ -0,0 +1,381 @@
+package detector
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"sync/atomic"
+
+	"github.com/minsix/backend/internal/decoder"
+	"github.com/minsix/backend/internal/models"
+)
+
+// Names of the rules registered by default
+const (
+	RuleBlacklist           = "blacklist"
+	RuleLargeTransfer       = "large_transfer"
+	RuleRapidTransactions   = "rapid_transactions"
+	RuleUnusualGasPrice     = "unusual_gas_price"
+	RuleContractInteraction = "contract_interaction"
+	RuleNullAddress         = "null_address"
+	RuleUnlimitedApproval   = "unlimited_approval"
+	RuleNFTApprovalForAll   = "nft_approval_for_all"
+)
+
+// BuiltinRuleNames lists the rules registered by default, in evaluation order
+var BuiltinRuleNames = []string{
+	RuleBlacklist,
+	RuleLargeTransfer,
+	RuleRapidTransactions,
+	RuleUnusualGasPrice,
+	RuleContractInteraction,
+	RuleNullAddress,
+	RuleUnlimitedApproval,
+	RuleNFTApprovalForAll,
+}
+
+// param binds a configurable threshold to a field of Thresholds
+type param struct {
+	get   func(t *Thresholds) float64
+	set   func(t *Thresholds, value float64)
+	whole bool    // a count, configured with whole numbers only
+	max   float64 // upper bound of the value, or 0 if unbounded
+}
+
+// checkRule adapts one of the detector's check methods to the Rule interface
+type checkRule struct {
+	name       string
+	version    string
+	check      func(tx *models.Transaction) (*Result, error)
+	thresholds *atomic.Pointer[Thresholds]
+	params     map[string]param
+}
+
+func (r *checkRule) Name() string    { return r.name }
+func (r *checkRule) Version() string { return r.version }
+
+func (r *checkRule) Evaluate(tx *models.Transaction) (*Result, error) {
+	return r.check(tx)
+}
+
+func (r *checkRule) DefaultThresholds() map[string]float64 {
+	defaults := DefaultThresholds()
+	values := make(map[string]float64, len(r.params))
+	for name, p := range r.params {
+		values[name] = p.get(&defaults)
+	}
+	return values
+}
+
+func (r *checkRule) Thresholds() map[string]float64 {
+	values := make(map[string]float64, len(r.params))
+	for name, p := range r.params {
+		values[name] = p.get(r.thresholds.Load())
+	}
+	return values
+}
+
+func (r *checkRule) IsWhole(name string) bool {
+	return r.params[name].whole
+}
+
+func (r *checkRule) MaxThreshold(name string) (float64, bool) {
+	max := r.params[name].max
+	return max, max > 0
+}
+
+// SetThresholds stores an updated copy of the thresholds, leaving the values
+// read by evaluations in flight untouched
+func (r *checkRule) SetThresholds(values map[string]float64) {
+	if len(r.params) == 0 {
+		return
+	}
+	thresholds := *r.thresholds.Load()
+	for name, value := range values {
+		if p, ok := r.params[name]; ok {
+			p.set(&thresholds, value)
+		}
+	}
+	r.thresholds.Store(&thresholds)
+}
+
+// builtinRules returns the default heuristics backed by this detector
+func (fd *FraudDetector) builtinRules() []Rule {
+	return []Rule{
+		&checkRule{name: RuleBlacklist, version: "1", check: fd.evalBlacklist},
+		&checkRule{
+			name: RuleLargeTransfer, version: "3", check: fd.evalLargeTransfer,
+			thresholds: &fd.thresholds,
+			params: map[string]param{
+				"amount_eth": {
+					get: func(t *Thresholds) float64 { return t.LargeTransferETH },
+					set: func(t *Thresholds, v float64) { t.LargeTransferETH = v },
+				},
+				"amount_usd": {
+					get: func(t *Thresholds) float64 { return t.LargeTransferUSD },
+					set: func(t *Thresholds, v float64) { t.LargeTransferUSD = v },
+				},
+			},
+		},
+		&checkRule{
+			name: RuleRapidTransactions, version: "1", check: fd.evalRapidTransactions,
+			thresholds: &fd.thresholds,
+			params: map[string]param{
+				"window_seconds": {
+					get: func(t *Thresholds) float64 { return t.RapidWindowSeconds },
+					set: func(t *Thresholds, v float64) { t.RapidWindowSeconds = v },
+				},
+				"max_transactions": {
+					get:   func(t *Thresholds) float64 { return float64(t.MaxRapidTransactions) },
+					set:   func(t *Thresholds, v float64) { t.MaxRapidTransactions = int(v) },
+					whole: true,
+				},
+			},
+		},
+		&checkRule{
+			name: RuleUnusualGasPrice, version: "1", check: fd.evalUnusualGasPrice,
+			thresholds: &fd.thresholds,
+			params: map[string]param{
+				"high_multiplier": {
+					get: func(t *Thresholds) float64 { return t.HighGasPriceMultiplier },
+					set: func(t *Thresholds, v float64) { t.HighGasPriceMultiplier = v },
+				},
+				"low_divisor": {
+					get: func(t *Thresholds) float64 { return t.LowGasPriceDivisor },
+					set: func(t *Thresholds, v float64) { t.LowGasPriceDivisor = v },
+				},
+			},
+		},
+		&checkRule{name: RuleContractInteraction, version: "4", check: fd.evalContractInteraction},
+		&checkRule{name: RuleNullAddress, version: "1", check: fd.evalNullAddress},
+		&checkRule{
+			name: RuleUnlimitedApproval, version: "1", check: fd.evalUnlimitedApproval,
+			thresholds: &fd.thresholds,
+			params: map[string]param{
+				"near_max_bits": {
+					get:   func(t *Thresholds) float64 { return float64(t.NearMaxAllowanceBits) },
+					set:   func(t *Thresholds, v float64) { t.NearMaxAllowanceBits = int(v) },
+					whole: true,
+					max:   256, // allowances are uint256
+				},
+				"fresh_contract_blocks": {
+					get:   func(t *Thresholds) float64 { return float64(t.FreshContractBlocks) },
+					set:   func(t *Thresholds, v float64) { t.FreshContractBlocks = int(v) },
+					whole: true,
+				},
+			},
+		},
+		&checkRule{
+			name: RuleNFTApprovalForAll, version: "1", check: fd.evalNFTApprovalForAll,
+			thresholds: &fd.thresholds,
+			params: map[string]param{
+				"window_seconds": {
+					get: func(t *Thresholds) float64 { return t.DrainerWindowSeconds },
+					set: func(t *Thresholds, v float64) { t.DrainerWindowSeconds = v },
+				},
+				"victim_threshold": {
+					get:   func(t *Thresholds) float64 { return float64(t.DrainerVictimThreshold) },
+					set:   func(t *Thresholds, v float64) { t.DrainerVictimThreshold = int(v) },
+					whole: true,
+				},
+			},
+		},
+	}
+}
+
+func (fd *FraudDetector) evalBlacklist(tx *models.Transaction) (*Result, error) {
+	address, err := fd.checkBlacklist(tx)
+	if err != nil || address == "" {
+		return nil, err
+	}
+	return &Result{
+		Score:    40,
+		Reason:   "Blacklisted address detected",
+		Evidence: map[string]interface{}{"matched_address": address},
+	}, nil
+}
+
+func (fd *FraudDetector) evalLargeTransfer(tx *models.Transaction) (*Result, error) {
+	isLarge, amount := fd.checkLargeTransfer(tx)
+	tokenTransfers := fd.checkLargeTokenTransfers(tx)
+	if !isLarge && len(tokenTransfers) == 0 {
+		return nil, nil
+	}
+
+	evidence := map[string]interface{}{}
+	var reasons []string
+	if isLarge {
+		reasons = append(reasons, "Large transfer: "+amount+" ETH")
+		evidence["amount"] = amount
+		evidence["value_wei"] = tx.Value
+		value, _ := new(big.Int).SetString(tx.Value, 10)
+		if usd, ok := fd.nativeUSDValue(value); ok {
+			evidence["usd_value"] = usd
+			evidence["threshold"] = fd.currentThresholds().LargeTransferUSD
+			evidence["unit"] = "USD"
+		} else {
+			evidence["threshold"] = fd.currentThresholds().LargeTransferETH
+			evidence["unit"] = "ETH"
+		}
+	}
+
+	if len(tokenTransfers) > 0 {
+		transfers := make([]map[string]interface{}, 0, len(tokenTransfers))
+		for _, large := range tokenTransfers {
+			transfer := map[string]interface{}{
+				"token":     large.transfer.Token,
+				"from":      large.transfer.FromAddress,
+				"to":        large.transfer.ToAddress,
+				"amount":    large.transfer.Amount,
+				"log_index": large.transfer.LogIndex,
+			}
+			if large.priced {
+				transfer["usd_value"] = large.usdValue
+			}
+			if large.threshold != nil {
+				transfer["threshold"] = large.threshold.String()
+			} else {
+				transfer["threshold_usd"] = fd.currentThresholds().LargeTransferUSD
+			}
+			transfers = append(transfers, transfer)
+		}
+		evidence["token_transfers"] = transfers
+		reasons = append(reasons, fmt.Sprintf("Large token transfer: %d above token threshold", len(tokenTransfers)))
+	}
+
+	return &Result{
+		Score:    25,
+		Reason:   strings.Join(reasons, "; "),
+		Evidence: evidence,
+	}, nil
+}
+
+func (fd *FraudDetector) evalRapidTransactions(tx *models.Transaction) (*Result, error) {
+	count, rapid := fd.checkRapidTransactions(tx)
+	if !rapid {
+		return nil, nil
+	}
+	return &Result{
+		Score:  20,
+		Reason: "Rapid succession of transactions detected",
+		Evidence: map[string]interface{}{
+			"address":          tx.FromAddress,
+			"count":            count,
+			"window_seconds":   fd.currentThresholds().RapidWindowSeconds,
+			"max_transactions": fd.currentThresholds().MaxRapidTransactions,
+		},
+	}, nil
+}
+
+func (fd *FraudDetector) evalUnusualGasPrice(tx *models.Transaction) (*Result, error) {
+	average, unusual := fd.checkUnusualGasPrice(tx)
+	if !unusual {
+		return nil, nil
+	}
+	return &Result{
+		Score:  15,
+		Reason: "Unusual gas price detected",
+		Evidence: map[string]interface{}{
+			"gas_price":         tx.GasPrice,
+			"average_gas_price": average.String(),
+		},
+	}, nil
+}
+
+func (fd *FraudDetector) evalContractInteraction(tx *models.Transaction) (*Result, error) {
+	reason, call := fd.checkContractInteraction(tx)
+	if reason == "" {
+		return nil, nil
+	}
+
+	evidence := map[string]interface{}{
+		"selector": call.Selector,
+		"method":   call.Method,
+		"token":    *tx.ToAddress,
+	}
+	if call.Spender != "" {
+		evidence["spender"] = call.Spender
+	}
+	if call.Recipient != "" {
+		evidence["recipient"] = call.Recipient
+	}
+	if call.Amount != nil {
+		evidence["amount"] = call.Amount.String()
+	}
+
+	return &Result{
+		Score:    20,
+		Reason:   reason,
+		Evidence: evidence,
+	}, nil
+}
+
+func (fd *FraudDetector) evalNullAddress(tx *models.Transaction) (*Result, error) {
+	if !fd.checkNullAddress(tx) {
+		return nil, nil
+	}
+	return &Result{
+		Score:    30,
+		Reason:   "Transaction to null/burn address",
+		Evidence: map[string]interface{}{"matched_address": *tx.ToAddress},
+	}, nil
+}
+
+func (fd *FraudDetector) evalUnlimitedApproval(tx *models.Transaction) (*Result, error) {
+	call, reputation, err := fd.checkUnlimitedApproval(tx)
+	if err != nil || call == nil {
+		return nil, err
+	}
+
+	score, ok := approvalScores[reputation]
+	if !ok {
+		// Approvals to trusted routers are routine
+		return nil, nil
+	}
+
+	result := &Result{
+		Score:  score,
+		Reason: "Unlimited token approval to " + strings.ReplaceAll(reputation, "_", " ") + " spender",
+		Evidence: map[string]interface{}{
+			"selector":   call.Selector,
+			"method":     call.Method,
+			"token":      *tx.ToAddress,
+			"spender":    call.Spender,
+			"reputation": reputation,
+			"amount":     call.Amount.String(),
+			"max_uint":   decoder.IsMaxUint256(call.Amount),
+		},
+	}
+	if reputation == SpenderBlacklisted {
+		result.Severity = models.SeverityCritical
+	}
+	return result, nil
+}
+
+func (fd *FraudDetector) evalNFTApprovalForAll(tx *models.Transaction) (*Result, error) {
+	approval, err := fd.checkNFTApprovalForAll(tx)
+	if err != nil || approval == nil {
+		return nil, err
+	}
+
+	result := &Result{
+		Score:  15,
+		Reason: "NFT approval for all to unknown operator",
+		Evidence: map[string]interface{}{
+			"selector":         approval.call.Selector,
+			"method":           approval.call.Method,
+			"collection":       *tx.ToAddress,
+			"operator":         approval.call.Operator,
+			"victim_count":     approval.victims,
+			"victim_threshold": fd.currentThresholds().DrainerVictimThreshold,
+			"window_seconds":   fd.currentThresholds().DrainerWindowSeconds,
+			"escalated":        approval.escalated,
+		},
+	}
+	if approval.suspected {
+		result.Score = 60
+		result.Severity = models.SeverityCritical
+		result.Reason = "NFT approval for all to suspected drainer"
+	}
+	return result, nil
+}
@@ -0,0 +1,277 @@
+package detector
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minsix/backend/internal/address"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the declarative rule configuration loaded from a YAML or JSON file
+type Config struct {
+	// FlagThreshold is the minimum risk score for a flag; defaults to DefaultFlagThreshold
+	FlagThreshold *int                  `json:"flag_threshold" yaml:"flag_threshold"`
+	Rules         map[string]RuleConfig `json:"rules" yaml:"rules"`
+	// WatchedFlagThreshold applies to transactions involving monitored
+	// wallets; defaults to DefaultWatchedFlagThreshold and is capped at FlagThreshold
+	WatchedFlagThreshold *int `json:"watched_flag_threshold" yaml:"watched_flag_threshold"`
+
+	// KnownSpenders lists trusted approval spenders; defaults to DefaultKnownSpenders
+	KnownSpenders []string `json:"known_spenders" yaml:"known_spenders"`
+	// TokenTransferThresholds maps token contracts to a transfer threshold in raw token units
+	TokenTransferThresholds map[string]string `json:"token_transfer_thresholds" yaml:"token_transfer_thresholds"`
+
+	// Chains overrides settings per chain ID; see ForChain
+	Chains map[int64]*Config `json:"chains" yaml:"chains"`
+}
+
+// RuleConfig holds the settings for a single rule. Omitted fields use defaults.
+type RuleConfig struct {
+	Enabled    *bool              `json:"enabled" yaml:"enabled"`
+	Weight     *float64           `json:"weight" yaml:"weight"`
+	Thresholds map[string]float64 `json:"thresholds" yaml:"thresholds"`
+}
+
+// LoadConfig reads a rule configuration file, choosing the format by extension
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules config: %w", err)
+	}
+
+	cfg := &Config{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(cfg)
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(cfg)
+	default:
+		return nil, fmt.Errorf("unsupported rules config format %q", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse rules config: %w", err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Validate checks the values that do not depend on which rules are registered
+func (c *Config) Validate() error {
+	if c.FlagThreshold != nil && (*c.FlagThreshold < 1 || *c.FlagThreshold > 100) {
+		return fmt.Errorf("flag_threshold must be between 1 and 100")
+	}
+	if c.WatchedFlagThreshold != nil && (*c.WatchedFlagThreshold < 1 || *c.WatchedFlagThreshold > 100) {
+		return fmt.Errorf("watched_flag_threshold must be between 1 and 100")
+	}
+	for name, rule := range c.Rules {
+		if rule.Weight != nil && *rule.Weight < 0 {
+			return fmt.Errorf("rule %q: weight must not be negative", name)
+		}
+		for key, value := range rule.Thresholds {
+			if value <= 0 {
+				return fmt.Errorf("rule %q: threshold %q must be positive", name, key)
+			}
+		}
+	}
+	for _, spender := range c.KnownSpenders {
+		if _, err := address.Parse(spender); err != nil {
+			return fmt.Errorf("known_spenders: %w", err)
+		}
+	}
+	for token, value := range c.TokenTransferThresholds {
+		if _, err := address.Parse(token); err != nil {
+			return fmt.Errorf("token_transfer_thresholds: %w", err)
+		}
+		if threshold, ok := new(big.Int).SetString(value, 10); !ok || threshold.Sign() <= 0 {
+			return fmt.Errorf("token_transfer_thresholds: %s must be a positive integer", token)
+		}
+	}
+	for chainID, override := range c.Chains {
+		if override == nil {
+			continue
+		}
+		if override.Chains != nil {
+			return fmt.Errorf("chains.%d: chain overrides cannot be nested", chainID)
+		}
+		if err := override.Validate(); err != nil {
+			return fmt.Errorf("chains.%d: %w", chainID, err)
+		}
+	}
+	return nil
+}
+
+// ForChain returns the configuration with a chain's overrides applied. Rule
+// settings are merged field by field; known_spenders and
+// token_transfer_thresholds replace the base lists when set.
+func (c *Config) ForChain(chainID int64) *Config {
+	override := c.Chains[chainID]
+	merged := &Config{
+		FlagThreshold:           c.FlagThreshold,
+		WatchedFlagThreshold:    c.WatchedFlagThreshold,
+		Rules:                   make(map[string]RuleConfig, len(c.Rules)),
+		KnownSpenders:           c.KnownSpenders,
+		TokenTransferThresholds: c.TokenTransferThresholds,
+	}
+	for name, rule := range c.Rules {
+		merged.Rules[name] = rule
+	}
+	if override == nil {
+		return merged
+	}
+
+	if override.FlagThreshold != nil {
+		merged.FlagThreshold = override.FlagThreshold
+	}
+	if override.WatchedFlagThreshold != nil {
+		merged.WatchedFlagThreshold = override.WatchedFlagThreshold
+	}
+	for name, rule := range override.Rules {
+		base := merged.Rules[name]
+		if rule.Enabled != nil {
+			base.Enabled = rule.Enabled
+		}
+		if rule.Weight != nil {
+			base.Weight = rule.Weight
+		}
+		if len(rule.Thresholds) > 0 {
+			thresholds := make(map[string]float64, len(base.Thresholds)+len(rule.Thresholds))
+			for key, value := range base.Thresholds {
+				thresholds[key] = value
+			}
+			for key, value := range rule.Thresholds {
+				thresholds[key] = value
+			}
+			base.Thresholds = thresholds
+		}
+		merged.Rules[name] = base
+	}
+	if override.KnownSpenders != nil {
+		merged.KnownSpenders = override.KnownSpenders
+	}
+	if override.TokenTransferThresholds != nil {
+		merged.TokenTransferThresholds = override.TokenTransferThresholds
+	}
+	return merged
+}
+
+// validate checks the settings against the rule they are applied to
+func (c RuleConfig) validate(name string, rule Rule) error {
+	if len(c.Thresholds) == 0 {
+		return nil
+	}
+
+	tunable, ok := rule.(Tunable)
+	if !ok {
+		return fmt.Errorf("rule %q does not support thresholds", name)
+	}
+
+	defaults := tunable.DefaultThresholds()
+	whole, _ := rule.(WholeThresholds)
+	bounded, _ := rule.(BoundedThresholds)
+	for key, value := range c.Thresholds {
+		if _, ok := defaults[key]; !ok {
+			return fmt.Errorf("rule %q: unknown threshold %q", name, key)
+		}
+		if whole != nil && whole.IsWhole(key) && (value != math.Trunc(value) || math.IsInf(value, 0)) {
+			return fmt.Errorf("rule %q: threshold %q must be a whole number", name, key)
+		}
+		if bounded != nil {
+			if max, ok := bounded.MaxThreshold(key); ok && value > max {
+				return fmt.Errorf("rule %q: threshold %q must be at most %g", name, key, max)
+			}
+		}
+	}
+	return nil
+}
+
+// ConfigWatcher reloads a rule configuration file into a detector when the
+// file changes or Reload is called. Invalid files are logged and ignored so
+// the detector keeps running with the last good configuration.
+type ConfigWatcher struct {
+	path     string
+	detector *FraudDetector
+	mu       sync.Mutex
+	modTime  time.Time
+}
+
+// NewConfigWatcher loads and applies the file once, failing if it is invalid
+func NewConfigWatcher(path string, detector *FraudDetector) (*ConfigWatcher, error) {
+	w := &ConfigWatcher{path: path, detector: detector}
+	if err := w.load(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Reload re-reads the configuration file and applies it
+func (w *ConfigWatcher) Reload() {
+	if err := w.load(); err != nil {
+		log.Printf("Failed to reload rules config: %v", err)
+		return
+	}
+	log.Printf("Reloaded rules config from %s", w.path)
+}
+
+// Watch polls the file for modifications until the context is cancelled
+func (w *ConfigWatcher) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			info, err := os.Stat(w.path)
+			if err != nil {
+				log.Printf("Failed to stat rules config: %v", err)
+				continue
+			}
+
+			w.mu.Lock()
+			changed := !info.ModTime().Equal(w.modTime)
+			w.mu.Unlock()
+
+			if changed {
+				w.Reload()
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (w *ConfigWatcher) load() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	info, err := os.Stat(w.path)
+	if err != nil {
+		return fmt.Errorf("failed to stat rules config: %w", err)
+	}
+	// Remember the attempt even if it fails so a broken file is not retried
+	// on every tick; the next edit will change the modification time again.
+	w.modTime = info.ModTime()
+
+	cfg, err := LoadConfig(w.path)
+	if err != nil {
+		return err
+	}
+	return w.detector.ApplyConfig(cfg)
+}
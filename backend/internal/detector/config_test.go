@@ -0,0 +1,209 @@
+package detector
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/minsix/backend/internal/models"
+)
+
+func writeConfig(t *testing.T, name, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	return path
+}
+
+func TestLoadConfigAndApply(t *testing.T) {
+	path := writeConfig(t, "rules.yaml", `
+flag_threshold: 30
+rules:
+  large_transfer:
+    weight: 2
+    thresholds:
+      amount_eth: 100
+  null_address:
+    enabled: false
+`)
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	fd := NewFraudDetector(nil)
+	if err := fd.ApplyConfig(cfg); err != nil {
+		t.Fatalf("ApplyConfig() error = %v", err)
+	}
+
+	if fd.FlagThreshold() != 30 {
+		t.Errorf("FlagThreshold() = %d, want 30", fd.FlagThreshold())
+	}
+	if fd.currentThresholds().LargeTransferETH != 100 {
+		t.Errorf("LargeTransferETH = %v, want 100", fd.currentThresholds().LargeTransferETH)
+	}
+
+	for _, info := range fd.Rules().Rules() {
+		switch info.Name {
+		case RuleLargeTransfer:
+			if info.Weight != 2 {
+				t.Errorf("large_transfer weight = %v, want 2", info.Weight)
+			}
+		case RuleNullAddress:
+			if info.Enabled {
+				t.Error("null_address should be disabled")
+			}
+		}
+	}
+
+	// Reapplying an empty config restores the defaults
+	if err := fd.ApplyConfig(&Config{}); err != nil {
+		t.Fatalf("ApplyConfig() error = %v", err)
+	}
+	if fd.FlagThreshold() != DefaultFlagThreshold || fd.currentThresholds().LargeTransferETH != LargeTransferThresholdETH {
+		t.Error("empty config should restore default thresholds")
+	}
+}
+
+func TestLoadConfigJSON(t *testing.T) {
+	path := writeConfig(t, "rules.json", `{"rules": {"rapid_transactions": {"thresholds": {"max_transactions": 2}}}}`)
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	fd := NewFraudDetector(nil)
+	if err := fd.ApplyConfig(cfg); err != nil {
+		t.Fatalf("ApplyConfig() error = %v", err)
+	}
+	if fd.currentThresholds().MaxRapidTransactions != 2 {
+		t.Errorf("MaxRapidTransactions = %d, want 2", fd.currentThresholds().MaxRapidTransactions)
+	}
+}
+
+func TestApplyConfigChainOverrides(t *testing.T) {
+	path := writeConfig(t, "rules.yaml", `
+flag_threshold: 30
+rules:
+  large_transfer:
+    weight: 2
+    thresholds:
+      amount_eth: 100
+      amount_usd: 50000
+chains:
+  137:
+    flag_threshold: 40
+    rules:
+      large_transfer:
+        thresholds:
+          amount_usd: 10000
+`)
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	mainnet := NewFraudDetector(nil)
+	mainnet.SetChainID(1)
+	if err := mainnet.ApplyConfig(cfg); err != nil {
+		t.Fatalf("ApplyConfig() error = %v", err)
+	}
+	if mainnet.FlagThreshold() != 30 || mainnet.currentThresholds().LargeTransferUSD != 50000 {
+		t.Errorf("mainnet got threshold %d and amount_usd %v, want the base config", mainnet.FlagThreshold(), mainnet.currentThresholds().LargeTransferUSD)
+	}
+
+	polygon := NewFraudDetector(nil)
+	polygon.SetChainID(137)
+	if err := polygon.ApplyConfig(cfg); err != nil {
+		t.Fatalf("ApplyConfig() error = %v", err)
+	}
+	if polygon.FlagThreshold() != 40 {
+		t.Errorf("FlagThreshold() = %d, want 40", polygon.FlagThreshold())
+	}
+	if polygon.currentThresholds().LargeTransferUSD != 10000 || polygon.currentThresholds().LargeTransferETH != 100 {
+		t.Errorf("thresholds = %v USD / %v ETH, want the override merged over the base", polygon.currentThresholds().LargeTransferUSD, polygon.currentThresholds().LargeTransferETH)
+	}
+	for _, info := range polygon.Rules().Rules() {
+		if info.Name == RuleLargeTransfer && info.Weight != 2 {
+			t.Errorf("large_transfer weight = %v, want the base weight 2", info.Weight)
+		}
+	}
+
+	// The base config is not modified by merging
+	if cfg.Rules[RuleLargeTransfer].Thresholds["amount_usd"] != 50000 {
+		t.Error("ForChain modified the base config")
+	}
+}
+
+func TestApplyConfigValidation(t *testing.T) {
+	negative := -1.0
+	threshold := 0
+
+	tests := []struct {
+		name string
+		cfg  *Config
+	}{
+		{
+			name: "Unknown rule",
+			cfg:  &Config{Rules: map[string]RuleConfig{"missing": {}}},
+		},
+		{
+			name: "Unknown threshold",
+			cfg:  &Config{Rules: map[string]RuleConfig{RuleLargeTransfer: {Thresholds: map[string]float64{"foo": 1}}}},
+		},
+		{
+			name: "Fractional count threshold",
+			cfg:  &Config{Rules: map[string]RuleConfig{RuleRapidTransactions: {Thresholds: map[string]float64{"max_transactions": 0.5}}}},
+		},
+		{
+			name: "Fractional bit count",
+			cfg:  &Config{Rules: map[string]RuleConfig{RuleUnlimitedApproval: {Thresholds: map[string]float64{"near_max_bits": 95.5}}}},
+		},
+		{
+			name: "Bit count above uint256",
+			cfg:  &Config{Rules: map[string]RuleConfig{RuleUnlimitedApproval: {Thresholds: map[string]float64{"near_max_bits": 1e18}}}},
+		},
+		{
+			name: "Negative weight",
+			cfg:  &Config{Rules: map[string]RuleConfig{RuleLargeTransfer: {Weight: &negative}}},
+		},
+		{
+			name: "Flag threshold out of range",
+			cfg:  &Config{FlagThreshold: &threshold},
+		},
+		{
+			name: "Invalid chain override",
+			cfg:  &Config{Chains: map[int64]*Config{1: {FlagThreshold: &threshold}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fd := NewFraudDetector(nil)
+			if err := fd.ApplyConfig(tt.cfg); err == nil {
+				t.Error("ApplyConfig() expected error, got nil")
+			}
+
+			// A rejected config must leave the detector untouched
+			tx := &models.Transaction{Value: "15000000000000000000", GasPrice: "30000000000"}
+			if isLarge, _ := fd.checkLargeTransfer(tx); !isLarge {
+				t.Error("rejected config changed large transfer threshold")
+			}
+		})
+	}
+}
+
+func TestBundledConfig(t *testing.T) {
+	cfg, err := LoadConfig("../../config/rules.yaml")
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if err := NewFraudDetector(nil).ApplyConfig(cfg); err != nil {
+		t.Errorf("ApplyConfig() error = %v", err)
+	}
+}
@@ -1,88 +1,344 @@
 package detector
 
 import (
-	"fmt"
 	"log"
+	"math"
 	"math/big"
-	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/minsix/backend/internal/address"
 	"github.com/minsix/backend/internal/database"
+	"github.com/minsix/backend/internal/decoder"
 	"github.com/minsix/backend/internal/models"
 )
 
 const (
-	// Thresholds for fraud detection
-	LargeTransferThresholdETH = 10.0  // ETH
-	HighGasPriceMultiplier    = 3.0   // 3x average
-	RapidTransactionWindow    = 60    // seconds
-	MaxRapidTransactions      = 5     // transactions in window
+	// Default thresholds for fraud detection, overridable through Config
+	LargeTransferThresholdETH = 10.0    // ETH, used when no ETH price is available
+	LargeTransferThresholdUSD = 25000.0 // USD
+	HighGasPriceMultiplier    = 3.0     // 3x average
+	LowGasPriceDivisor        = 10.0    // 1/10 of average
+	RapidTransactionWindow    = 60      // seconds
+	MaxRapidTransactions      = 5       // transactions in window
+	NearMaxAllowanceBits      = 96      // allowances >= 2^96-1 are treated as unlimited
+	FreshContractBlocks       = 7200    // ~1 day of mainnet blocks
+	DrainerWindowSeconds      = 3600    // seconds
+	DrainerVictimThreshold    = 3       // distinct victims approving one operator
+
+	// DefaultFlagThreshold is the minimum risk score for a transaction to be flagged
+	DefaultFlagThreshold = 20
+
+	// DefaultWatchedFlagThreshold is the minimum risk score for a transaction
+	// involving a monitored wallet to be flagged
+	DefaultWatchedFlagThreshold = 10
 )
 
+// DefaultKnownSpenders are widely used routers that routinely receive token approvals
+var DefaultKnownSpenders = []string{
+	"0x7a250d5630B4cF539739dF2C5dAcb4c659F2488D", // Uniswap V2 Router
+	"0xE592427A0AEce92De3Edee1F18E0157C05861564", // Uniswap V3 SwapRouter
+	"0x68b3465833fb72A70ecDF485E0e4C7bD8665Fc45", // Uniswap SwapRouter02
+	"0x3fC91A3afd70395Cd496C647d5a6CC9D4B2b7FAD", // Uniswap Universal Router
+	"0x000000000022D473030F116dDEE9F6B43aC78BA3", // Uniswap Permit2
+	"0x1111111254EEB25477B68fb85Ed929f73A960582", // 1inch Aggregation Router V5
+	"0xDef1C0ded9bec7F1a1670819833240f027b25EfF", // 0x Exchange Proxy
+	"0x1E0049783F008A0085193E00003D00cd54003c71", // OpenSea Conduit
+	"0x00000000000111AbE46ff893f3B2fdF1F759a8A8", // Blur Execution Delegate
+}
+
+// Thresholds holds the current values used by the built-in rules
+type Thresholds struct {
+	LargeTransferETH       float64
+	LargeTransferUSD       float64
+	HighGasPriceMultiplier float64
+	LowGasPriceDivisor     float64
+	RapidWindowSeconds     float64
+	MaxRapidTransactions   int
+	NearMaxAllowanceBits   int
+	FreshContractBlocks    int
+	DrainerWindowSeconds   float64
+	DrainerVictimThreshold int
+}
+
+// DefaultThresholds returns the compiled-in threshold values
+func DefaultThresholds() Thresholds {
+	return Thresholds{
+		LargeTransferETH:       LargeTransferThresholdETH,
+		LargeTransferUSD:       LargeTransferThresholdUSD,
+		HighGasPriceMultiplier: HighGasPriceMultiplier,
+		LowGasPriceDivisor:     LowGasPriceDivisor,
+		RapidWindowSeconds:     RapidTransactionWindow,
+		MaxRapidTransactions:   MaxRapidTransactions,
+		NearMaxAllowanceBits:   NearMaxAllowanceBits,
+		FreshContractBlocks:    FreshContractBlocks,
+		DrainerWindowSeconds:   DrainerWindowSeconds,
+		DrainerVictimThreshold: DrainerVictimThreshold,
+	}
+}
+
+// BlacklistChecker looks up whether an address is blacklisted on a chain
+type BlacklistChecker interface {
+	IsBlacklisted(chainID int64, addr address.Address) (bool, error)
+}
+
+// AllowlistChecker finds the allowlist entry that covers an address for a
+// rule on a chain, or nil when the address isn't allowlisted for it
+type AllowlistChecker interface {
+	Match(chainID int64, addr address.Address, rule string) *models.AllowlistedAddress
+}
+
+// WalletWatcher finds the monitored wallet at an address on a chain, or nil
+// when the address isn't monitored
+type WalletWatcher interface {
+	Watched(chainID int64, addr address.Address) *models.MonitoredWallet
+}
+
+// TokenValuer prices native and token amounts in USD, reporting false when
+// a token's decimals or price are unknown
+type TokenValuer interface {
+	NativeUSDValue(wei *big.Int) (float64, bool)
+	TokenUSDValue(token address.Address, amount *big.Int) (float64, bool)
+}
+
+// FraudDetector scores transactions against the registered rules. It is safe
+// for concurrent use, but order-sensitive rules such as rapid_transactions
+// expect each sender's transactions in order.
 type FraudDetector struct {
-	db                *database.DB
-	recentTxs         map[string][]time.Time // address -> timestamps
-	averageGasPrice   *big.Int
+	chainID    int64
+	blacklist  BlacklistChecker
+	allowlist  AllowlistChecker
+	watchlist  WalletWatcher
+	inspector  ContractInspector
+	valuer     TokenValuer
+	rules      *Registry
+	thresholds atomic.Pointer[Thresholds] // replaced, never modified, on reconfiguration
+
+	gasMu           sync.RWMutex
+	averageGasPrice *big.Int
+
+	recentMu  sync.Mutex
+	recentTxs map[address.Address][]time.Time // address -> timestamps
+
+	spenderMu    sync.Mutex
+	spenderCache map[address.Address]spenderInfo // address -> on-chain facts
+
+	drainerMu         sync.Mutex
+	operatorVictims   map[address.Address]map[address.Address]time.Time // operator -> victim -> last approval
+	victimsSweptAt    time.Time                                         // last time stale operators were pruned
+	suspectedDrainers map[address.Address]bool
+	onDrainer         func(*models.SuspectedDrainer)
+	drainerQueue      chan *models.SuspectedDrainer
+	drainerOnce       sync.Once
+
+	mu                   sync.RWMutex
+	flagThreshold        int
+	watchedFlagThreshold int
+	knownSpenders        map[address.Address]bool     // address -> trusted
+	tokenThresholds      map[address.Address]*big.Int // token address -> raw units
 }
 
 func NewFraudDetector(db *database.DB) *FraudDetector {
-	return &FraudDetector{
-		db:              db,
-		recentTxs:       make(map[string][]time.Time),
-		averageGasPrice: big.NewInt(30000000000), // 30 Gwei default
+	fd := &FraudDetector{
+		rules:                NewRegistry(),
+		recentTxs:            make(map[address.Address][]time.Time),
+		averageGasPrice:      big.NewInt(30000000000), // 30 Gwei default
+		spenderCache:         make(map[address.Address]spenderInfo),
+		operatorVictims:      make(map[address.Address]map[address.Address]time.Time),
+		suspectedDrainers:    make(map[address.Address]bool),
+		drainerQueue:         make(chan *models.SuspectedDrainer, drainerQueueSize),
+		flagThreshold:        DefaultFlagThreshold,
+		watchedFlagThreshold: DefaultWatchedFlagThreshold,
+		knownSpenders:        addressSet(DefaultKnownSpenders),
+		tokenThresholds:      make(map[address.Address]*big.Int),
+	}
+	defaults := DefaultThresholds()
+	fd.thresholds.Store(&defaults)
+	if db != nil {
+		fd.blacklist = db
 	}
+
+	for _, rule := range fd.builtinRules() {
+		if err := fd.rules.Register(rule); err != nil {
+			log.Printf("Failed to register rule: %v", err)
+		}
+	}
+
+	return fd
 }
 
-// AnalyzeTransaction runs all fraud detection heuristics
-func (fd *FraudDetector) AnalyzeTransaction(tx *models.Transaction) (*models.FlaggedTransaction, error) {
-	reasons := []string{}
-	riskScore := 0
+// currentThresholds returns the thresholds in effect; the values are not
+// modified afterwards, so rules may read them while a reload is applied
+func (fd *FraudDetector) currentThresholds() *Thresholds {
+	return fd.thresholds.Load()
+}
+
+// SetChainID scopes blacklist lookups and chain overrides in the rule
+// configuration to one chain; call it before applying a configuration
+func (fd *FraudDetector) SetChainID(chainID int64) {
+	fd.chainID = chainID
+}
+
+// ChainID returns the chain the detector analyzes
+func (fd *FraudDetector) ChainID() int64 {
+	return fd.chainID
+}
+
+// SetBlacklist replaces the database lookups of the blacklist, e.g. with an
+// in-memory cache; call it before analyzing transactions
+func (fd *FraudDetector) SetBlacklist(blacklist BlacklistChecker) {
+	fd.blacklist = blacklist
+}
+
+// SetAllowlist enables suppressing rule scores for trusted counterparties;
+// call it before analyzing transactions
+func (fd *FraudDetector) SetAllowlist(allowlist AllowlistChecker) {
+	fd.allowlist = allowlist
+}
+
+// SetWatchlist enables the stricter flag threshold for transactions involving
+// monitored wallets; call it before analyzing transactions
+func (fd *FraudDetector) SetWatchlist(watchlist WalletWatcher) {
+	fd.watchlist = watchlist
+}
+
+// SetContractInspector enables on-chain lookups such as detecting freshly deployed spenders
+func (fd *FraudDetector) SetContractInspector(inspector ContractInspector) {
+	fd.inspector = inspector
+}
+
+// SetTokenValuer enables USD thresholds for native and token transfers
+func (fd *FraudDetector) SetTokenValuer(valuer TokenValuer) {
+	fd.valuer = valuer
+}
+
+// Rules returns the registry used to add, disable or reweight heuristics
+func (fd *FraudDetector) Rules() *Registry {
+	return fd.rules
+}
 
-	// Heuristic 1: Check for blacklisted addresses
-	if blacklisted, err := fd.checkBlacklist(tx); err != nil {
-		log.Printf("Error checking blacklist: %v", err)
-	} else if blacklisted {
-		reasons = append(reasons, "Blacklisted address detected")
-		riskScore += 40
+// ApplyConfig validates the configuration and applies it, with the overrides
+// for the detector's chain, to the registered rules
+func (fd *FraudDetector) ApplyConfig(cfg *Config) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	cfg = cfg.ForChain(fd.chainID)
+	if err := fd.rules.Configure(cfg.Rules); err != nil {
+		return err
 	}
 
-	// Heuristic 2: Large transfer detection
-	if isLarge, amount := fd.checkLargeTransfer(tx); isLarge {
-		reasons = append(reasons, fmt.Sprintf("Large transfer: %s ETH", amount))
-		riskScore += 25
+	knownSpenders := DefaultKnownSpenders
+	if cfg.KnownSpenders != nil {
+		knownSpenders = cfg.KnownSpenders
 	}
 
-	// Heuristic 3: Unusual timing patterns
-	if rapidFire := fd.checkRapidTransactions(tx); rapidFire {
-		reasons = append(reasons, "Rapid succession of transactions detected")
-		riskScore += 20
+	tokenThresholds := make(map[address.Address]*big.Int, len(cfg.TokenTransferThresholds))
+	for token, value := range cfg.TokenTransferThresholds {
+		threshold, _ := new(big.Int).SetString(value, 10)
+		tokenThresholds[address.MustParse(token)] = threshold
 	}
 
-	// Heuristic 4: Gas price anomaly
-	if unusual := fd.checkUnusualGasPrice(tx); unusual {
-		reasons = append(reasons, "Unusual gas price detected")
-		riskScore += 15
+	fd.mu.Lock()
+	fd.flagThreshold = DefaultFlagThreshold
+	if cfg.FlagThreshold != nil {
+		fd.flagThreshold = *cfg.FlagThreshold
+	}
+	fd.watchedFlagThreshold = DefaultWatchedFlagThreshold
+	if cfg.WatchedFlagThreshold != nil {
+		fd.watchedFlagThreshold = *cfg.WatchedFlagThreshold
 	}
+	fd.knownSpenders = addressSet(knownSpenders)
+	fd.tokenThresholds = tokenThresholds
+	fd.mu.Unlock()
+
+	return nil
+}
 
-	// Heuristic 5: Contract interaction patterns
-	if suspicious := fd.checkContractInteraction(tx); suspicious {
-		reasons = append(reasons, "Suspicious contract interaction")
-		riskScore += 20
+// FlagThreshold returns the minimum risk score for a transaction to be flagged
+func (fd *FraudDetector) FlagThreshold() int {
+	fd.mu.RLock()
+	defer fd.mu.RUnlock()
+	return fd.flagThreshold
+}
+
+// WatchedFlagThreshold returns the minimum risk score for a transaction
+// involving a monitored wallet to be flagged; it is never above FlagThreshold
+func (fd *FraudDetector) WatchedFlagThreshold() int {
+	fd.mu.RLock()
+	defer fd.mu.RUnlock()
+	return min(fd.watchedFlagThreshold, fd.flagThreshold)
+}
+
+// WatchedWallets returns the monitored wallets a transaction involves as
+// sender, recipient or party to one of its token transfers
+func (fd *FraudDetector) WatchedWallets(tx *models.Transaction) []*models.MonitoredWallet {
+	parties := []address.Address{tx.FromAddress}
+	if tx.ToAddress != nil {
+		parties = append(parties, *tx.ToAddress)
 	}
+	for _, transfer := range tx.TokenTransfers {
+		parties = append(parties, transfer.FromAddress, transfer.ToAddress)
+	}
+	return fd.MonitoredWallets(parties)
+}
 
-	// Heuristic 6: Null or burn address
-	if nullAddress := fd.checkNullAddress(tx); nullAddress {
-		reasons = append(reasons, "Transaction to null/burn address")
-		riskScore += 30
+// MonitoredWallets returns the monitored wallets among the addresses on the
+// detector's chain, each once
+func (fd *FraudDetector) MonitoredWallets(addresses []address.Address) []*models.MonitoredWallet {
+	if fd.watchlist == nil {
+		return nil
+	}
+
+	var wallets []*models.MonitoredWallet
+	seen := make(map[int]bool)
+	for _, a := range addresses {
+		wallet := fd.watchlist.Watched(fd.chainID, a)
+		if wallet != nil && !seen[wallet.ID] {
+			seen[wallet.ID] = true
+			wallets = append(wallets, wallet)
+		}
+	}
+	return wallets
+}
+
+// AnalyzeTransaction runs all enabled fraud detection rules. Transactions
+// involving monitored wallets are held to WatchedFlagThreshold and their flags
+// list the wallets' addresses.
+func (fd *FraudDetector) AnalyzeTransaction(tx *models.Transaction) (*models.FlaggedTransaction, error) {
+	findings, errs := fd.rules.Evaluate(tx)
+	for _, err := range errs {
+		log.Printf("Error evaluating %v", err)
+	}
+	fd.applyAllowlist(tx, findings)
+
+	reasons := []string{}
+	riskScore := 0
+	for _, finding := range findings {
+		reasons = append(reasons, finding.Message)
+		riskScore += finding.Score
+	}
+
+	threshold := fd.FlagThreshold()
+	var watched []address.Address
+	for _, wallet := range fd.WatchedWallets(tx) {
+		watched = append(watched, wallet.Address)
+	}
+	if len(watched) > 0 {
+		threshold = fd.WatchedFlagThreshold()
 	}
 
 	// Only flag if risk score is above threshold
-	if riskScore >= 20 {
+	if riskScore >= threshold {
 		flagged := &models.FlaggedTransaction{
-			TxHash:    tx.TxHash,
-			RiskScore: min(riskScore, 100),
-			Reasons:   reasons,
-			Status:    "pending",
+			ChainID:        tx.ChainID,
+			TxHash:         tx.TxHash,
+			RiskScore:      min(riskScore, 100),
+			Reasons:        reasons,
+			ReasonDetails:  findings,
+			Status:         "pending",
+			WatchedWallets: watched,
 		}
 
 		if tx.ID > 0 {
@@ -95,30 +351,97 @@ func (fd *FraudDetector) AnalyzeTransaction(tx *models.Transaction) (*models.Fla
 	return nil, nil
 }
 
-// checkBlacklist verifies if addresses are blacklisted
-func (fd *FraudDetector) checkBlacklist(tx *models.Transaction) (bool, error) {
-	fromBlacklisted, err := fd.db.IsBlacklisted(tx.FromAddress)
+// PreviewTransaction analyzes a transaction seen ahead of block ingestion the
+// way mempool transactions are: the history of order-sensitive rules such as
+// rapid_transactions and drainer escalation is read but not recorded, and
+// spenders are not looked up over RPC. tx is left unchanged.
+func (fd *FraudDetector) PreviewTransaction(tx *models.Transaction) (*models.FlaggedTransaction, error) {
+	preview := *tx
+	preview.Finality = models.FinalityPending
+	return fd.AnalyzeTransaction(&preview)
+}
+
+// applyAllowlist scales down the findings whose rule an allowlist entry covers
+// for one of the transaction's counterparties, recording the entry and the
+// original score in the finding's evidence; the severity follows the new
+// score. When several entries apply, the one suppressing the most wins.
+func (fd *FraudDetector) applyAllowlist(tx *models.Transaction, findings []models.FlagReason) {
+	if fd.allowlist == nil {
+		return
+	}
+
+	for i := range findings {
+		finding := &findings[i]
+		var match *models.AllowlistedAddress
+		for _, counterparty := range counterparties(tx, finding) {
+			entry := fd.allowlist.Match(fd.chainID, counterparty, finding.RuleID)
+			if entry != nil && (match == nil || entry.ScoreFactor < match.ScoreFactor) {
+				match = entry
+			}
+		}
+		if match == nil {
+			continue
+		}
+
+		if finding.Evidence == nil {
+			finding.Evidence = map[string]interface{}{}
+		}
+		finding.Evidence["allowlist"] = map[string]interface{}{
+			"entry_id":       match.ID,
+			"address":        match.Address,
+			"label":          match.Label,
+			"score_factor":   match.ScoreFactor,
+			"original_score": finding.Score,
+		}
+		finding.Score = int(math.Round(float64(finding.Score) * match.ScoreFactor))
+		finding.Severity = severityForScore(finding.Score)
+	}
+}
+
+// counterparties returns the addresses a finding is about: the sender, the
+// recipient, and the spender or operator of an approval
+func counterparties(tx *models.Transaction, finding *models.FlagReason) []address.Address {
+	addresses := []address.Address{tx.FromAddress}
+	if tx.ToAddress != nil {
+		addresses = append(addresses, *tx.ToAddress)
+	}
+	for _, key := range []string{"spender", "operator"} {
+		if party, ok := finding.Evidence[key].(address.Address); ok && party != "" {
+			addresses = append(addresses, party)
+		}
+	}
+	return addresses
+}
+
+// checkBlacklist returns the first blacklisted address involved in the transaction
+func (fd *FraudDetector) checkBlacklist(tx *models.Transaction) (address.Address, error) {
+	if fd.blacklist == nil {
+		return "", nil
+	}
+
+	fromBlacklisted, err := fd.blacklist.IsBlacklisted(fd.chainID, tx.FromAddress)
 	if err != nil {
-		return false, err
+		return "", err
 	}
 	if fromBlacklisted {
-		return true, nil
+		return tx.FromAddress, nil
 	}
 
 	if tx.ToAddress != nil {
-		toBlacklisted, err := fd.db.IsBlacklisted(*tx.ToAddress)
+		toBlacklisted, err := fd.blacklist.IsBlacklisted(fd.chainID, *tx.ToAddress)
 		if err != nil {
-			return false, err
+			return "", err
 		}
 		if toBlacklisted {
-			return true, nil
+			return *tx.ToAddress, nil
 		}
 	}
 
-	return false, nil
+	return "", nil
 }
 
-// checkLargeTransfer detects unusually large transfers
+// checkLargeTransfer detects unusually large transfers, comparing the USD
+// value when ETH can be priced and the ETH amount otherwise
 func (fd *FraudDetector) checkLargeTransfer(tx *models.Transaction) (bool, string) {
 	value := new(big.Int)
 	value.SetString(tx.Value, 10)
@@ -129,7 +452,14 @@ func (fd *FraudDetector) checkLargeTransfer(tx *models.Transaction) (bool, strin
 		big.NewFloat(1e18),
 	)
 
-	threshold := big.NewFloat(LargeTransferThresholdETH)
+	if usd, ok := fd.nativeUSDValue(value); ok {
+		if usd > fd.currentThresholds().LargeTransferUSD {
+			return true, ethValue.Text('f', 4)
+		}
+		return false, ""
+	}
+
+	threshold := big.NewFloat(fd.currentThresholds().LargeTransferETH)
 	if ethValue.Cmp(threshold) > 0 {
 		return true, ethValue.Text('f', 4)
 	}
@@ -137,74 +467,196 @@ func (fd *FraudDetector) checkLargeTransfer(tx *models.Transaction) (bool, strin
 	return false, ""
 }
 
-// checkRapidTransactions detects rapid succession of transactions
-func (fd *FraudDetector) checkRapidTransactions(tx *models.Transaction) bool {
+func (fd *FraudDetector) nativeUSDValue(wei *big.Int) (float64, bool) {
+	if fd.valuer == nil {
+		return 0, false
+	}
+	return fd.valuer.NativeUSDValue(wei)
+}
+
+// largeTokenTransfer is a token movement above its token's threshold, either
+// a configured raw amount or, failing that, the USD threshold
+type largeTokenTransfer struct {
+	transfer  *models.TokenTransfer
+	threshold *big.Int // nil when compared in USD
+	usdValue  float64
+	priced    bool
+}
+
+// checkLargeTokenTransfers returns the token transfers above their token's
+// threshold. Transfers come from receipt logs; for transactions ingested
+// without a receipt, a direct transfer call is decoded from the calldata.
+func (fd *FraudDetector) checkLargeTokenTransfers(tx *models.Transaction) []largeTokenTransfer {
+	transfers := tx.TokenTransfers
+	if tx.Status == nil {
+		transfers = calldataTokenTransfers(tx)
+	}
+
+	var large []largeTokenTransfer
+	for _, transfer := range transfers {
+		amount, ok := new(big.Int).SetString(transfer.Amount, 10)
+		if !ok {
+			continue
+		}
+
+		candidate := largeTokenTransfer{transfer: transfer}
+		if fd.valuer != nil {
+			candidate.usdValue, candidate.priced = fd.valuer.TokenUSDValue(transfer.Token, amount)
+		}
+
+		// An explicit per-token threshold takes precedence over the USD threshold
+		if threshold := fd.tokenTransferThreshold(transfer.Token); threshold != nil {
+			if amount.Cmp(threshold) > 0 {
+				candidate.threshold = threshold
+				large = append(large, candidate)
+			}
+			continue
+		}
+		if candidate.priced && candidate.usdValue > fd.currentThresholds().LargeTransferUSD {
+			large = append(large, candidate)
+		}
+	}
+	return large
+}
+
+// calldataTokenTransfers decodes a direct transfer or transferFrom call
+func calldataTokenTransfers(tx *models.Transaction) []*models.TokenTransfer {
+	if tx.InputData == nil || tx.ToAddress == nil {
+		return nil
+	}
+
+	call, err := decoder.Decode(*tx.InputData)
+	if err != nil || !call.IsTransfer() {
+		return nil
+	}
+
+	from := call.From
+	if from == "" {
+		from = tx.FromAddress
+	}
+	return []*models.TokenTransfer{{
+		TxHash:      tx.TxHash,
+		BlockNumber: tx.BlockNumber,
+		Token:       *tx.ToAddress,
+		FromAddress: from,
+		ToAddress:   call.Recipient,
+		Amount:      call.Amount.String(),
+		Timestamp:   tx.Timestamp,
+	}}
+}
+
+// checkRapidTransactions detects rapid succession of transactions, returning
+// the sender's transaction count within the window. Pending transactions are
+// counted but not recorded, since they are analyzed again once mined.
+func (fd *FraudDetector) checkRapidTransactions(tx *models.Transaction) (int, bool) {
 	now := tx.Timestamp
-	address := tx.FromAddress
+	sender := tx.FromAddress
+
+	fd.recentMu.Lock()
+	defer fd.recentMu.Unlock()
 
 	// Clean old timestamps
-	if timestamps, exists := fd.recentTxs[address]; exists {
+	if timestamps, exists := fd.recentTxs[sender]; exists {
 		filtered := []time.Time{}
 		for _, ts := range timestamps {
-			if now.Sub(ts).Seconds() <= RapidTransactionWindow {
+			if now.Sub(ts).Seconds() <= fd.currentThresholds().RapidWindowSeconds {
 				filtered = append(filtered, ts)
 			}
 		}
-		fd.recentTxs[address] = filtered
+		fd.recentTxs[sender] = filtered
 	}
 
-	// Add current transaction
-	fd.recentTxs[address] = append(fd.recentTxs[address], now)
+	count := len(fd.recentTxs[sender]) + 1
+	if tx.Finality != models.FinalityPending {
+		// Add current transaction
+		fd.recentTxs[sender] = append(fd.recentTxs[sender], now)
+	}
 
 	// Check if exceeds threshold
-	return len(fd.recentTxs[address]) > MaxRapidTransactions
+	return count, count > fd.currentThresholds().MaxRapidTransactions
 }
 
-// checkUnusualGasPrice detects abnormal gas prices
-func (fd *FraudDetector) checkUnusualGasPrice(tx *models.Transaction) bool {
+// ForgetTransactions reverts the history recorded for transactions rolled back
+// by a reorg, so the canonical chain is not counted on top of them
+func (fd *FraudDetector) ForgetTransactions(txs []*models.Transaction) {
+	fd.recentMu.Lock()
+	defer fd.recentMu.Unlock()
+	for _, tx := range txs {
+		timestamps := fd.recentTxs[tx.FromAddress]
+		for i, ts := range timestamps {
+			if ts.Equal(tx.Timestamp) {
+				fd.recentTxs[tx.FromAddress] = append(timestamps[:i], timestamps[i+1:]...)
+				break
+			}
+		}
+		fd.forgetApprovalForAll(tx)
+	}
+}
+
+// currentAverageGasPrice returns the rolling average gas price
+func (fd *FraudDetector) currentAverageGasPrice() *big.Int {
+	fd.gasMu.RLock()
+	defer fd.gasMu.RUnlock()
+	return fd.averageGasPrice
+}
+
+// checkUnusualGasPrice detects abnormal gas prices against the average it
+// returns
+func (fd *FraudDetector) checkUnusualGasPrice(tx *models.Transaction) (*big.Int, bool) {
 	gasPrice := new(big.Int)
 	gasPrice.SetString(tx.GasPrice, 10)
+	averageGasPrice := fd.currentAverageGasPrice()
 
 	// Check if gas price is unusually high
-	threshold := new(big.Int).Mul(fd.averageGasPrice, big.NewInt(int64(HighGasPriceMultiplier)))
-	if gasPrice.Cmp(threshold) > 0 {
-		return true
+	average := new(big.Float).SetInt(averageGasPrice)
+	price := new(big.Float).SetInt(gasPrice)
+	threshold := new(big.Float).Mul(average, big.NewFloat(fd.currentThresholds().HighGasPriceMultiplier))
+	if price.Cmp(threshold) > 0 {
+		return averageGasPrice, true
 	}
 
 	// Check if gas price is suspiciously low (potential front-running)
-	minPrice := new(big.Int).Div(fd.averageGasPrice, big.NewInt(10))
-	if gasPrice.Cmp(minPrice) < 0 && gasPrice.Cmp(big.NewInt(0)) > 0 {
-		return true
+	minPrice := new(big.Float).Quo(average, big.NewFloat(fd.currentThresholds().LowGasPriceDivisor))
+	if price.Cmp(minPrice) < 0 && gasPrice.Sign() > 0 {
+		return averageGasPrice, true
 	}
 
-	return false
+	return averageGasPrice, false
 }
 
-// checkContractInteraction detects suspicious contract interactions
-func (fd *FraudDetector) checkContractInteraction(tx *models.Transaction) bool {
-	// If there's input data and it's not a simple transfer
-	if tx.InputData != nil && len(*tx.InputData) > 10 {
-		data := *tx.InputData
-		
-		// Check for common malicious patterns
-		// Note: In production, this would be more sophisticated
-		suspiciousPatterns := []string{
-			"0xa9059cbb", // transfer (could be token draining)
-			"0x095ea7b3", // approve (unlimited approvals are risky)
-		}
-
-		for _, pattern := range suspiciousPatterns {
-			if strings.HasPrefix(strings.ToLower(data), pattern) {
-				// Additional checks could be added here
-				// For now, we'll flag high-value approvals
-				if tx.ToAddress != nil && len(data) > 138 {
-					return true
-				}
-			}
-		}
+// checkContractInteraction decodes token calldata and returns why the call is
+// suspicious, or an empty string: limited approvals to unknown spenders.
+// Unlimited approvals are scored by the unlimited_approval rule and large
+// token transfers by the large_transfer rule.
+func (fd *FraudDetector) checkContractInteraction(tx *models.Transaction) (string, *decoder.Call) {
+	if tx.InputData == nil || tx.ToAddress == nil {
+		return "", nil
 	}
 
-	return false
+	call, err := decoder.Decode(*tx.InputData)
+	if err != nil {
+		return "", nil
+	}
+
+	if call.IsApproval() && !fd.isNearMaxAllowance(call.Amount) && !fd.isKnownSpender(call.Spender) {
+		return "Token approval to unknown spender", call
+	}
+
+	return "", nil
+}
+
+// isKnownSpender reports whether an approval spender is a trusted contract
+func (fd *FraudDetector) isKnownSpender(spender address.Address) bool {
+	fd.mu.RLock()
+	defer fd.mu.RUnlock()
+	return fd.knownSpenders[spender]
+}
+
+// tokenTransferThreshold returns the raw-unit transfer threshold for a token, if configured
+func (fd *FraudDetector) tokenTransferThreshold(token address.Address) *big.Int {
+	fd.mu.RLock()
+	defer fd.mu.RUnlock()
+	return fd.tokenThresholds[token]
 }
 
 // checkNullAddress detects transactions to null or burn addresses
@@ -213,14 +665,13 @@ func (fd *FraudDetector) checkNullAddress(tx *models.Transaction) bool {
 		return false
 	}
 
-	nullAddresses := []string{
+	nullAddresses := []address.Address{
 		"0x0000000000000000000000000000000000000000",
 		"0x000000000000000000000000000000000000dead",
 	}
 
-	toAddr := strings.ToLower(*tx.ToAddress)
 	for _, null := range nullAddresses {
-		if toAddr == null {
+		if *tx.ToAddress == null {
 			return true
 		}
 	}
@@ -234,16 +685,28 @@ func (fd *FraudDetector) UpdateAverageGasPrice(gasPrice *big.Int) {
 	alpha := big.NewFloat(0.1) // Weight for new value
 	oldWeight := big.NewFloat(0.9)
 
+	fd.gasMu.Lock()
+	defer fd.gasMu.Unlock()
+
 	oldAvg := new(big.Float).SetInt(fd.averageGasPrice)
 	newVal := new(big.Float).SetInt(gasPrice)
 
 	weighted := new(big.Float).Mul(newVal, alpha)
 	oldWeighted := new(big.Float).Mul(oldAvg, oldWeight)
-	
+
 	newAvg := new(big.Float).Add(weighted, oldWeighted)
 	fd.averageGasPrice, _ = newAvg.Int(nil)
 }
 
+// addressSet builds a lookup set of validated addresses
+func addressSet(addresses []string) map[address.Address]bool {
+	set := make(map[address.Address]bool, len(addresses))
+	for _, a := range addresses {
+		set[address.MustParse(a)] = true
+	}
+	return set
+}
+
 func min(a, b int) int {
 	if a < b {
 		return a
@@ -1,14 +1,18 @@
 package detector
 
 import (
+	"fmt"
+	"math/big"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/minsix/backend/internal/address"
 	"github.com/minsix/backend/internal/models"
 )
 
 func TestCheckLargeTransfer(t *testing.T) {
-	fd := &FraudDetector{}
+	fd := NewFraudDetector(nil)
 
 	tests := []struct {
 		name     string
@@ -46,26 +50,26 @@ func TestCheckLargeTransfer(t *testing.T) {
 }
 
 func TestCheckNullAddress(t *testing.T) {
-	fd := &FraudDetector{}
+	fd := NewFraudDetector(nil)
 
 	tests := []struct {
 		name      string
-		toAddress *string
+		toAddress *address.Address
 		expected  bool
 	}{
 		{
 			name:      "Null address",
-			toAddress: stringPtr("0x0000000000000000000000000000000000000000"),
+			toAddress: address.FromHex("0x0000000000000000000000000000000000000000").Ptr(),
 			expected:  true,
 		},
 		{
 			name:      "Burn address",
-			toAddress: stringPtr("0x000000000000000000000000000000000000dead"),
+			toAddress: address.FromHex("0x000000000000000000000000000000000000dead").Ptr(),
 			expected:  true,
 		},
 		{
 			name:      "Normal address",
-			toAddress: stringPtr("0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb"),
+			toAddress: address.FromHex("0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb").Ptr(),
 			expected:  false,
 		},
 		{
@@ -89,72 +93,452 @@ func TestCheckNullAddress(t *testing.T) {
 }
 
 func TestCheckRapidTransactions(t *testing.T) {
-	fd := &FraudDetector{
-		recentTxs: make(map[string][]time.Time),
-	}
+	fd := NewFraudDetector(nil)
 
-	address := "0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb"
+	sender := address.FromHex("0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb")
 	now := time.Now()
 
 	// Add transactions rapidly
 	for i := 0; i < 6; i++ {
 		tx := &models.Transaction{
-			FromAddress: address,
+			FromAddress: sender,
 			Timestamp:   now.Add(time.Duration(i) * time.Second),
 		}
-		result := fd.checkRapidTransactions(tx)
-		
+		_, result := fd.checkRapidTransactions(tx)
+
 		// Should only flag after exceeding threshold
-		if i <= MaxRapidTransactions && result {
+		count := i + 1
+		if count <= MaxRapidTransactions && result {
 			t.Errorf("Transaction %d: expected false, got true", i)
 		}
-		if i > MaxRapidTransactions && !result {
+		if count > MaxRapidTransactions && !result {
 			t.Errorf("Transaction %d: expected true, got false", i)
 		}
 	}
 }
 
+func TestForgetTransactions(t *testing.T) {
+	fd := NewFraudDetector(nil)
+
+	sender := address.FromHex("0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb")
+	now := time.Now()
+
+	var orphaned []*models.Transaction
+	for i := 0; i < MaxRapidTransactions; i++ {
+		tx := &models.Transaction{FromAddress: sender, Timestamp: now.Add(time.Duration(i) * time.Second)}
+		fd.checkRapidTransactions(tx)
+		orphaned = append(orphaned, tx)
+	}
+	fd.ForgetTransactions(orphaned)
+
+	// Replaying the same transactions from the canonical chain counts them once
+	for i, tx := range orphaned {
+		if _, rapid := fd.checkRapidTransactions(tx); rapid {
+			t.Errorf("Transaction %d: expected false after reorg, got true", i)
+		}
+	}
+}
+
+func TestCheckRapidTransactionsPending(t *testing.T) {
+	fd := NewFraudDetector(nil)
+
+	sender := address.FromHex("0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb")
+	now := time.Now()
+	for i := 0; i < MaxRapidTransactions; i++ {
+		fd.checkRapidTransactions(&models.Transaction{FromAddress: sender, Timestamp: now})
+	}
+
+	pending := &models.Transaction{FromAddress: sender, Finality: models.FinalityPending, Timestamp: now}
+	for i := 0; i < 2; i++ {
+		if _, rapid := fd.checkRapidTransactions(pending); !rapid {
+			t.Fatal("pending transaction over the limit was not flagged")
+		}
+	}
+	if got := len(fd.recentTxs[sender]); got != MaxRapidTransactions {
+		t.Errorf("recorded %d transactions, want pending ones left out of %d", got, MaxRapidTransactions)
+	}
+}
+
+func TestPreviewTransaction(t *testing.T) {
+	fd := NewFraudDetector(nil)
+
+	sender := address.FromHex("0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb")
+	tx := &models.Transaction{FromAddress: sender, Value: "0", GasPrice: "30000000000", Timestamp: time.Now()}
+	for i := 0; i < 2; i++ {
+		if _, err := fd.PreviewTransaction(tx); err != nil {
+			t.Fatalf("PreviewTransaction() error = %v", err)
+		}
+	}
+	if got := len(fd.recentTxs[sender]); got != 0 {
+		t.Errorf("recorded %d previewed transactions, want none", got)
+	}
+	if tx.Finality != "" {
+		t.Errorf("PreviewTransaction() changed the finality to %q", tx.Finality)
+	}
+}
+
 func TestCheckContractInteraction(t *testing.T) {
-	fd := &FraudDetector{}
+	fd := NewFraudDetector(nil)
+	dai := "0x6B175474E89094C44Da98b954EedeAC495271d0F"
+	weth := "0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2"
+	if err := fd.ApplyConfig(&Config{
+		TokenTransferThresholds: map[string]string{
+			dai:  "1000000000000000000000000", // 1M DAI
+			weth: "500000000000000000",        // 0.5 WETH
+		},
+	}); err != nil {
+		t.Fatalf("ApplyConfig() error = %v", err)
+	}
 
 	tests := []struct {
 		name      string
+		token     string
 		inputData *string
 		expected  bool
 	}{
 		{
 			name:      "No input data",
+			token:     dai,
 			inputData: nil,
 			expected:  false,
 		},
 		{
 			name:      "Simple transfer",
+			token:     dai,
 			inputData: stringPtr("0x"),
 			expected:  false,
 		},
 		{
-			name:      "Transfer function with sufficient data",
-			inputData: stringPtr("0xa9059cbb000000000000000000000000742d35cc6634c0532925a3b844bc9e7595f0beb0000000000000000000000000000000000000000000000000de0b6b3a7640000"),
+			name:      "Token transfer below threshold",
+			token:     dai,
+			inputData: stringPtr("0xa9059cbb000000000000000000000000742d35cc6634c0532925a3b844bc9e7595f0beb00000000000000000000000000000000000000000000000000de0b6b3a7640000"),
+			expected:  false,
+		},
+		{
+			name:      "Token transfer above threshold is left to large_transfer rule",
+			token:     weth,
+			inputData: stringPtr("0xa9059cbb000000000000000000000000742d35cc6634c0532925a3b844bc9e7595f0beb00000000000000000000000000000000000000000000000000de0b6b3a7640000"),
+			expected:  false,
+		},
+		{
+			name:      "Unlimited approval is left to unlimited_approval rule",
+			token:     dai,
+			inputData: stringPtr("0x095ea7b3000000000000000000000000742d35cc6634c0532925a3b844bc9e7595f0beb0ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"),
+			expected:  false,
+		},
+		{
+			name:      "Limited approval to known router",
+			token:     dai,
+			inputData: stringPtr("0x095ea7b30000000000000000000000007a250d5630b4cf539739df2c5dacb4c659f2488d00000000000000000000000000000000000000000000000000000000000f4240"),
+			expected:  false,
+		},
+		{
+			name:      "Limited approval to unknown spender",
+			token:     dai,
+			inputData: stringPtr("0x095ea7b3000000000000000000000000742d35cc6634c0532925a3b844bc9e7595f0beb000000000000000000000000000000000000000000000000000000000000f4240"),
 			expected:  true,
 		},
+		{
+			name:      "Truncated calldata",
+			token:     dai,
+			inputData: stringPtr("0xa9059cbb000000000000000000000000742d35cc6634c0532925a3b844bc9e7595f0beb"),
+			expected:  false,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			toAddress := "0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb"
+			toAddress := address.FromHex(tt.token)
 			tx := &models.Transaction{
 				ToAddress: &toAddress,
 				InputData: tt.inputData,
 			}
-			result := fd.checkContractInteraction(tx)
-			if result != tt.expected {
-				t.Errorf("checkContractInteraction() = %v, want %v", result, tt.expected)
+			reason, _ := fd.checkContractInteraction(tx)
+			if (reason != "") != tt.expected {
+				t.Errorf("checkContractInteraction() = %q, want flagged %v", reason, tt.expected)
 			}
 		})
 	}
 }
 
+func TestCheckLargeTokenTransfers(t *testing.T) {
+	fd := NewFraudDetector(nil)
+	weth := "0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2"
+	if err := fd.ApplyConfig(&Config{
+		TokenTransferThresholds: map[string]string{weth: "500000000000000000"}, // 0.5 WETH
+	}); err != nil {
+		t.Fatalf("ApplyConfig() error = %v", err)
+	}
+
+	// 1 WETH transfer decoded from calldata when no receipt was fetched
+	calldata := &models.Transaction{
+		FromAddress: "0x0000000000000000000000000000000000000001",
+		ToAddress:   address.FromHex(weth).Ptr(),
+		InputData:   stringPtr("0xa9059cbb000000000000000000000000742d35cc6634c0532925a3b844bc9e7595f0beb00000000000000000000000000000000000000000000000000de0b6b3a7640000"),
+	}
+	if large := fd.checkLargeTokenTransfers(calldata); len(large) != 1 || large[0].transfer.Amount != "1000000000000000000" {
+		t.Errorf("checkLargeTokenTransfers() calldata = %+v, want one transfer", large)
+	}
+
+	// With a receipt, the logs are authoritative: a router call moving WETH is
+	// caught and a reverted direct transfer with no logs is not
+	router := address.FromHex("0x7a250d5630B4cF539739dF2C5dAcb4c659F2488D")
+	status := models.TxStatusSuccess
+	swap := &models.Transaction{
+		ToAddress: &router,
+		Status:    &status,
+		TokenTransfers: []*models.TokenTransfer{
+			{Token: address.FromHex(weth), Amount: "100000000000000000", LogIndex: 1}, // 0.1 WETH
+			{Token: address.FromHex(weth), Amount: "2000000000000000000", LogIndex: 2},
+			{Token: address.FromHex("0x6B175474E89094C44Da98b954EedeAC495271d0F"), Amount: "5000000000000000000000"},
+		},
+	}
+	if large := fd.checkLargeTokenTransfers(swap); len(large) != 1 || large[0].transfer.LogIndex != 2 {
+		t.Errorf("checkLargeTokenTransfers() logs = %+v, want log 2", large)
+	}
+
+	reverted := models.TxStatusReverted
+	calldata.Status = &reverted
+	if large := fd.checkLargeTokenTransfers(calldata); len(large) != 0 {
+		t.Errorf("checkLargeTokenTransfers() reverted = %+v, want none", large)
+	}
+
+	result, err := fd.evalLargeTransfer(swap)
+	if err != nil || result == nil {
+		t.Fatalf("evalLargeTransfer() = %v, %v, want result", result, err)
+	}
+	if transfers, ok := result.Evidence["token_transfers"].([]map[string]interface{}); !ok || len(transfers) != 1 {
+		t.Errorf("evalLargeTransfer() evidence = %+v", result.Evidence)
+	}
+}
+
+// fakeValuer prices ETH and one token at fixed USD rates
+type fakeValuer struct {
+	ethUSD   float64
+	token    address.Address
+	tokenUSD float64 // per raw unit
+}
+
+func (v fakeValuer) NativeUSDValue(wei *big.Int) (float64, bool) {
+	eth, _ := new(big.Float).Quo(new(big.Float).SetInt(wei), big.NewFloat(1e18)).Float64()
+	return eth * v.ethUSD, true
+}
+
+func (v fakeValuer) TokenUSDValue(token address.Address, amount *big.Int) (float64, bool) {
+	if token != v.token {
+		return 0, false
+	}
+	raw, _ := new(big.Float).SetInt(amount).Float64()
+	return raw * v.tokenUSD, true
+}
+
+func TestLargeTransferUSD(t *testing.T) {
+	usdc := address.FromHex("0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48")
+	fd := NewFraudDetector(nil)
+	fd.SetTokenValuer(fakeValuer{ethUSD: 3000, token: usdc, tokenUSD: 1e-6})
+
+	// 9 ETH is below the ETH fallback but above $25,000 at $3,000/ETH
+	if large, _ := fd.checkLargeTransfer(&models.Transaction{Value: "9000000000000000000"}); !large {
+		t.Error("checkLargeTransfer(9 ETH) = false, want true in USD mode")
+	}
+	if large, _ := fd.checkLargeTransfer(&models.Transaction{Value: "8000000000000000000"}); large {
+		t.Error("checkLargeTransfer(8 ETH) = true, want false in USD mode")
+	}
+
+	status := models.TxStatusSuccess
+	tx := &models.Transaction{
+		Value:  "0",
+		Status: &status,
+		TokenTransfers: []*models.TokenTransfer{
+			{Token: usdc, Amount: "30000000000", LogIndex: 0}, // $30,000
+			{Token: usdc, Amount: "20000000000", LogIndex: 1}, // $20,000
+			{Token: address.FromHex("0x6B175474E89094C44Da98b954EedeAC495271d0F"), Amount: "1000000000000000000000000"},
+		},
+	}
+	large := fd.checkLargeTokenTransfers(tx)
+	if len(large) != 1 || large[0].transfer.LogIndex != 0 || large[0].usdValue != 30000 {
+		t.Fatalf("checkLargeTokenTransfers() = %+v, want the $30,000 transfer", large)
+	}
+
+	result, err := fd.evalLargeTransfer(tx)
+	if err != nil || result == nil {
+		t.Fatalf("evalLargeTransfer() = %v, %v, want result", result, err)
+	}
+	transfers := result.Evidence["token_transfers"].([]map[string]interface{})
+	if transfers[0]["usd_value"] != 30000.0 || transfers[0]["threshold_usd"] != LargeTransferThresholdUSD {
+		t.Errorf("evalLargeTransfer() evidence = %+v", transfers[0])
+	}
+}
+
 // Helper function
 func stringPtr(s string) *string {
 	return &s
 }
+
+func TestAnalyzeTransactionReasonDetails(t *testing.T) {
+	fd := NewFraudDetector(nil)
+	fd.Rules().Disable(RuleBlacklist)
+
+	tx := &models.Transaction{
+		TxHash:    "0xabc",
+		ToAddress: address.FromHex("0x000000000000000000000000000000000000dead").Ptr(),
+		Value:     "50000000000000000000", // 50 ETH
+		GasPrice:  "30000000000",
+		Timestamp: time.Now(),
+	}
+
+	flagged, err := fd.AnalyzeTransaction(tx)
+	if err != nil || flagged == nil {
+		t.Fatalf("AnalyzeTransaction() = %v, %v, want flag", flagged, err)
+	}
+	if len(flagged.ReasonDetails) != len(flagged.Reasons) {
+		t.Fatalf("got %d reason details for %d reasons", len(flagged.ReasonDetails), len(flagged.Reasons))
+	}
+
+	details := map[string]models.FlagReason{}
+	for _, detail := range flagged.ReasonDetails {
+		details[detail.RuleID] = detail
+	}
+
+	large, ok := details[RuleLargeTransfer]
+	if !ok {
+		t.Fatal("missing large_transfer reason")
+	}
+	if large.Score != 25 || large.Severity != models.SeverityHigh || large.Evidence["amount"] != "50.0000" {
+		t.Errorf("unexpected large_transfer reason: %+v", large)
+	}
+	if details[RuleNullAddress].Evidence["matched_address"] != *tx.ToAddress {
+		t.Errorf("unexpected null_address reason: %+v", details[RuleNullAddress])
+	}
+}
+
+func TestAnalyzeTransactionConcurrent(t *testing.T) {
+	fd := NewFraudDetector(nil)
+	fd.Rules().Disable(RuleBlacklist)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				fd.AnalyzeTransaction(&models.Transaction{
+					TxHash:      fmt.Sprintf("0x%x%x", i, j),
+					FromAddress: "0x742d35cc6634c0532925a3b844bc9e7595f0beb0",
+					ToAddress:   address.FromHex("0x000000000000000000000000000000000000dead").Ptr(),
+					Value:       "0",
+					GasPrice:    "200000000000",
+					Timestamp:   time.Now(),
+				})
+				fd.UpdateAverageGasPrice(big.NewInt(30000000000))
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+// fakeAllowlist allowlists one address with an entry per rule
+type fakeAllowlist struct {
+	address address.Address
+	entries map[string]*models.AllowlistedAddress
+}
+
+func (f *fakeAllowlist) Match(chainID int64, addr address.Address, rule string) *models.AllowlistedAddress {
+	if addr != f.address {
+		return nil
+	}
+	return f.entries[rule]
+}
+
+func TestAnalyzeTransactionAllowlist(t *testing.T) {
+	hotWallet := address.FromHex("0x28c6c06298d514db089934071355e5743bf21d60")
+	fd := NewFraudDetector(nil)
+	fd.SetAllowlist(&fakeAllowlist{address: hotWallet, entries: map[string]*models.AllowlistedAddress{
+		RuleLargeTransfer:   {ID: 1, Address: hotWallet, Label: "Exchange hot wallet", ScoreFactor: 0},
+		RuleUnusualGasPrice: {ID: 2, Address: hotWallet, Label: "Exchange hot wallet", ScoreFactor: 0.5},
+	}})
+
+	tx := &models.Transaction{
+		TxHash:      "0xabc",
+		FromAddress: hotWallet,
+		ToAddress:   address.FromHex("0x000000000000000000000000000000000000dead").Ptr(),
+		Value:       "50000000000000000000", // 50 ETH
+		GasPrice:    "200000000000",         // well above the 30 Gwei average
+		Timestamp:   time.Now(),
+	}
+
+	flagged, err := fd.AnalyzeTransaction(tx)
+	if err != nil || flagged == nil {
+		t.Fatalf("AnalyzeTransaction() = %v, %v, want flag", flagged, err)
+	}
+	// null_address 30 + large_transfer 25*0 + unusual_gas_price round(15*0.5)
+	if flagged.RiskScore != 38 {
+		t.Errorf("risk score = %d, want 38", flagged.RiskScore)
+	}
+
+	details := map[string]models.FlagReason{}
+	for _, detail := range flagged.ReasonDetails {
+		details[detail.RuleID] = detail
+	}
+	large := details[RuleLargeTransfer]
+	suppression, ok := large.Evidence["allowlist"].(map[string]interface{})
+	if large.Score != 0 || large.Severity != models.SeverityLow || !ok {
+		t.Fatalf("unexpected large_transfer reason: %+v", large)
+	}
+	if suppression["entry_id"] != 1 || suppression["original_score"] != 25 || suppression["label"] != "Exchange hot wallet" {
+		t.Errorf("unexpected suppression evidence: %+v", suppression)
+	}
+	if details[RuleUnusualGasPrice].Score != 8 {
+		t.Errorf("unusual_gas_price score = %d, want 8", details[RuleUnusualGasPrice].Score)
+	}
+	if _, ok := details[RuleNullAddress].Evidence["allowlist"]; ok || details[RuleNullAddress].Score != 30 {
+		t.Errorf("null_address was suppressed: %+v", details[RuleNullAddress])
+	}
+}
+
+// fakeWatchlist monitors one wallet
+type fakeWatchlist struct {
+	wallet *models.MonitoredWallet
+}
+
+func (f *fakeWatchlist) Watched(chainID int64, addr address.Address) *models.MonitoredWallet {
+	if addr != f.wallet.Address {
+		return nil
+	}
+	return f.wallet
+}
+
+func TestAnalyzeTransactionWatchedWallet(t *testing.T) {
+	treasury := address.FromHex("0x28c6c06298d514db089934071355e5743bf21d60")
+	tx := &models.Transaction{
+		TxHash:      "0xabc",
+		FromAddress: "0x1111111111111111111111111111111111111111",
+		ToAddress:   treasury.Ptr(),
+		Value:       "0",
+		GasPrice:    "200000000000", // well above the 30 Gwei average, scores 15
+		Timestamp:   time.Now(),
+	}
+
+	fd := NewFraudDetector(nil)
+	flagged, err := fd.AnalyzeTransaction(tx)
+	if err != nil || flagged != nil {
+		t.Fatalf("AnalyzeTransaction() = %v, %v, want no flag for an unwatched wallet", flagged, err)
+	}
+
+	fd.SetWatchlist(&fakeWatchlist{wallet: &models.MonitoredWallet{ID: 1, Address: treasury}})
+	flagged, err = fd.AnalyzeTransaction(tx)
+	if err != nil || flagged == nil {
+		t.Fatalf("AnalyzeTransaction() = %v, %v, want flag for a watched wallet", flagged, err)
+	}
+	if len(flagged.WatchedWallets) != 1 || flagged.WatchedWallets[0] != treasury {
+		t.Errorf("watched wallets = %v, want [%s]", flagged.WatchedWallets, treasury)
+	}
+
+	threshold := 20
+	if err := fd.ApplyConfig(&Config{WatchedFlagThreshold: &threshold}); err != nil {
+		t.Fatal(err)
+	}
+	if flagged, _ := fd.AnalyzeTransaction(tx); flagged != nil {
+		t.Errorf("flagged below watched_flag_threshold: %+v", flagged)
+	}
+}
@@ -0,0 +1,188 @@
+package detector
+
+import (
+	"log"
+	"time"
+
+	"github.com/minsix/backend/internal/address"
+	"github.com/minsix/backend/internal/decoder"
+	"github.com/minsix/backend/internal/models"
+)
+
+// nftApproval is the outcome of checking a setApprovalForAll call
+type nftApproval struct {
+	call      *decoder.Call
+	victims   int  // distinct victims approving the operator within the window
+	suspected bool // operator is blacklisted or on the suspected-drainer list
+	escalated bool // this approval pushed the operator onto the list
+}
+
+// drainerQueueSize bounds the escalations waiting to be handed to the
+// OnDrainerSuspected callback
+const drainerQueueSize = 256
+
+// OnDrainerSuspected registers a callback invoked when an operator is escalated
+// to the suspected-drainer list, e.g. to persist it. The callback runs on its
+// own goroutine so slow handlers don't hold up analysis.
+func (fd *FraudDetector) OnDrainerSuspected(fn func(*models.SuspectedDrainer)) {
+	fd.drainerMu.Lock()
+	defer fd.drainerMu.Unlock()
+	fd.onDrainer = fn
+	fd.drainerOnce.Do(func() {
+		go fd.deliverDrainers()
+	})
+}
+
+// deliverDrainers hands queued escalations to the registered callback
+func (fd *FraudDetector) deliverDrainers() {
+	for drainer := range fd.drainerQueue {
+		fd.drainerMu.Lock()
+		onDrainer := fd.onDrainer
+		fd.drainerMu.Unlock()
+		onDrainer(drainer)
+	}
+}
+
+// LoadSuspectedDrainers seeds the suspected-drainer list, e.g. from the database at startup
+func (fd *FraudDetector) LoadSuspectedDrainers(addresses []address.Address) {
+	fd.drainerMu.Lock()
+	defer fd.drainerMu.Unlock()
+	for _, a := range addresses {
+		fd.suspectedDrainers[a] = true
+	}
+}
+
+// IsSuspectedDrainer reports whether an operator has been escalated
+func (fd *FraudDetector) IsSuspectedDrainer(operator address.Address) bool {
+	fd.drainerMu.Lock()
+	defer fd.drainerMu.Unlock()
+	return fd.suspectedDrainers[operator]
+}
+
+// checkNFTApprovalForAll tracks setApprovalForAll(operator, true) grants to
+// operators that are not known marketplaces, escalating an operator once
+// enough distinct victims approve it within the window. Pending transactions
+// are scored without being recorded.
+func (fd *FraudDetector) checkNFTApprovalForAll(tx *models.Transaction) (*nftApproval, error) {
+	if tx.InputData == nil || tx.ToAddress == nil {
+		return nil, nil
+	}
+
+	call, err := decoder.Decode(*tx.InputData)
+	if err != nil || call.Method != decoder.MethodSetApprovalForAll || !call.Approved {
+		return nil, nil
+	}
+	if fd.isKnownSpender(call.Operator) {
+		return nil, nil
+	}
+
+	result := &nftApproval{call: call}
+	if fd.blacklist != nil {
+		blacklisted, err := fd.blacklist.IsBlacklisted(fd.chainID, call.Operator)
+		if err != nil {
+			return nil, err
+		}
+		result.suspected = blacklisted
+	}
+
+	operator := call.Operator
+	window := time.Duration(fd.currentThresholds().DrainerWindowSeconds * float64(time.Second))
+
+	fd.drainerMu.Lock()
+	if tx.Finality == models.FinalityPending {
+		// Report the count the approval would reach without recording it
+		result.victims = countVictims(fd.operatorVictims[operator], tx.FromAddress, tx.Timestamp, window)
+		result.suspected = result.suspected || fd.suspectedDrainers[operator]
+		fd.drainerMu.Unlock()
+		return result, nil
+	}
+	victims := fd.operatorVictims[operator]
+	if victims == nil {
+		victims = make(map[address.Address]time.Time)
+		fd.operatorVictims[operator] = victims
+	}
+	for victim, seen := range victims {
+		if tx.Timestamp.Sub(seen) > window {
+			delete(victims, victim)
+		}
+	}
+	victims[tx.FromAddress] = tx.Timestamp
+	result.victims = len(victims)
+
+	if !fd.suspectedDrainers[operator] && result.victims >= fd.currentThresholds().DrainerVictimThreshold {
+		fd.suspectedDrainers[operator] = true
+		result.escalated = true
+	}
+	result.suspected = result.suspected || fd.suspectedDrainers[operator]
+	if tx.Timestamp.Sub(fd.victimsSweptAt) > window {
+		fd.pruneOperatorVictims(tx.Timestamp, window)
+		fd.victimsSweptAt = tx.Timestamp
+	}
+	notify := result.escalated && fd.onDrainer != nil
+	fd.drainerMu.Unlock()
+
+	if notify {
+		drainer := &models.SuspectedDrainer{
+			ChainID:     fd.chainID,
+			Address:     operator,
+			VictimCount: result.victims,
+			Reason:      "setApprovalForAll granted by multiple victims",
+			EscalatedAt: tx.Timestamp,
+		}
+		select {
+		case fd.drainerQueue <- drainer:
+		default:
+			log.Printf("Drainer queue full, not persisting suspected drainer %s", drainer.Address)
+		}
+	}
+
+	return result, nil
+}
+
+// forgetApprovalForAll removes the victim recorded for a rolled back
+// setApprovalForAll call. Escalations already made are kept.
+func (fd *FraudDetector) forgetApprovalForAll(tx *models.Transaction) {
+	if tx.InputData == nil {
+		return
+	}
+	call, err := decoder.Decode(*tx.InputData)
+	if err != nil || call.Method != decoder.MethodSetApprovalForAll || !call.Approved {
+		return
+	}
+
+	fd.drainerMu.Lock()
+	defer fd.drainerMu.Unlock()
+	victims := fd.operatorVictims[call.Operator]
+	victim := tx.FromAddress
+	if seen, ok := victims[victim]; ok && seen.Equal(tx.Timestamp) {
+		delete(victims, victim)
+	}
+}
+
+// countVictims counts the victims approved within the window, including victim
+func countVictims(victims map[address.Address]time.Time, victim address.Address, now time.Time, window time.Duration) int {
+	count := 1
+	for a, seen := range victims {
+		if a != victim && now.Sub(seen) <= window {
+			count++
+		}
+	}
+	return count
+}
+
+// pruneOperatorVictims drops operators with no approvals inside the window.
+// It runs at most once per window; the caller must hold drainerMu.
+func (fd *FraudDetector) pruneOperatorVictims(now time.Time, window time.Duration) {
+	for operator, victims := range fd.operatorVictims {
+		stale := true
+		for _, seen := range victims {
+			if now.Sub(seen) <= window {
+				stale = false
+				break
+			}
+		}
+		if stale {
+			delete(fd.operatorVictims, operator)
+		}
+	}
+}
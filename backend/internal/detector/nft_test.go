@@ -0,0 +1,207 @@
+package detector
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/minsix/backend/internal/address"
+	"github.com/minsix/backend/internal/models"
+)
+
+func approvalForAllCalldata(operator string, approved bool) *string {
+	flag := "0"
+	if approved {
+		flag = "1"
+	}
+	data := "0xa22cb465" + strings.Repeat("0", 24) + strings.TrimPrefix(strings.ToLower(operator), "0x") + strings.Repeat("0", 63) + flag
+	return &data
+}
+
+func TestNFTApprovalForAllEscalation(t *testing.T) {
+	const operator = "0x5555555555555555555555555555555555555555"
+	collection := address.FromHex("0xBC4CA0EdA7647A8aB7C2061c2E118A18a936f13D")
+
+	fd := NewFraudDetector(nil)
+	escalated := make(chan *models.SuspectedDrainer, 2)
+	fd.OnDrainerSuspected(func(d *models.SuspectedDrainer) {
+		escalated <- d
+	})
+
+	now := time.Now()
+	approve := func(victim int, at time.Time) *Result {
+		tx := &models.Transaction{
+			FromAddress: address.FromHex(fmt.Sprintf("0x%040d", victim)),
+			ToAddress:   &collection,
+			InputData:   approvalForAllCalldata(operator, true),
+			Timestamp:   at,
+		}
+		result, err := fd.evalNFTApprovalForAll(tx)
+		if err != nil {
+			t.Fatalf("evalNFTApprovalForAll() error = %v", err)
+		}
+		return result
+	}
+
+	// An approval outside the window does not count toward the threshold
+	approve(1, now.Add(-2*time.Hour))
+
+	if result := approve(2, now); result == nil || result.Score != 15 {
+		t.Fatalf("first victim result = %+v, want score 15", result)
+	}
+	// The same victim approving again is not a new victim
+	approve(2, now.Add(time.Second))
+	if fd.IsSuspectedDrainer(operator) {
+		t.Fatal("operator escalated before reaching the victim threshold")
+	}
+
+	approve(3, now.Add(2*time.Second))
+	result := approve(4, now.Add(3*time.Second))
+	if result == nil || result.Score != 60 || result.Evidence["escalated"] != true {
+		t.Fatalf("threshold result = %+v, want escalation", result)
+	}
+	if !fd.IsSuspectedDrainer(operator) {
+		t.Fatal("operator not escalated at the victim threshold")
+	}
+	select {
+	case d := <-escalated:
+		if d.VictimCount != 3 {
+			t.Fatalf("escalation callback = %+v, want 3 victims", d)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("escalation callback not called")
+	}
+
+	// Later victims are flagged as approving a suspected drainer without re-escalating
+	if result := approve(5, now.Add(4*time.Second)); result == nil || result.Severity != models.SeverityCritical {
+		t.Errorf("post-escalation result = %+v, want critical", result)
+	}
+	select {
+	case d := <-escalated:
+		t.Errorf("operator escalated again: %+v", d)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestNFTApprovalForAllPending(t *testing.T) {
+	const operator = "0x5555555555555555555555555555555555555555"
+	collection := address.FromHex("0xBC4CA0EdA7647A8aB7C2061c2E118A18a936f13D")
+
+	fd := NewFraudDetector(nil)
+	now := time.Now()
+	for victim := 1; victim <= 2; victim++ {
+		if _, err := fd.evalNFTApprovalForAll(&models.Transaction{
+			FromAddress: address.FromHex(fmt.Sprintf("0x%040d", victim)),
+			ToAddress:   &collection,
+			InputData:   approvalForAllCalldata(operator, true),
+			Timestamp:   now,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A pending approval reports the victim count it would reach but neither
+	// records the victim nor escalates the operator
+	pending := &models.Transaction{
+		FromAddress: address.FromHex(fmt.Sprintf("0x%040d", 3)),
+		ToAddress:   &collection,
+		InputData:   approvalForAllCalldata(operator, true),
+		Finality:    models.FinalityPending,
+		Timestamp:   now,
+	}
+	result, err := fd.evalNFTApprovalForAll(pending)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result == nil || result.Evidence["victim_count"] != 3 || result.Evidence["escalated"] != false {
+		t.Fatalf("pending result = %+v, want 3 victims without escalation", result)
+	}
+	if fd.IsSuspectedDrainer(operator) || len(fd.operatorVictims[operator]) != 2 {
+		t.Error("pending approval changed the drainer state")
+	}
+}
+
+func TestNFTApprovalForAllIgnored(t *testing.T) {
+	fd := NewFraudDetector(nil)
+	collection := address.FromHex("0xBC4CA0EdA7647A8aB7C2061c2E118A18a936f13D")
+
+	tests := []struct {
+		name  string
+		input *string
+	}{
+		{"Revocation", approvalForAllCalldata("0x5555555555555555555555555555555555555555", false)},
+		{"Known marketplace", approvalForAllCalldata("0x1E0049783F008A0085193E00003D00cd54003c71", true)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := &models.Transaction{FromAddress: "0x01", ToAddress: &collection, InputData: tt.input, Timestamp: time.Now()}
+			if result, _ := fd.evalNFTApprovalForAll(tx); result != nil {
+				t.Errorf("evalNFTApprovalForAll() = %+v, want nil", result)
+			}
+		})
+	}
+}
+
+func TestNFTApprovalForAllPrunesStaleOperators(t *testing.T) {
+	fd := NewFraudDetector(nil)
+	collection := address.FromHex("0xBC4CA0EdA7647A8aB7C2061c2E118A18a936f13D")
+	now := time.Now()
+
+	approve := func(operator string, at time.Time) {
+		tx := &models.Transaction{FromAddress: "0x01", ToAddress: &collection, InputData: approvalForAllCalldata(operator, true), Timestamp: at}
+		if _, err := fd.evalNFTApprovalForAll(tx); err != nil {
+			t.Fatalf("evalNFTApprovalForAll() error = %v", err)
+		}
+	}
+
+	approve("0x5555555555555555555555555555555555555555", now)
+	approve("0x6666666666666666666666666666666666666666", now.Add(time.Minute))
+	if len(fd.operatorVictims) != 2 {
+		t.Fatalf("tracked operators = %d, want 2", len(fd.operatorVictims))
+	}
+
+	// Once a window has passed, operators without recent approvals are dropped
+	approve("0x7777777777777777777777777777777777777777", now.Add(2*time.Hour))
+	if len(fd.operatorVictims) != 1 {
+		t.Errorf("tracked operators = %d, want 1", len(fd.operatorVictims))
+	}
+}
+
+func TestNFTApprovalForAllForgetTransactions(t *testing.T) {
+	const operator = "0x5555555555555555555555555555555555555555"
+	collection := address.FromHex("0xBC4CA0EdA7647A8aB7C2061c2E118A18a936f13D")
+
+	fd := NewFraudDetector(nil)
+	now := time.Now()
+
+	var orphaned []*models.Transaction
+	for victim := 1; victim < DrainerVictimThreshold; victim++ {
+		tx := &models.Transaction{
+			FromAddress: address.FromHex(fmt.Sprintf("0x%040d", victim)),
+			ToAddress:   &collection,
+			InputData:   approvalForAllCalldata(operator, true),
+			Timestamp:   now,
+		}
+		if _, err := fd.evalNFTApprovalForAll(tx); err != nil {
+			t.Fatalf("evalNFTApprovalForAll() error = %v", err)
+		}
+		orphaned = append(orphaned, tx)
+	}
+
+	// Victims of orphaned approvals no longer count toward escalation
+	fd.ForgetTransactions(orphaned)
+	tx := &models.Transaction{
+		FromAddress: address.FromHex(fmt.Sprintf("0x%040d", DrainerVictimThreshold)),
+		ToAddress:   &collection,
+		InputData:   approvalForAllCalldata(operator, true),
+		Timestamp:   now.Add(time.Second),
+	}
+	if _, err := fd.evalNFTApprovalForAll(tx); err != nil {
+		t.Fatalf("evalNFTApprovalForAll() error = %v", err)
+	}
+	if fd.IsSuspectedDrainer(operator) {
+		t.Error("operator escalated by orphaned approvals")
+	}
+}
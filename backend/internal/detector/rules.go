@@ -0,0 +1,264 @@
+package detector
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/minsix/backend/internal/models"
+)
+
+// Rule is a single fraud heuristic evaluated against every transaction
+type Rule interface {
+	// Name uniquely identifies the rule within a registry
+	Name() string
+	// Version should change whenever the rule's logic changes
+	Version() string
+	// Evaluate returns nil if the rule does not match the transaction
+	Evaluate(tx *models.Transaction) (*Result, error)
+}
+
+// Tunable is implemented by rules that expose numeric thresholds for configuration
+type Tunable interface {
+	// DefaultThresholds returns every supported threshold with its default value
+	DefaultThresholds() map[string]float64
+	// Thresholds returns the current threshold values
+	Thresholds() map[string]float64
+	// SetThresholds replaces the current values; keys are already validated
+	SetThresholds(values map[string]float64)
+}
+
+// WholeThresholds is implemented by tunable rules with count thresholds,
+// which only take whole numbers
+type WholeThresholds interface {
+	// IsWhole reports whether a threshold only takes whole numbers
+	IsWhole(name string) bool
+}
+
+// BoundedThresholds is implemented by tunable rules with thresholds that have
+// an upper bound
+type BoundedThresholds interface {
+	// MaxThreshold returns the largest value a threshold takes, if it is bounded
+	MaxThreshold(name string) (float64, bool)
+}
+
+// Result describes why a rule matched and how much it contributes to the risk score.
+// Severity is optional and derived from the weighted score when empty.
+type Result struct {
+	Score    int
+	Severity string
+	Reason   string
+	Evidence map[string]interface{}
+}
+
+// RuleInfo describes a registered rule and its current settings
+type RuleInfo struct {
+	Name       string             `json:"name"`
+	Version    string             `json:"version"`
+	Enabled    bool               `json:"enabled"`
+	Weight     float64            `json:"weight"`
+	Thresholds map[string]float64 `json:"thresholds,omitempty"`
+}
+
+type registeredRule struct {
+	rule    Rule
+	enabled bool
+	weight  float64
+}
+
+// Registry holds the ordered set of rules run by the fraud detector
+type Registry struct {
+	mu    sync.RWMutex
+	rules []*registeredRule
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds an enabled rule with a weight of 1
+func (r *Registry) Register(rule Rule) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.find(rule.Name()) != nil {
+		return fmt.Errorf("rule %q is already registered", rule.Name())
+	}
+
+	r.rules = append(r.rules, &registeredRule{rule: rule, enabled: true, weight: 1})
+	return nil
+}
+
+// Unregister removes a rule from the registry
+func (r *Registry) Unregister(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, rr := range r.rules {
+		if rr.rule.Name() == name {
+			r.rules = append(r.rules[:i], r.rules[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("rule %q is not registered", name)
+}
+
+// Enable turns a registered rule on
+func (r *Registry) Enable(name string) error {
+	return r.update(name, func(rr *registeredRule) { rr.enabled = true })
+}
+
+// Disable turns a registered rule off without removing it
+func (r *Registry) Disable(name string) error {
+	return r.update(name, func(rr *registeredRule) { rr.enabled = false })
+}
+
+// SetWeight scales the score contributed by a rule
+func (r *Registry) SetWeight(name string, weight float64) error {
+	if weight < 0 {
+		return fmt.Errorf("weight for rule %q must not be negative", name)
+	}
+	return r.update(name, func(rr *registeredRule) { rr.weight = weight })
+}
+
+// Rules lists the registered rules in evaluation order
+func (r *Registry) Rules() []RuleInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	infos := make([]RuleInfo, 0, len(r.rules))
+	for _, rr := range r.rules {
+		info := RuleInfo{
+			Name:    rr.rule.Name(),
+			Version: rr.rule.Version(),
+			Enabled: rr.enabled,
+			Weight:  rr.weight,
+		}
+		if tunable, ok := rr.rule.(Tunable); ok {
+			info.Thresholds = tunable.Thresholds()
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// Configure validates the settings and then applies them to every registered
+// rule at once. Rules without settings are reset to their defaults, so the
+// result does not depend on previously applied configurations.
+func (r *Registry) Configure(settings map[string]RuleConfig) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for name, cfg := range settings {
+		rr := r.find(name)
+		if rr == nil {
+			return fmt.Errorf("unknown rule %q", name)
+		}
+		if err := cfg.validate(name, rr.rule); err != nil {
+			return err
+		}
+	}
+
+	for _, rr := range r.rules {
+		cfg := settings[rr.rule.Name()]
+
+		rr.enabled = cfg.Enabled == nil || *cfg.Enabled
+		rr.weight = 1
+		if cfg.Weight != nil {
+			rr.weight = *cfg.Weight
+		}
+
+		if tunable, ok := rr.rule.(Tunable); ok {
+			values := tunable.DefaultThresholds()
+			for key, value := range cfg.Thresholds {
+				values[key] = value
+			}
+			tunable.SetThresholds(values)
+		}
+	}
+	return nil
+}
+
+// Evaluate runs every enabled rule against the transaction. A failing rule
+// does not stop the others; its error is returned alongside the findings.
+// Rules run outside the registry lock, on the rules and weights enabled when
+// the evaluation started, so slow rules don't hold up reconfiguration.
+func (r *Registry) Evaluate(tx *models.Transaction) ([]models.FlagReason, []error) {
+	var findings []models.FlagReason
+	var errs []error
+	for _, rr := range r.enabled() {
+		result, err := rr.rule.Evaluate(tx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("rule %s: %w", rr.rule.Name(), err))
+			continue
+		}
+		if result == nil {
+			continue
+		}
+
+		score := int(math.Round(float64(result.Score) * rr.weight))
+		severity := result.Severity
+		if severity == "" {
+			severity = severityForScore(score)
+		}
+
+		findings = append(findings, models.FlagReason{
+			RuleID:      rr.rule.Name(),
+			RuleVersion: rr.rule.Version(),
+			Score:       score,
+			Severity:    severity,
+			Message:     result.Reason,
+			Evidence:    result.Evidence,
+		})
+	}
+	return findings, errs
+}
+
+// enabled copies the enabled rules with their weights
+func (r *Registry) enabled() []registeredRule {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	rules := make([]registeredRule, 0, len(r.rules))
+	for _, rr := range r.rules {
+		if rr.enabled {
+			rules = append(rules, *rr)
+		}
+	}
+	return rules
+}
+
+func (r *Registry) update(name string, fn func(*registeredRule)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rr := r.find(name)
+	if rr == nil {
+		return fmt.Errorf("rule %q is not registered", name)
+	}
+	fn(rr)
+	return nil
+}
+
+func (r *Registry) find(name string) *registeredRule {
+	for _, rr := range r.rules {
+		if rr.rule.Name() == name {
+			return rr
+		}
+	}
+	return nil
+}
+
+// severityForScore maps a score contribution to a severity level
+func severityForScore(score int) string {
+	switch {
+	case score >= 40:
+		return models.SeverityCritical
+	case score >= 25:
+		return models.SeverityHigh
+	case score >= 15:
+		return models.SeverityMedium
+	default:
+		return models.SeverityLow
+	}
+}
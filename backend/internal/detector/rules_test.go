@@ -0,0 +1,102 @@
+package detector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/minsix/backend/internal/models"
+)
+
+type staticRule struct {
+	name  string
+	score int
+}
+
+func (r *staticRule) Name() string    { return r.name }
+func (r *staticRule) Version() string { return "1" }
+
+func (r *staticRule) Evaluate(tx *models.Transaction) (*Result, error) {
+	return &Result{Score: r.score, Reason: r.name}, nil
+}
+
+func TestRegistryEvaluate(t *testing.T) {
+	registry := NewRegistry()
+	if err := registry.Register(&staticRule{name: "a", score: 10}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if err := registry.Register(&staticRule{name: "b", score: 20}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if err := registry.Register(&staticRule{name: "a", score: 5}); err == nil {
+		t.Error("Register() with duplicate name should fail")
+	}
+
+	findings, errs := registry.Evaluate(&models.Transaction{})
+	if len(errs) != 0 || len(findings) != 2 {
+		t.Fatalf("Evaluate() = %d findings, %d errors, want 2, 0", len(findings), len(errs))
+	}
+
+	if err := registry.Disable("a"); err != nil {
+		t.Fatalf("Disable() error = %v", err)
+	}
+	if err := registry.SetWeight("b", 1.5); err != nil {
+		t.Fatalf("SetWeight() error = %v", err)
+	}
+
+	findings, _ = registry.Evaluate(&models.Transaction{})
+	if len(findings) != 1 || findings[0].RuleID != "b" || findings[0].Score != 30 {
+		t.Errorf("Evaluate() = %+v, want single weighted finding for b", findings)
+	}
+
+	if err := registry.SetWeight("missing", 1); err == nil {
+		t.Error("SetWeight() on unknown rule should fail")
+	}
+}
+
+// blockingRule holds its evaluation until release is closed
+type blockingRule struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (r *blockingRule) Name() string    { return "slow" }
+func (r *blockingRule) Version() string { return "1" }
+
+func (r *blockingRule) Evaluate(tx *models.Transaction) (*Result, error) {
+	close(r.started)
+	<-r.release
+	return &Result{Score: 10, Reason: "slow"}, nil
+}
+
+func TestRegistryConfigureDuringEvaluate(t *testing.T) {
+	rule := &blockingRule{started: make(chan struct{}), release: make(chan struct{})}
+	registry := NewRegistry()
+	if err := registry.Register(rule); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	done := make(chan []models.FlagReason)
+	go func() {
+		findings, _ := registry.Evaluate(&models.Transaction{})
+		done <- findings
+	}()
+	<-rule.started
+
+	weight := 2.0
+	configured := make(chan error)
+	go func() { configured <- registry.Configure(map[string]RuleConfig{"slow": {Weight: &weight}}) }()
+	select {
+	case err := <-configured:
+		if err != nil {
+			t.Fatalf("Configure() error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Configure() blocked behind an evaluation in flight")
+	}
+
+	close(rule.release)
+	// The evaluation in flight keeps the weight it started with
+	if findings := <-done; len(findings) != 1 || findings[0].Score != 10 {
+		t.Errorf("Evaluate() = %+v, want the finding at its original weight", findings)
+	}
+}
@@ -2,86 +2,161 @@ package ethereum
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"math/big"
+	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/minsix/backend/internal/address"
+	"github.com/minsix/backend/internal/decoder"
+	"github.com/minsix/backend/internal/metrics"
 	"github.com/minsix/backend/internal/models"
 )
 
 type Client struct {
-	client    *ethclient.Client
-	apiURL    string
-	networkID *big.Int
+	mu         sync.RWMutex
+	providers  []*provider
+	active     *provider // the connection RPC calls use, replaced on failover
+	subscribed bool      // the active provider must support subscriptions
+	chainID    *big.Int
+
+	statusMu   sync.Mutex
+	status     SubscriptionStatus
+	resumeFrom uint64
+	onBlock    func(block *models.Block)
+	onReorg    func(ancestor uint64, orphaned []*models.Block)
+	chain      *chainWindow
+	stages     *metrics.Stages
 }
 
+// NewClient connects to an Alchemy network such as eth-mainnet
 func NewClient(alchemyAPIKey, network string) (*Client, error) {
-	apiURL := fmt.Sprintf("wss://eth-mainnet.g.alchemy.com/v2/%s", alchemyAPIKey)
-	if network != "eth-mainnet" {
-		apiURL = fmt.Sprintf("wss://%s.g.alchemy.com/v2/%s", network, alchemyAPIKey)
-	}
+	return Dial([]string{AlchemyURL(alchemyAPIKey, network)})
+}
 
-	client, err := ethclient.Dial(apiURL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to Ethereum client: %w", err)
+// Dial connects to the first reachable of one or more RPC endpoints (ws, wss,
+// http, https or an IPC socket path). The others stay on standby for
+// failover and must serve the same chain.
+func Dial(urls []string) (*Client, error) {
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("no RPC providers configured")
 	}
 
-	networkID, err := client.NetworkID(context.Background())
-	if err != nil {
-		return nil, fmt.Errorf("failed to get network ID: %w", err)
+	c := &Client{chain: newChainWindow(MaxReorgDepth)}
+	for _, raw := range urls {
+		p, err := newProvider(raw)
+		if err != nil {
+			return nil, err
+		}
+		c.providers = append(c.providers, p)
 	}
 
-	log.Printf("Connected to Ethereum network (ID: %s)", networkID.String())
-
-	return &Client{
-		client:    client,
-		apiURL:    apiURL,
-		networkID: networkID,
-	}, nil
+	var lastErr error
+	for _, p := range c.providers {
+		if err := c.activate(context.Background(), p); err != nil {
+			log.Printf("Failed to connect to RPC provider: %v", err)
+			lastErr = err
+			continue
+		}
+		log.Printf("Connected to chain %s via %s", c.chainID.String(), p.redacted())
+		return c, nil
+	}
+	return nil, fmt.Errorf("failed to connect to Ethereum client: %w", lastErr)
 }
 
-// SubscribeToBlocks subscribes to new blocks and processes transactions
-func (c *Client) SubscribeToBlocks(ctx context.Context, txHandler func(*models.Transaction)) error {
-	headers := make(chan *types.Header)
-	sub, err := c.client.SubscribeNewHead(ctx, headers)
+// MonitoredTxMemory is how many transactions an address monitor remembers
+// having handed over, so each is handed over once
+const MonitoredTxMemory = 1024
+
+// MonitorAddress monitors transactions for a specific address: those emitting
+// logs from it, such as a contract wallet's, and those whose token events name
+// it as sender, owner or recipient. Each transaction is handed over once.
+// Only logs are subscribed to; MonitorTransfers covers native transfers,
+// which emit none. Logs removed by a reorg are skipped, so what txHandler
+// receives must not be stored: only block ingestion rolls orphaned blocks
+// back. Block ingestion also covers logs missed while a dropped subscription
+// is renewed with backoff on the active provider.
+// onDone, if set, is called once monitoring has stopped, which only happens
+// when ctx is cancelled.
+func (c *Client) MonitorAddress(ctx context.Context, addr address.Address, txHandler func(*models.Transaction), onDone func()) error {
+	watched := addr.Common()
+	topic := common.BytesToHash(watched.Bytes())
+	queries := []ethereum.FilterQuery{
+		{Addresses: []common.Address{watched}},
+		{Topics: [][]common.Hash{nil, {topic}}},      // Transfer and Approval from the address
+		{Topics: [][]common.Hash{nil, nil, {topic}}}, // Transfer to and Approval for the address
+	}
+
+	logs := make(chan types.Log)
+	sub, err := c.subscribeAddress(ctx, queries, logs)
 	if err != nil {
-		return fmt.Errorf("failed to subscribe to new blocks: %w", err)
+		return err
 	}
 
-	log.Println("Subscribed to new blocks")
+	log.Printf("Monitoring address: %s", addr)
 
 	go func() {
+		defer func() {
+			if sub != nil {
+				sub.Unsubscribe()
+			}
+			if onDone != nil {
+				onDone()
+			}
+		}()
+
+		// A transaction emits several logs, which the subscriptions deliver
+		// interleaved, and again after a resubscription
+		seen := newRecentHashes(MonitoredTxMemory)
 		for {
 			select {
-			case err := <-sub.Err():
-				log.Printf("Subscription error: %v", err)
-				return
-			case header := <-headers:
-				log.Printf("New block: %d", header.Number.Uint64())
-				
-				// Process block transactions
-				block, err := c.client.BlockByNumber(ctx, header.Number)
+			case err := <-sub.errs:
+				log.Printf("Address monitoring error for %s: %v", addr, err)
+				sub.Unsubscribe()
+				sub = c.resubscribeAddress(ctx, addr, queries, logs)
+				if sub == nil {
+					log.Printf("Address monitoring stopped: %s", addr)
+					return
+				}
+			case vLog := <-logs:
+				if vLog.Removed || !seen.add(vLog.TxHash) {
+					continue
+				}
+
+				tx, _, err := c.eth().TransactionByHash(ctx, vLog.TxHash)
+				if err != nil {
+					log.Printf("Failed to get transaction: %v", err)
+					continue
+				}
+
+				block, err := c.eth().BlockByHash(ctx, vLog.BlockHash)
 				if err != nil {
 					log.Printf("Failed to get block: %v", err)
 					continue
 				}
 
-				// Process each transaction in the block
-				for _, tx := range block.Transactions() {
-					modelTx, err := c.convertTransaction(tx, block)
-					if err != nil {
-						log.Printf("Failed to convert transaction: %v", err)
-						continue
-					}
-					txHandler(modelTx)
+				receipt, err := c.eth().TransactionReceipt(ctx, vLog.TxHash)
+				if err != nil {
+					log.Printf("Failed to get receipt: %v", err)
+				}
+
+				modelTx, err := c.convertTransaction(tx, block, receipt)
+				if err != nil {
+					log.Printf("Failed to convert transaction: %v", err)
+					continue
 				}
+				txHandler(modelTx)
 			case <-ctx.Done():
-				log.Println("Block subscription stopped")
+				log.Printf("Address monitoring stopped: %s", addr)
 				return
 			}
 		}
@@ -90,47 +165,108 @@ func (c *Client) SubscribeToBlocks(ctx context.Context, txHandler func(*models.T
 	return nil
 }
 
-// MonitorAddress monitors transactions for a specific address
-func (c *Client) MonitorAddress(ctx context.Context, address string, txHandler func(*models.Transaction)) error {
-	query := ethereum.FilterQuery{
-		Addresses: []common.Address{common.HexToAddress(address)},
+// addressSubscription is the set of log subscriptions following one address;
+// errs receives the first error of any of them
+type addressSubscription struct {
+	subs []ethereum.Subscription
+	errs chan error
+}
+
+// Unsubscribe ends every log subscription of the address
+func (s *addressSubscription) Unsubscribe() {
+	for _, sub := range s.subs {
+		sub.Unsubscribe()
 	}
+}
 
-	logs := make(chan types.Log)
-	sub, err := c.client.SubscribeFilterLogs(ctx, query, logs)
+// subscribeAddress subscribes to every query on the active provider, sending
+// their logs to logs
+func (c *Client) subscribeAddress(ctx context.Context, queries []ethereum.FilterQuery, logs chan types.Log) (*addressSubscription, error) {
+	s := &addressSubscription{errs: make(chan error, len(queries))}
+	for _, query := range queries {
+		sub, err := c.eth().SubscribeFilterLogs(ctx, query, logs)
+		if err != nil {
+			s.Unsubscribe()
+			return nil, fmt.Errorf("failed to subscribe to address logs: %w", err)
+		}
+		s.subs = append(s.subs, sub)
+	}
+	for _, sub := range s.subs {
+		go func(sub ethereum.Subscription) {
+			if err := <-sub.Err(); err != nil {
+				s.errs <- err
+			}
+		}(sub)
+	}
+	return s, nil
+}
+
+// resubscribeAddress retries the address subscriptions with backoff on
+// whichever provider is active, which the block subscription and health
+// monitor keep current. It returns nil once ctx is cancelled.
+func (c *Client) resubscribeAddress(ctx context.Context, addr address.Address, queries []ethereum.FilterQuery, logs chan types.Log) *addressSubscription {
+	for attempt := 0; ; attempt++ {
+		delay := backoffDelay(attempt)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil
+		}
+
+		sub, err := c.subscribeAddress(ctx, queries, logs)
+		if err != nil {
+			log.Printf("Failed to resubscribe to address %s: %v", addr, err)
+			continue
+		}
+		log.Printf("Resubscribed to address: %s", addr)
+		return sub
+	}
+}
+
+// MonitorTransfers follows new heads and hands over the transactions sent by
+// or to an address watched reports that MonitorAddress can't see: native
+// transfers and calls whose logs don't name the address. Transactions whose
+// logs the address subscriptions match are left to them. Each head's block is
+// fetched once, however many addresses are watched. A dropped subscription is
+// renewed with backoff on the active provider, leaving the heads missed
+// meanwhile to block ingestion. onDone, if set, is called once monitoring has
+// stopped, which only happens when ctx is cancelled.
+func (c *Client) MonitorTransfers(ctx context.Context, watched func(address.Address) bool, txHandler func(*models.Transaction), onDone func()) error {
+	headers := make(chan *types.Header)
+	sub, err := c.eth().SubscribeNewHead(ctx, headers)
 	if err != nil {
-		return fmt.Errorf("failed to subscribe to address logs: %w", err)
+		return fmt.Errorf("failed to subscribe to new heads: %w", err)
 	}
 
-	log.Printf("Monitoring address: %s", address)
+	log.Println("Monitoring transfers of watched addresses")
 
 	go func() {
+		defer func() {
+			if sub != nil {
+				sub.Unsubscribe()
+			}
+			if onDone != nil {
+				onDone()
+			}
+		}()
+
+		// The heads replacing reorged blocks may repeat transactions already
+		// handed over
+		seen := newRecentHashes(MonitoredTxMemory)
 		for {
 			select {
 			case err := <-sub.Err():
-				log.Printf("Address monitoring error: %v", err)
-				return
-			case vLog := <-logs:
-				tx, _, err := c.client.TransactionByHash(ctx, vLog.TxHash)
-				if err != nil {
-					log.Printf("Failed to get transaction: %v", err)
-					continue
-				}
-
-				block, err := c.client.BlockByHash(ctx, vLog.BlockHash)
-				if err != nil {
-					log.Printf("Failed to get block: %v", err)
-					continue
-				}
-
-				modelTx, err := c.convertTransaction(tx, block)
-				if err != nil {
-					log.Printf("Failed to convert transaction: %v", err)
-					continue
+				log.Printf("Transfer monitoring error: %v", err)
+				sub.Unsubscribe()
+				sub = c.resubscribeHeads(ctx, headers)
+				if sub == nil {
+					log.Println("Transfer monitoring stopped")
+					return
 				}
-				txHandler(modelTx)
+			case header := <-headers:
+				c.handleTransfers(ctx, header.Hash(), watched, seen, txHandler)
 			case <-ctx.Done():
-				log.Println("Address monitoring stopped")
+				log.Println("Transfer monitoring stopped")
 				return
 			}
 		}
@@ -139,10 +275,119 @@ func (c *Client) MonitorAddress(ctx context.Context, address string, txHandler f
 	return nil
 }
 
+// handleTransfers fetches a block and hands over its transactions sent by or
+// to a watched address, unless their logs name one
+func (c *Client) handleTransfers(ctx context.Context, hash common.Hash, watched func(address.Address) bool, seen *recentHashes, txHandler func(*models.Transaction)) {
+	block, err := c.eth().BlockByHash(ctx, hash)
+	if err != nil {
+		log.Printf("Failed to get block: %v", err)
+		return
+	}
+
+	signer := types.LatestSignerForChainID(c.chainID)
+	for _, tx := range block.Transactions() {
+		if tx.To() == nil || !watched(address.FromCommon(*tx.To())) {
+			from, err := types.Sender(signer, tx)
+			if err != nil || !watched(address.FromCommon(from)) {
+				continue
+			}
+		}
+		if !seen.add(tx.Hash()) {
+			continue
+		}
+
+		receipt, err := c.eth().TransactionReceipt(ctx, tx.Hash())
+		if err != nil {
+			log.Printf("Failed to get receipt: %v", err)
+		}
+		if receipt != nil && logsMention(receipt.Logs, watched) {
+			continue
+		}
+
+		modelTx, err := c.convertTransaction(tx, block, receipt)
+		if err != nil {
+			log.Printf("Failed to convert transaction: %v", err)
+			continue
+		}
+		txHandler(modelTx)
+	}
+}
+
+// logsMention reports whether the log queries of MonitorAddress match one of
+// the logs for a watched address: emitted by it or naming it as the first or
+// second indexed topic
+func logsMention(logs []*types.Log, watched func(address.Address) bool) bool {
+	for _, vLog := range logs {
+		if watched(address.FromCommon(vLog.Address)) {
+			return true
+		}
+		for i := 1; i < len(vLog.Topics) && i <= 2; i++ {
+			named := common.BytesToAddress(vLog.Topics[i].Bytes())
+			if common.BytesToHash(named.Bytes()) == vLog.Topics[i] && watched(address.FromCommon(named)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// resubscribeHeads retries the head subscription of MonitorTransfers with
+// backoff on whichever provider is active. It returns nil once ctx is
+// cancelled.
+func (c *Client) resubscribeHeads(ctx context.Context, headers chan *types.Header) ethereum.Subscription {
+	for attempt := 0; ; attempt++ {
+		delay := backoffDelay(attempt)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil
+		}
+
+		sub, err := c.eth().SubscribeNewHead(ctx, headers)
+		if err != nil {
+			log.Printf("Failed to resubscribe to new heads: %v", err)
+			continue
+		}
+		log.Println("Resubscribed to new heads for transfer monitoring")
+		return sub
+	}
+}
+
+// recentHashes is a set of the transaction hashes last added, bounded to a
+// fixed size by forgetting the oldest
+type recentHashes struct {
+	seen  map[common.Hash]bool
+	order []common.Hash // ring of the remembered hashes
+	next  int           // oldest entry of order once it is full
+}
+
+func newRecentHashes(size int) *recentHashes {
+	return &recentHashes{
+		seen:  make(map[common.Hash]bool, size),
+		order: make([]common.Hash, 0, size),
+	}
+}
+
+// add remembers a hash; it returns false if the hash is already remembered
+func (r *recentHashes) add(hash common.Hash) bool {
+	if r.seen[hash] {
+		return false
+	}
+	if len(r.order) < cap(r.order) {
+		r.order = append(r.order, hash)
+	} else {
+		delete(r.seen, r.order[r.next])
+		r.order[r.next] = hash
+		r.next = (r.next + 1) % len(r.order)
+	}
+	r.seen[hash] = true
+	return true
+}
+
 // GetTransaction retrieves a transaction by hash
 func (c *Client) GetTransaction(ctx context.Context, txHash string) (*models.Transaction, error) {
 	hash := common.HexToHash(txHash)
-	tx, pending, err := c.client.TransactionByHash(ctx, hash)
+	tx, pending, err := c.eth().TransactionByHash(ctx, hash)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get transaction: %w", err)
 	}
@@ -151,51 +396,190 @@ func (c *Client) GetTransaction(ctx context.Context, txHash string) (*models.Tra
 		return nil, fmt.Errorf("transaction is pending")
 	}
 
-	receipt, err := c.client.TransactionReceipt(ctx, hash)
+	receipt, err := c.eth().TransactionReceipt(ctx, hash)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get receipt: %w", err)
 	}
 
-	block, err := c.client.BlockByNumber(ctx, receipt.BlockNumber)
+	block, err := c.eth().BlockByNumber(ctx, receipt.BlockNumber)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get block: %w", err)
 	}
 
-	return c.convertTransaction(tx, block)
+	return c.convertTransaction(tx, block, receipt)
+}
+
+// ChainID returns the chain ID reported by the node at connection time
+func (c *Client) ChainID() *big.Int {
+	return new(big.Int).Set(c.chainID)
 }
 
 // GetLatestBlock gets the latest block number
 func (c *Client) GetLatestBlock(ctx context.Context) (uint64, error) {
-	header, err := c.client.HeaderByNumber(ctx, nil)
+	header, err := c.eth().HeaderByNumber(ctx, nil)
 	if err != nil {
 		return 0, err
 	}
 	return header.Number.Uint64(), nil
 }
 
-// convertTransaction converts eth transaction to internal model
-func (c *Client) convertTransaction(tx *types.Transaction, block *types.Block) (*models.Transaction, error) {
-	from, err := types.Sender(types.LatestSignerForChainID(c.networkID), tx)
+// FinalizedBlock gets the number of the latest block tagged finalized
+func (c *Client) FinalizedBlock(ctx context.Context) (uint64, error) {
+	header, err := c.eth().HeaderByNumber(ctx, big.NewInt(int64(rpc.FinalizedBlockNumber)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to get finalized block: %w", err)
+	}
+	return header.Number.Uint64(), nil
+}
+
+// InspectContract reports whether address has code and whether that code was
+// deployed within the last maxAgeBlocks blocks. The age check reads historical
+// state, so it needs an archive-capable endpoint.
+func (c *Client) InspectContract(ctx context.Context, contract address.Address, maxAgeBlocks uint64) (bool, bool, error) {
+	addr := contract.Common()
+
+	code, err := c.eth().CodeAt(ctx, addr, nil)
+	if err != nil {
+		return false, false, fmt.Errorf("failed to get code: %w", err)
+	}
+	if len(code) == 0 {
+		return false, false, nil
+	}
+
+	head, err := c.GetLatestBlock(ctx)
+	if err != nil {
+		return true, false, fmt.Errorf("failed to get latest block: %w", err)
+	}
+	if head <= maxAgeBlocks {
+		return true, false, nil
+	}
+
+	oldCode, err := c.eth().CodeAt(ctx, addr, new(big.Int).SetUint64(head-maxAgeBlocks))
+	if err != nil {
+		return true, false, fmt.Errorf("failed to get historical code: %w", err)
+	}
+	return true, len(oldCode) == 0, nil
+}
+
+// blockByNumber gets a block with its transactions. Blocks of L2 chains can
+// hold system transactions whose types go-ethereum cannot decode, such as
+// deposits; those are skipped so the rest of the block is still ingested.
+func (c *Client) blockByNumber(ctx context.Context, number uint64) (*types.Block, error) {
+	block, err := c.eth().BlockByNumber(ctx, new(big.Int).SetUint64(number))
+	if !errors.Is(err, types.ErrTxTypeNotSupported) {
+		return block, err
+	}
+
+	var raw json.RawMessage
+	if err := c.eth().Client().CallContext(ctx, &raw, "eth_getBlockByNumber", hexutil.EncodeUint64(number), true); err != nil {
+		return nil, err
+	}
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, ethereum.NotFound
+	}
+
+	var header types.Header
+	if err := json.Unmarshal(raw, &header); err != nil {
+		return nil, fmt.Errorf("failed to decode block header: %w", err)
+	}
+	var body struct {
+		Transactions []json.RawMessage `json:"transactions"`
+	}
+	if err := json.Unmarshal(raw, &body); err != nil {
+		return nil, fmt.Errorf("failed to decode block transactions: %w", err)
+	}
+
+	txs := make([]*types.Transaction, 0, len(body.Transactions))
+	skipped := 0
+	for _, rawTx := range body.Transactions {
+		tx := new(types.Transaction)
+		if err := tx.UnmarshalJSON(rawTx); err != nil {
+			if errors.Is(err, types.ErrTxTypeNotSupported) {
+				skipped++
+				continue
+			}
+			return nil, fmt.Errorf("failed to decode transaction: %w", err)
+		}
+		txs = append(txs, tx)
+	}
+	if skipped > 0 {
+		log.Printf("Skipped %d transactions of unsupported types in block %d", skipped, number)
+	}
+	return types.NewBlockWithHeader(&header).WithBody(txs, nil), nil
+}
+
+// fetchReceipts gets all receipts of a block in one call, falling back to a
+// batch of per-transaction requests for providers without eth_getBlockReceipts
+func (c *Client) fetchReceipts(ctx context.Context, block *types.Block) (map[common.Hash]*types.Receipt, error) {
+	receipts := make(map[common.Hash]*types.Receipt, len(block.Transactions()))
+	if len(block.Transactions()) == 0 {
+		return receipts, nil
+	}
+
+	blockReceipts, err := c.eth().BlockReceipts(ctx, rpc.BlockNumberOrHashWithHash(block.Hash(), false))
+	if err == nil {
+		for _, receipt := range blockReceipts {
+			receipts[receipt.TxHash] = receipt
+		}
+		return receipts, nil
+	}
+
+	batch := make([]rpc.BatchElem, 0, len(block.Transactions()))
+	for _, tx := range block.Transactions() {
+		batch = append(batch, rpc.BatchElem{
+			Method: "eth_getTransactionReceipt",
+			Args:   []interface{}{tx.Hash()},
+			Result: new(types.Receipt),
+		})
+	}
+	if err := c.eth().Client().BatchCallContext(ctx, batch); err != nil {
+		return receipts, fmt.Errorf("failed to batch receipt requests: %w", err)
+	}
+
+	for _, elem := range batch {
+		if elem.Error != nil {
+			log.Printf("Failed to get receipt: %v", elem.Error)
+			continue
+		}
+		// A null result (unknown transaction) leaves the receipt empty
+		receipt := elem.Result.(*types.Receipt)
+		if receipt.TxHash != (common.Hash{}) {
+			receipts[receipt.TxHash] = receipt
+		}
+	}
+	return receipts, nil
+}
+
+// convertTransaction converts eth transaction to internal model. Without a
+// receipt, gas used falls back to the gas limit and no logs are attached.
+func (c *Client) convertTransaction(tx *types.Transaction, block *types.Block, receipt *types.Receipt) (*models.Transaction, error) {
+	from, err := types.Sender(types.LatestSignerForChainID(c.chainID), tx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get sender: %w", err)
 	}
 
+	blockHash := block.Hash().Hex()
+	nonce := tx.Nonce()
 	modelTx := &models.Transaction{
+		ChainID:     c.chainID.Int64(),
 		TxHash:      tx.Hash().Hex(),
 		BlockNumber: block.Number().Int64(),
-		FromAddress: from.Hex(),
+		BlockHash:   &blockHash,
+		FromAddress: address.FromCommon(from),
+		Nonce:       &nonce,
 		Value:       tx.Value().String(),
 		GasPrice:    tx.GasPrice().String(),
 		Timestamp:   time.Unix(int64(block.Time()), 0),
 	}
 
 	if tx.To() != nil {
-		to := tx.To().Hex()
-		modelTx.ToAddress = &to
+		modelTx.ToAddress = address.FromCommon(*tx.To()).Ptr()
 	}
 
-	// Get gas used from receipt (in real impl)
 	modelTx.GasUsed = int64(tx.Gas())
+	if receipt != nil {
+		applyReceipt(modelTx, receipt)
+	}
 
 	if len(tx.Data()) > 0 {
 		data := fmt.Sprintf("0x%x", tx.Data())
@@ -205,7 +589,77 @@ func (c *Client) convertTransaction(tx *types.Transaction, block *types.Block) (
 	return modelTx, nil
 }
 
-// Close closes the Ethereum client connection
+// applyReceipt copies execution results and decoded logs from a receipt
+func applyReceipt(modelTx *models.Transaction, receipt *types.Receipt) {
+	modelTx.GasUsed = int64(receipt.GasUsed)
+
+	status := models.TxStatusSuccess
+	if receipt.Status == types.ReceiptStatusFailed {
+		status = models.TxStatusReverted
+	}
+	modelTx.Status = &status
+
+	if receipt.EffectiveGasPrice != nil {
+		price := receipt.EffectiveGasPrice.String()
+		modelTx.EffectiveGasPrice = &price
+	}
+
+	if receipt.ContractAddress != (common.Address{}) {
+		modelTx.ContractAddress = address.FromCommon(receipt.ContractAddress).Ptr()
+	}
+
+	for _, l := range receipt.Logs {
+		topics := make([]string, 0, len(l.Topics))
+		for _, topic := range l.Topics {
+			topics = append(topics, topic.Hex())
+		}
+
+		txLog := &models.TransactionLog{
+			ChainID:     modelTx.ChainID,
+			TxHash:      modelTx.TxHash,
+			LogIndex:    int(l.Index),
+			BlockNumber: modelTx.BlockNumber,
+			Address:     address.FromCommon(l.Address),
+			Topics:      topics,
+			Data:        fmt.Sprintf("0x%x", l.Data),
+		}
+		if event, err := decoder.DecodeLog(topics, txLog.Data); err == nil {
+			txLog.Event = &event.Name
+			txLog.Decoded = event.Fields()
+
+			if event.Name == decoder.EventTransfer && event.Standard == decoder.StandardERC20 {
+				modelTx.TokenTransfers = append(modelTx.TokenTransfers, &models.TokenTransfer{
+					ChainID:     modelTx.ChainID,
+					TxHash:      modelTx.TxHash,
+					LogIndex:    txLog.LogIndex,
+					BlockNumber: modelTx.BlockNumber,
+					Token:       txLog.Address,
+					FromAddress: event.From,
+					ToAddress:   event.To,
+					Amount:      event.Amount.String(),
+					Timestamp:   modelTx.Timestamp,
+				})
+			}
+		}
+		modelTx.Logs = append(modelTx.Logs, txLog)
+	}
+}
+
+// eth returns the current connection, which may be replaced on failover
+func (c *Client) eth() *ethclient.Client {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.active.client
+}
+
+// Close closes every open provider connection
 func (c *Client) Close() {
-	c.client.Close()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, p := range c.providers {
+		if p.client != nil {
+			p.client.Close()
+			p.client = nil
+		}
+	}
 }
@@ -0,0 +1,118 @@
+package ethereum
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/minsix/backend/internal/address"
+	"github.com/minsix/backend/internal/decoder"
+	"github.com/minsix/backend/internal/models"
+)
+
+func TestApplyReceipt(t *testing.T) {
+	token := common.HexToAddress("0xdAC17F958D2ee523a2206206994597C13D831ec7")
+	from := common.HexToAddress("0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb0")
+	to := common.HexToAddress("0x1234567890123456789012345678901234567890")
+
+	receipt := &types.Receipt{
+		Status:            types.ReceiptStatusSuccessful,
+		GasUsed:           51000,
+		EffectiveGasPrice: big.NewInt(25000000000),
+		Logs: []*types.Log{
+			{
+				Address: token,
+				Topics: []common.Hash{
+					common.HexToHash(decoder.TopicTransfer),
+					common.BytesToHash(from.Bytes()),
+					common.BytesToHash(to.Bytes()),
+				},
+				Data:  common.BigToHash(big.NewInt(1000000)).Bytes(),
+				Index: 7,
+			},
+			{
+				Address: token,
+				Topics:  []common.Hash{common.HexToHash("0x01")},
+				Index:   8,
+			},
+		},
+	}
+
+	tx := &models.Transaction{TxHash: "0xabc", BlockNumber: 100, GasUsed: 90000}
+	applyReceipt(tx, receipt)
+
+	if tx.GasUsed != 51000 || *tx.Status != models.TxStatusSuccess || *tx.EffectiveGasPrice != "25000000000" {
+		t.Errorf("applyReceipt() = gas %d, status %v, price %v", tx.GasUsed, *tx.Status, *tx.EffectiveGasPrice)
+	}
+	if tx.ContractAddress != nil {
+		t.Errorf("ContractAddress = %v, want nil", *tx.ContractAddress)
+	}
+	if len(tx.Logs) != 2 {
+		t.Fatalf("got %d logs, want 2", len(tx.Logs))
+	}
+
+	transfer := tx.Logs[0]
+	if transfer.Event == nil || *transfer.Event != decoder.EventTransfer || transfer.LogIndex != 7 {
+		t.Errorf("transfer log = %+v", transfer)
+	}
+	if transfer.Decoded["from"] != address.FromCommon(from).String() || transfer.Decoded["to"] != address.FromCommon(to).String() || transfer.Decoded["amount"] != "1000000" {
+		t.Errorf("transfer decoded = %v", transfer.Decoded)
+	}
+	if len(tx.TokenTransfers) != 1 || tx.TokenTransfers[0].Token != address.FromCommon(token) || tx.TokenTransfers[0].Amount != "1000000" {
+		t.Errorf("token transfers = %+v", tx.TokenTransfers)
+	}
+	if tx.Logs[1].Event != nil || tx.Logs[1].Decoded != nil {
+		t.Errorf("unknown log should not be decoded: %+v", tx.Logs[1])
+	}
+
+	reverted := &models.Transaction{}
+	applyReceipt(reverted, &types.Receipt{Status: types.ReceiptStatusFailed, ContractAddress: to})
+	if *reverted.Status != models.TxStatusReverted || *reverted.ContractAddress != address.FromCommon(to) {
+		t.Errorf("reverted receipt = status %v, contract %v", *reverted.Status, reverted.ContractAddress)
+	}
+}
+
+func TestRecentHashes(t *testing.T) {
+	seen := newRecentHashes(2)
+	first, second, third := common.HexToHash("0x01"), common.HexToHash("0x02"), common.HexToHash("0x03")
+
+	if !seen.add(first) || !seen.add(second) {
+		t.Fatal("add() rejected a new hash")
+	}
+	if seen.add(first) {
+		t.Error("add() accepted a remembered hash")
+	}
+	// The oldest hash is forgotten once the set is full
+	if !seen.add(third) || !seen.add(first) {
+		t.Error("add() still remembers the oldest hash of a full set")
+	}
+	if seen.add(third) {
+		t.Error("add() forgot a recent hash")
+	}
+}
+
+func TestLogsMention(t *testing.T) {
+	wallet := common.HexToAddress("0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb0")
+	other := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	watched := func(addr address.Address) bool { return addr == address.FromCommon(wallet) }
+	transfer := common.HexToHash(decoder.TopicTransfer)
+
+	tests := []struct {
+		name string
+		log  *types.Log
+		want bool
+	}{
+		{"emitted by the address", &types.Log{Address: wallet, Topics: []common.Hash{transfer}}, true},
+		{"sent by the address", &types.Log{Address: other, Topics: []common.Hash{transfer, common.BytesToHash(wallet.Bytes()), common.BytesToHash(other.Bytes())}}, true},
+		{"sent to the address", &types.Log{Address: other, Topics: []common.Hash{transfer, common.BytesToHash(other.Bytes()), common.BytesToHash(wallet.Bytes())}}, true},
+		{"third topic", &types.Log{Address: other, Topics: []common.Hash{transfer, {}, {}, common.BytesToHash(wallet.Bytes())}}, false},
+		{"dirty topic", &types.Log{Address: other, Topics: []common.Hash{transfer, common.HexToHash("0xff000000000000000000000" + wallet.Hex()[2:])}}, false},
+		{"unrelated", &types.Log{Address: other, Topics: []common.Hash{transfer}}, false},
+	}
+	for _, tt := range tests {
+		if got := logsMention([]*types.Log{tt.log}, watched); got != tt.want {
+			t.Errorf("%s: logsMention() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package ethereum
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/minsix/backend/internal/address"
+	"github.com/minsix/backend/internal/models"
+)
+
+const (
+	// PendingFetchConcurrency bounds the lookups of pending transactions
+	// announced by hash only
+	PendingFetchConcurrency = 8
+
+	// pendingBuffer is how many announcements may queue before the handler
+	pendingBuffer = 1024
+)
+
+// SubscribePendingTransactions follows the mempool with eth_subscribe
+// newPendingTransactions and hands each transaction to txHandler before it
+// is mined. Full transaction objects are requested; nodes that only announce
+// hashes are supported by looking each transaction up. Pending transactions
+// carry FinalityPending and have no block, receipt or logs. The
+// subscription is renewed with backoff when it drops.
+func (c *Client) SubscribePendingTransactions(ctx context.Context, txHandler func(*models.Transaction)) error {
+	if err := c.requireSubscriptions(ctx); err != nil {
+		return err
+	}
+
+	messages := make(chan json.RawMessage, pendingBuffer)
+	sub, err := c.subscribePending(ctx, messages)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to pending transactions: %w", err)
+	}
+	log.Println("Subscribed to pending transactions")
+
+	go func() {
+		sem := make(chan struct{}, PendingFetchConcurrency)
+		for {
+			select {
+			case err := <-sub.Err():
+				log.Printf("Pending transaction subscription error: %v", err)
+				sub = c.resubscribePending(ctx, messages)
+				if sub == nil {
+					log.Println("Pending transaction subscription stopped")
+					return
+				}
+			case raw := <-messages:
+				if len(raw) > 0 && raw[0] == '"' {
+					// Hash-only announcement; look the transaction up without
+					// holding back the rest of the stream
+					var hash common.Hash
+					if err := json.Unmarshal(raw, &hash); err != nil {
+						log.Printf("Failed to decode pending transaction hash: %v", err)
+						continue
+					}
+					select {
+					case sem <- struct{}{}:
+					case <-ctx.Done():
+						continue
+					}
+					go func() {
+						defer func() { <-sem }()
+						if tx := c.fetchPending(ctx, hash); tx != nil {
+							txHandler(tx)
+						}
+					}()
+					continue
+				}
+
+				tx := new(types.Transaction)
+				if err := tx.UnmarshalJSON(raw); err != nil {
+					if !errors.Is(err, types.ErrTxTypeNotSupported) {
+						log.Printf("Failed to decode pending transaction: %v", err)
+					}
+					continue
+				}
+				modelTx, err := c.convertPending(tx)
+				if err != nil {
+					log.Printf("Failed to convert pending transaction: %v", err)
+					continue
+				}
+				txHandler(modelTx)
+			case <-ctx.Done():
+				sub.Unsubscribe()
+				log.Println("Pending transaction subscription stopped")
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// subscribePending subscribes on the active provider, asking for full
+// transactions and falling back to hashes for nodes that reject the flag
+func (c *Client) subscribePending(ctx context.Context, messages chan json.RawMessage) (ethereum.Subscription, error) {
+	_, conn := c.activeProvider()
+	sub, err := conn.Client().EthSubscribe(ctx, messages, "newPendingTransactions", true)
+	if err == nil {
+		return sub, nil
+	}
+	log.Printf("Full pending transactions unavailable, subscribing to hashes: %v", err)
+	return conn.Client().EthSubscribe(ctx, messages, "newPendingTransactions")
+}
+
+// resubscribePending retries the subscription with backoff on whichever
+// provider is active, which the block subscription and health monitor keep
+// current. It returns nil once ctx is cancelled.
+func (c *Client) resubscribePending(ctx context.Context, messages chan json.RawMessage) ethereum.Subscription {
+	for attempt := 0; ; attempt++ {
+		delay := backoffDelay(attempt)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil
+		}
+
+		sub, err := c.subscribePending(ctx, messages)
+		if err != nil {
+			log.Printf("Failed to resubscribe to pending transactions: %v", err)
+			continue
+		}
+		log.Println("Resubscribed to pending transactions")
+		return sub
+	}
+}
+
+// fetchPending looks up an announced transaction; it returns nil when the
+// transaction is unknown or already mined
+func (c *Client) fetchPending(ctx context.Context, hash common.Hash) *models.Transaction {
+	tx, pending, err := c.eth().TransactionByHash(ctx, hash)
+	if err != nil {
+		if !errors.Is(err, ethereum.NotFound) && ctx.Err() == nil {
+			log.Printf("Failed to get pending transaction %s: %v", hash.Hex(), err)
+		}
+		return nil
+	}
+	if !pending {
+		return nil
+	}
+	modelTx, err := c.convertPending(tx)
+	if err != nil {
+		log.Printf("Failed to convert pending transaction: %v", err)
+		return nil
+	}
+	return modelTx
+}
+
+// convertPending converts a mempool transaction; it is timestamped when seen
+// and its gas used is the gas limit
+func (c *Client) convertPending(tx *types.Transaction) (*models.Transaction, error) {
+	from, err := types.Sender(types.LatestSignerForChainID(c.chainID), tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sender: %w", err)
+	}
+
+	nonce := tx.Nonce()
+	modelTx := &models.Transaction{
+		ChainID:     c.chainID.Int64(),
+		TxHash:      tx.Hash().Hex(),
+		FromAddress: address.FromCommon(from),
+		Nonce:       &nonce,
+		Value:       tx.Value().String(),
+		GasPrice:    tx.GasPrice().String(),
+		GasUsed:     int64(tx.Gas()),
+		Finality:    models.FinalityPending,
+		Timestamp:   time.Now(),
+	}
+	if tx.To() != nil {
+		modelTx.ToAddress = address.FromCommon(*tx.To()).Ptr()
+	}
+	if len(tx.Data()) > 0 {
+		data := fmt.Sprintf("0x%x", tx.Data())
+		modelTx.InputData = &data
+	}
+	return modelTx, nil
+}
+
+// PendingState reports whether a transaction is unknown to the node, still in
+// the mempool or mined
+func (c *Client) PendingState(ctx context.Context, txHash string) (known, pending bool, err error) {
+	var raw *struct {
+		BlockNumber *string `json:"blockNumber"`
+	}
+	if err := c.eth().Client().CallContext(ctx, &raw, "eth_getTransactionByHash", common.HexToHash(txHash)); err != nil {
+		return false, false, err
+	}
+	if raw == nil {
+		return false, false, nil
+	}
+	return true, raw.BlockNumber == nil, nil
+}
@@ -0,0 +1,113 @@
+package ethereum
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/minsix/backend/internal/models"
+)
+
+// Block sources selectable with BLOCK_SOURCE
+const (
+	BlockSourceAuto      = "auto"      // subscribe when a ws or ipc provider is configured, otherwise poll
+	BlockSourceSubscribe = "subscribe" // eth_subscribe newHeads
+	BlockSourcePoll      = "poll"      // eth_blockNumber on an interval
+)
+
+// DefaultPollInterval is roughly one mainnet slot
+const DefaultPollInterval = 12 * time.Second
+
+// StartBlocks follows new blocks with the given source, handing their
+// transactions to txHandler
+func (c *Client) StartBlocks(ctx context.Context, source string, pollInterval time.Duration, txHandler func(*models.Transaction)) error {
+	switch source {
+	case BlockSourceAuto, "":
+		if !c.CanSubscribe() {
+			return c.PollBlocks(ctx, pollInterval, txHandler)
+		}
+		return c.SubscribeToBlocks(ctx, txHandler)
+	case BlockSourceSubscribe:
+		return c.SubscribeToBlocks(ctx, txHandler)
+	case BlockSourcePoll:
+		return c.PollBlocks(ctx, pollInterval, txHandler)
+	default:
+		return fmt.Errorf("unknown block source %q", source)
+	}
+}
+
+// CanSubscribe reports whether any configured provider supports subscriptions
+func (c *Client) CanSubscribe() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.pick(nil, true) != nil
+}
+
+// PollBlocks follows new blocks by polling eth_blockNumber, for endpoints that
+// only speak HTTP. Blocks are fetched in order and handled like subscribed
+// heads, including catch-up, gap filling and reorg detection. When a poll
+// fails the client fails over to the next provider.
+func (c *Client) PollBlocks(ctx context.Context, interval time.Duration, txHandler func(*models.Transaction)) error {
+	if interval <= 0 {
+		return fmt.Errorf("poll interval must be positive")
+	}
+
+	log.Printf("Polling for new blocks every %s", interval)
+	c.updateStatus(func(s *SubscriptionStatus) { s.Connected = true })
+
+	go func() {
+		last := c.catchUp(ctx, txHandler)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				last = c.poll(ctx, last, txHandler)
+			case <-ctx.Done():
+				log.Println("Block polling stopped")
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// poll processes any blocks after last up to the current head and returns the
+// new last processed block
+func (c *Client) poll(ctx context.Context, last uint64, txHandler func(*models.Transaction)) uint64 {
+	active, _ := c.activeProvider()
+	head, err := c.GetLatestBlock(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return last
+		}
+		log.Printf("Failed to poll latest block: %v", err)
+		now := time.Now()
+		c.updateStatus(func(s *SubscriptionStatus) {
+			if s.Connected {
+				s.DisconnectedAt = &now
+			}
+			s.Connected = false
+			s.LastError = err.Error()
+		})
+		if err := c.failover(ctx, active); err != nil {
+			log.Printf("Failed to reconnect: %v", err)
+		}
+		return last
+	}
+
+	c.updateStatus(func(s *SubscriptionStatus) {
+		if !s.Connected {
+			s.Reconnects++
+		}
+		s.Connected = true
+		s.DisconnectedAt = nil
+	})
+	if head <= last {
+		return last
+	}
+	return c.processBlocks(ctx, last, head, MaxGapFill, txHandler)
+}
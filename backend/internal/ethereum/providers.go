@@ -0,0 +1,352 @@
+package ethereum
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+const (
+	// HealthCheckInterval is how often MonitorProviders probes each provider
+	HealthCheckInterval = 30 * time.Second
+
+	// HealthCheckTimeout bounds each provider probe
+	HealthCheckTimeout = 5 * time.Second
+
+	// MaxHeadLag is how many blocks a provider may trail the best head before
+	// its probe counts as a failure
+	MaxHeadLag = 3
+
+	// healthDecay weights the latest probe in a provider's score
+	healthDecay = 0.3
+
+	// failoverMargin is how much healthier another provider must be before
+	// the active one is replaced, to avoid flapping
+	failoverMargin = 0.25
+)
+
+// provider is one RPC endpoint with a rolling health score
+type provider struct {
+	url           string
+	client        *ethclient.Client // nil until dialed
+	subscriptions bool              // ws and ipc support subscriptions, http does not
+	score         float64           // 0 (failing) to 1 (healthy)
+	latency       time.Duration
+	head          uint64
+	disabled      bool // wrong chain; never selected
+	lastError     string
+}
+
+// ProviderStatus describes an RPC provider for the health endpoint
+type ProviderStatus struct {
+	URL       string  `json:"url"`
+	Active    bool    `json:"active"`
+	Score     float64 `json:"score"`
+	LatencyMS int64   `json:"latency_ms"`
+	Head      uint64  `json:"head"`
+	Disabled  bool    `json:"disabled,omitempty"`
+	LastError string  `json:"last_error,omitempty"`
+}
+
+// AlchemyURL builds the websocket endpoint for an Alchemy network such as eth-mainnet
+func AlchemyURL(apiKey, network string) string {
+	return fmt.Sprintf("wss://%s.g.alchemy.com/v2/%s", network, apiKey)
+}
+
+// ProviderURLs combines a comma-separated RPC_URL list with an optional
+// Alchemy endpoint, which is added last as a fallback
+func ProviderURLs(rpcURLs, alchemyAPIKey, alchemyNetwork string) []string {
+	var urls []string
+	for _, raw := range strings.Split(rpcURLs, ",") {
+		if raw = strings.TrimSpace(raw); raw != "" {
+			urls = append(urls, raw)
+		}
+	}
+	if alchemyAPIKey != "" {
+		urls = append(urls, AlchemyURL(alchemyAPIKey, alchemyNetwork))
+	}
+	return urls
+}
+
+// newProvider validates an endpoint: ws(s) and http(s) URLs or an IPC socket path
+func newProvider(raw string) (*provider, error) {
+	p := &provider{url: raw, score: 1}
+	if !strings.Contains(raw, "://") {
+		p.subscriptions = true // IPC path
+		return p, nil
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid RPC URL: %w", err)
+	}
+	switch u.Scheme {
+	case "ws", "wss":
+		p.subscriptions = true
+	case "http", "https":
+	default:
+		return nil, fmt.Errorf("unsupported RPC URL scheme %q", u.Scheme)
+	}
+	return p, nil
+}
+
+// redacted hides credentials such as API keys embedded in the URL path or query
+func (p *provider) redacted() string {
+	u, err := url.Parse(p.url)
+	if err != nil || u.Host == "" {
+		return p.url
+	}
+	redacted := u.Scheme + "://" + u.Host
+	if u.Path != "" && u.Path != "/" {
+		redacted += "/…"
+	}
+	return redacted
+}
+
+// record folds a probe or call outcome into the provider's score
+func (p *provider) record(ok bool, err error) {
+	sample := 0.0
+	if ok {
+		sample = 1
+		p.lastError = ""
+	} else if err != nil {
+		p.lastError = err.Error()
+	}
+	p.score = (1-healthDecay)*p.score + healthDecay*sample
+}
+
+// dial connects the provider and checks it serves the expected chain
+func (c *Client) dial(ctx context.Context, p *provider) error {
+	client, err := ethclient.DialContext(ctx, p.url)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", p.redacted(), err)
+	}
+
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		client.Close()
+		return fmt.Errorf("failed to get chain ID from %s: %w", p.redacted(), err)
+	}
+	if c.chainID == nil {
+		c.chainID = chainID
+	} else if chainID.Cmp(c.chainID) != 0 {
+		client.Close()
+		p.disabled = true
+		return fmt.Errorf("provider %s serves chain %s, want %s", p.redacted(), chainID, c.chainID)
+	}
+
+	p.client = client
+	return nil
+}
+
+// pick returns the healthiest usable provider other than exclude, preferring
+// configuration order on ties; when subscriptions is set only ws and ipc
+// providers qualify. The caller must hold c.mu.
+func (c *Client) pick(exclude *provider, subscriptions bool) *provider {
+	var best *provider
+	for _, p := range c.providers {
+		if p == exclude || p.disabled || (subscriptions && !p.subscriptions) {
+			continue
+		}
+		if best == nil || p.score > best.score {
+			best = p
+		}
+	}
+	return best
+}
+
+// failover marks failed as unhealthy and switches to the healthiest other
+// provider, redialing failed when it is the only option. It does nothing if
+// another caller, e.g. the health monitor, already moved off failed.
+func (c *Client) failover(ctx context.Context, failed *provider) error {
+	c.mu.Lock()
+	if c.active != failed {
+		c.mu.Unlock()
+		return nil
+	}
+	failed.record(false, nil)
+	candidate := c.pick(failed, c.subscribed)
+	if candidate == nil {
+		candidate = failed
+	}
+	c.mu.Unlock()
+
+	return c.activate(ctx, candidate)
+}
+
+// requireSubscriptions switches to a ws or ipc provider if the active one
+// cannot subscribe, and keeps failover among such providers from then on
+func (c *Client) requireSubscriptions(ctx context.Context) error {
+	c.mu.Lock()
+	c.subscribed = true
+	active := c.active
+	candidate := c.pick(active, true)
+	c.mu.Unlock()
+
+	if active.subscriptions {
+		return nil
+	}
+	if candidate == nil {
+		return fmt.Errorf("no configured RPC provider supports subscriptions; use a ws, wss or IPC endpoint")
+	}
+	return c.activate(ctx, candidate)
+}
+
+// activeProvider returns the active provider together with its connection
+func (c *Client) activeProvider() (*provider, *ethclient.Client) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.active, c.active.client
+}
+
+// activate dials a provider if needed and makes it the active connection,
+// closing the previous one
+func (c *Client) activate(ctx context.Context, p *provider) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	old := c.active
+	if old == p && p.client != nil {
+		// Redial the same provider to replace a broken connection
+		p.client.Close()
+		p.client = nil
+	}
+	if p.client == nil {
+		if err := c.dial(ctx, p); err != nil {
+			p.record(false, err)
+			return err
+		}
+	}
+
+	c.active = p
+	if old != nil && old != p && old.client != nil {
+		old.client.Close()
+		old.client = nil
+	}
+	if old != nil && old != p {
+		log.Printf("Switched RPC provider to %s", p.redacted())
+	}
+	return nil
+}
+
+// MonitorProviders probes every provider on an interval, scoring them by
+// availability and head lag, and fails over when another provider is
+// clearly healthier than the active one. It blocks until ctx is cancelled.
+func (c *Client) MonitorProviders(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.probeProviders(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *Client) probeProviders(ctx context.Context) {
+	c.mu.RLock()
+	providers := append([]*provider(nil), c.providers...)
+	c.mu.RUnlock()
+
+	results := make([]probeResult, len(providers))
+	var best uint64
+	for i, p := range providers {
+		if p.disabled {
+			continue
+		}
+		results[i] = c.probe(ctx, p)
+		if results[i].err == nil && results[i].head > best {
+			best = results[i].head
+		}
+	}
+
+	c.mu.Lock()
+	for i, p := range providers {
+		if p.disabled {
+			continue
+		}
+		r := results[i]
+		if r.err != nil {
+			p.record(false, r.err)
+			continue
+		}
+		p.head, p.latency = r.head, r.latency
+		if best > r.head+MaxHeadLag {
+			p.record(false, fmt.Errorf("%d blocks behind", best-r.head))
+			continue
+		}
+		p.record(true, nil)
+	}
+
+	active := c.active
+	candidate := c.pick(active, c.subscribed)
+	switchTo := candidate != nil && candidate.score > active.score+failoverMargin
+	c.mu.Unlock()
+
+	if switchTo {
+		log.Printf("RPC provider %s is unhealthy (score %.2f)", active.redacted(), active.score)
+		if err := c.activate(ctx, candidate); err != nil {
+			log.Printf("Failed to fail over: %v", err)
+		}
+	}
+}
+
+// probeResult is the outcome of one provider health check
+type probeResult struct {
+	head    uint64
+	latency time.Duration
+	err     error
+}
+
+// probe asks a provider for its head block, dialing it for the check if it
+// is not connected
+func (c *Client) probe(ctx context.Context, p *provider) (result probeResult) {
+	ctx, cancel := context.WithTimeout(ctx, HealthCheckTimeout)
+	defer cancel()
+
+	c.mu.RLock()
+	client := p.client
+	c.mu.RUnlock()
+	if client == nil {
+		dialed, err := ethclient.DialContext(ctx, p.url)
+		if err != nil {
+			result.err = err
+			return result
+		}
+		defer dialed.Close()
+		client = dialed
+	}
+
+	start := time.Now()
+	result.head, result.err = client.BlockNumber(ctx)
+	result.latency = time.Since(start)
+	return result
+}
+
+// ProviderStatus returns the health of every configured provider
+func (c *Client) ProviderStatus() []ProviderStatus {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	statuses := make([]ProviderStatus, 0, len(c.providers))
+	for _, p := range c.providers {
+		statuses = append(statuses, ProviderStatus{
+			URL:       p.redacted(),
+			Active:    p == c.active,
+			Score:     p.score,
+			LatencyMS: p.latency.Milliseconds(),
+			Head:      p.head,
+			Disabled:  p.disabled,
+			LastError: p.lastError,
+		})
+	}
+	return statuses
+}
@@ -0,0 +1,121 @@
+package ethereum
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestProviderURLs(t *testing.T) {
+	tests := []struct {
+		name     string
+		rpcURL   string
+		alchemy  string
+		expected []string
+	}{
+		{"None", "", "", nil},
+		{"Alchemy only", "", "key", []string{"wss://eth-mainnet.g.alchemy.com/v2/key"}},
+		{"RPC only", "ws://localhost:8546", "", []string{"ws://localhost:8546"}},
+		{
+			"Several with Alchemy fallback",
+			" ws://node:8546 , http://backup:8545,,",
+			"key",
+			[]string{"ws://node:8546", "http://backup:8545", "wss://eth-mainnet.g.alchemy.com/v2/key"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ProviderURLs(tt.rpcURL, tt.alchemy, "eth-mainnet")
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("ProviderURLs() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNewProvider(t *testing.T) {
+	tests := []struct {
+		url           string
+		subscriptions bool
+		wantErr       bool
+	}{
+		{"wss://eth-mainnet.g.alchemy.com/v2/key", true, false},
+		{"ws://localhost:8546", true, false},
+		{"http://localhost:8545", false, false},
+		{"https://rpc.example.com", false, false},
+		{"/var/run/geth.ipc", true, false},
+		{"ftp://localhost", false, true},
+	}
+	for _, tt := range tests {
+		p, err := newProvider(tt.url)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("newProvider(%q) error = %v, wantErr %v", tt.url, err, tt.wantErr)
+			continue
+		}
+		if err == nil && p.subscriptions != tt.subscriptions {
+			t.Errorf("newProvider(%q) subscriptions = %v, want %v", tt.url, p.subscriptions, tt.subscriptions)
+		}
+	}
+}
+
+func TestProviderRedacted(t *testing.T) {
+	p := &provider{url: "wss://eth-mainnet.g.alchemy.com/v2/secret"}
+	if got := p.redacted(); got != "wss://eth-mainnet.g.alchemy.com/…" {
+		t.Errorf("redacted() = %q", got)
+	}
+	p = &provider{url: "http://localhost:8545"}
+	if got := p.redacted(); got != "http://localhost:8545" {
+		t.Errorf("redacted() = %q", got)
+	}
+}
+
+func TestProviderRecord(t *testing.T) {
+	p := &provider{score: 1}
+	for i := 0; i < 5; i++ {
+		p.record(false, nil)
+	}
+	if p.score > 0.2 {
+		t.Errorf("score after repeated failures = %.2f, want <= 0.2", p.score)
+	}
+	low := p.score
+	p.record(true, nil)
+	if p.score <= low {
+		t.Errorf("score did not recover after success: %.2f", p.score)
+	}
+}
+
+func TestPickProvider(t *testing.T) {
+	ws := &provider{url: "ws://a", subscriptions: true, score: 0.5}
+	http := &provider{url: "http://b", score: 0.9}
+	ipc := &provider{url: "/c.ipc", subscriptions: true, score: 0.5}
+	wrongNetwork := &provider{url: "ws://d", subscriptions: true, score: 1, disabled: true}
+	c := &Client{providers: []*provider{ws, http, ipc, wrongNetwork}}
+
+	if got := c.pick(nil, false); got != http {
+		t.Errorf("pick() = %s, want the healthiest provider", got.url)
+	}
+	if got := c.pick(nil, true); got != ws {
+		t.Errorf("pick(subscriptions) = %s, want the first ws provider on a tie", got.url)
+	}
+	if got := c.pick(ws, true); got != ipc {
+		t.Errorf("pick(exclude ws) = %s, want the ipc provider", got.url)
+	}
+
+	c = &Client{providers: []*provider{http}}
+	if got := c.pick(nil, true); got != nil {
+		t.Errorf("pick(subscriptions) = %s, want none", got.url)
+	}
+}
+
+func TestStartBlocksSource(t *testing.T) {
+	c := &Client{providers: []*provider{{url: "http://a", score: 1}}}
+	if c.CanSubscribe() {
+		t.Error("CanSubscribe() = true with only an HTTP provider")
+	}
+	if err := c.StartBlocks(context.Background(), "websocket", DefaultPollInterval, nil); err == nil {
+		t.Error("StartBlocks() accepted an unknown source")
+	}
+	if err := c.PollBlocks(context.Background(), 0, nil); err == nil {
+		t.Error("PollBlocks() accepted a zero interval")
+	}
+}
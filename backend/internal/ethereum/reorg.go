@@ -0,0 +1,134 @@
+package ethereum
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/minsix/backend/internal/models"
+)
+
+// MaxReorgDepth is how many recent blocks are remembered to detect reorganizations
+const MaxReorgDepth = 64
+
+// reorgError stops block processing so it restarts after the common ancestor
+type reorgError struct {
+	ancestor uint64
+}
+
+func (e *reorgError) Error() string {
+	return fmt.Sprintf("chain reorganized after block %d", e.ancestor)
+}
+
+// chainWindow remembers the most recent processed blocks by height. It is only
+// used by the subscription goroutine once subscribed.
+type chainWindow struct {
+	blocks map[uint64]*models.Block
+	depth  uint64
+}
+
+func newChainWindow(depth uint64) *chainWindow {
+	return &chainWindow{blocks: make(map[uint64]*models.Block), depth: depth}
+}
+
+func (w *chainWindow) get(number uint64) (*models.Block, bool) {
+	block, ok := w.blocks[number]
+	return block, ok
+}
+
+// add records a block and forgets blocks that fell out of the window
+func (w *chainWindow) add(block *models.Block) {
+	w.blocks[block.Number] = block
+	for number := range w.blocks {
+		if number+w.depth <= block.Number {
+			delete(w.blocks, number)
+		}
+	}
+}
+
+// truncate removes and returns the blocks above ancestor in ascending order
+func (w *chainWindow) truncate(ancestor uint64) []*models.Block {
+	var removed []*models.Block
+	for number, block := range w.blocks {
+		if number > ancestor {
+			removed = append(removed, block)
+			delete(w.blocks, number)
+		}
+	}
+	sort.Slice(removed, func(i, j int) bool { return removed[i].Number < removed[j].Number })
+	return removed
+}
+
+// RememberBlocks seeds reorg detection with previously processed blocks, e.g.
+// from the database at startup; call it before subscribing
+func (c *Client) RememberBlocks(blocks []*models.Block) {
+	for _, block := range blocks {
+		c.chain.add(block)
+	}
+}
+
+// OnReorg registers a callback invoked with the common ancestor and the
+// orphaned blocks when the chain reorganizes, before the canonical blocks
+// are reprocessed
+func (c *Client) OnReorg(fn func(ancestor uint64, orphaned []*models.Block)) {
+	c.statusMu.Lock()
+	defer c.statusMu.Unlock()
+	c.onReorg = fn
+}
+
+// rollback finds the common ancestor at or below from, forgets the orphaned
+// blocks above it and reports them
+func (c *Client) rollback(ctx context.Context, from uint64) (uint64, error) {
+	ancestor, err := c.findAncestor(ctx, from)
+	if err != nil {
+		return 0, err
+	}
+
+	orphaned := c.chain.truncate(ancestor)
+	if len(orphaned) == 0 {
+		return ancestor, nil
+	}
+	log.Printf("WARNING: Chain reorganization: %d blocks after %d orphaned", len(orphaned), ancestor)
+
+	c.statusMu.Lock()
+	onReorg := c.onReorg
+	c.statusMu.Unlock()
+	if onReorg != nil {
+		onReorg(ancestor, orphaned)
+	}
+	return ancestor, nil
+}
+
+// findAncestor walks down from a height until the canonical block matches the
+// remembered one. Heights outside the window are assumed canonical.
+func (c *Client) findAncestor(ctx context.Context, from uint64) (uint64, error) {
+	for number := from; number > 0; number-- {
+		known, ok := c.chain.get(number)
+		if !ok {
+			return number, nil
+		}
+		header, err := c.eth().HeaderByNumber(ctx, new(big.Int).SetUint64(number))
+		if err != nil {
+			return 0, fmt.Errorf("failed to get header %d: %w", number, err)
+		}
+		if header.Hash().Hex() == known.Hash {
+			return number, nil
+		}
+	}
+	return 0, nil
+}
+
+// blockRef extracts the identity of a block
+func (c *Client) blockRef(block *types.Block) *models.Block {
+	return &models.Block{
+		ChainID:    c.chainID.Int64(),
+		Number:     block.NumberU64(),
+		Hash:       block.Hash().Hex(),
+		ParentHash: block.ParentHash().Hex(),
+		Timestamp:  time.Unix(int64(block.Time()), 0),
+	}
+}
@@ -0,0 +1,38 @@
+package ethereum
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/minsix/backend/internal/models"
+)
+
+func testBlock(number uint64) *models.Block {
+	return &models.Block{Number: number, Hash: fmt.Sprintf("0x%064x", number)}
+}
+
+func TestChainWindow(t *testing.T) {
+	w := newChainWindow(4)
+	for number := uint64(10); number <= 15; number++ {
+		w.add(testBlock(number))
+	}
+
+	// Only the last four blocks are remembered
+	if _, ok := w.get(11); ok {
+		t.Error("block 11 should have fallen out of the window")
+	}
+	if block, ok := w.get(12); !ok || block.Number != 12 {
+		t.Errorf("get(12) = %v, %v", block, ok)
+	}
+
+	orphaned := w.truncate(13)
+	if len(orphaned) != 2 || orphaned[0].Number != 14 || orphaned[1].Number != 15 {
+		t.Fatalf("truncate(13) = %v, want blocks 14 and 15 in order", orphaned)
+	}
+	if _, ok := w.get(14); ok {
+		t.Error("truncated block 14 is still remembered")
+	}
+	if _, ok := w.get(13); !ok {
+		t.Error("common ancestor 13 should be kept")
+	}
+}
@@ -0,0 +1,364 @@
+package ethereum
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/minsix/backend/internal/metrics"
+	"github.com/minsix/backend/internal/models"
+)
+
+const (
+	// Reconnect backoff after the block subscription drops
+	MinReconnectDelay = time.Second
+	MaxReconnectDelay = time.Minute
+
+	// MaxGapFill caps how many missed blocks are replayed after a reconnect
+	MaxGapFill = 1000
+
+	// ConvertWorkers bounds the goroutines converting a block's transactions
+	ConvertWorkers = 8
+)
+
+// SubscriptionStatus describes the health of the block subscription
+type SubscriptionStatus struct {
+	Connected      bool       `json:"connected"`
+	LastBlock      uint64     `json:"last_block"`
+	LastBlockAt    time.Time  `json:"last_block_at"`
+	Reconnects     int        `json:"reconnects"`
+	LastError      string     `json:"last_error,omitempty"`
+	DisconnectedAt *time.Time `json:"disconnected_at,omitempty"`
+
+	Providers []ProviderStatus `json:"providers"`
+}
+
+// SubscriptionStatus returns a snapshot of the block subscription health
+func (c *Client) SubscriptionStatus() SubscriptionStatus {
+	c.statusMu.Lock()
+	status := c.status
+	c.statusMu.Unlock()
+
+	status.Providers = c.ProviderStatus()
+	return status
+}
+
+func (c *Client) updateStatus(fn func(s *SubscriptionStatus)) {
+	c.statusMu.Lock()
+	defer c.statusMu.Unlock()
+	fn(&c.status)
+}
+
+// SubscribeToBlocks subscribes to new blocks and processes transactions. The
+// subscription is supervised: when it drops, the client redials with
+// exponential backoff, resubscribes and replays any blocks missed meanwhile.
+func (c *Client) SubscribeToBlocks(ctx context.Context, txHandler func(*models.Transaction)) error {
+	if err := c.requireSubscriptions(ctx); err != nil {
+		return err
+	}
+
+	headers := make(chan *types.Header)
+	subProvider, conn := c.activeProvider()
+	sub, err := conn.SubscribeNewHead(ctx, headers)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to new blocks: %w", err)
+	}
+
+	log.Println("Subscribed to new blocks")
+	c.updateStatus(func(s *SubscriptionStatus) { s.Connected = true })
+
+	go func() {
+		last := c.catchUp(ctx, txHandler) // last block processed, 0 before the first header
+
+		for {
+			select {
+			case err := <-sub.Err():
+				log.Printf("Subscription error: %v", err)
+				now := time.Now()
+				c.updateStatus(func(s *SubscriptionStatus) {
+					s.Connected = false
+					s.DisconnectedAt = &now
+					if err != nil {
+						s.LastError = err.Error()
+					}
+				})
+
+				sub, subProvider = c.resubscribe(ctx, headers, subProvider)
+				if sub == nil {
+					log.Println("Block subscription stopped")
+					return
+				}
+				if head, err := c.GetLatestBlock(ctx); err != nil {
+					log.Printf("Failed to get latest block for gap fill: %v", err)
+				} else {
+					last = c.processBlocks(ctx, last, head, MaxGapFill, txHandler)
+				}
+			case header := <-headers:
+				number := header.Number.Uint64()
+				// A new head at an already processed height replaces that block
+				if known, ok := c.chain.get(number); ok && known.Hash != header.Hash().Hex() {
+					ancestor, err := c.rollback(ctx, number)
+					if err != nil {
+						log.Printf("Failed to handle reorg: %v", err)
+						continue
+					}
+					last = ancestor
+				}
+				last = c.processBlocks(ctx, last, number, MaxGapFill, txHandler)
+			case <-ctx.Done():
+				sub.Unsubscribe()
+				log.Println("Block subscription stopped")
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// catchUp processes every block after the ResumeFrom checkpoint up to the
+// current head and returns the last processed block
+func (c *Client) catchUp(ctx context.Context, txHandler func(*models.Transaction)) uint64 {
+	c.statusMu.Lock()
+	last := c.resumeFrom
+	c.statusMu.Unlock()
+
+	if last == 0 {
+		return 0
+	}
+	head, err := c.GetLatestBlock(ctx)
+	if err != nil {
+		log.Printf("Failed to get latest block for catch-up: %v", err)
+		return last
+	}
+	if head > last {
+		log.Printf("Catching up blocks %d-%d", last+1, head)
+		last = c.processBlocks(ctx, last, head, 0, txHandler)
+	}
+	return last
+}
+
+// resubscribe fails over from the provider whose subscription dropped and
+// resubscribes until it succeeds or ctx is cancelled, in which case it
+// returns nil. It also returns the provider now subscribed to.
+func (c *Client) resubscribe(ctx context.Context, headers chan *types.Header, failed *provider) (ethereum.Subscription, *provider) {
+	for attempt := 0; ; attempt++ {
+		delay := backoffDelay(attempt)
+		log.Printf("Reconnecting block subscription in %s (attempt %d)", delay, attempt+1)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, nil
+		}
+
+		if err := c.failover(ctx, failed); err != nil {
+			log.Printf("Failed to reconnect: %v", err)
+			continue
+		}
+		active, conn := c.activeProvider()
+		sub, err := conn.SubscribeNewHead(ctx, headers)
+		if err != nil {
+			log.Printf("Failed to resubscribe to new blocks: %v", err)
+			failed = active
+			continue
+		}
+
+		log.Println("Resubscribed to new blocks")
+		c.updateStatus(func(s *SubscriptionStatus) {
+			s.Connected = true
+			s.DisconnectedAt = nil
+			s.Reconnects++
+		})
+		return sub, active
+	}
+}
+
+// processBlocks handles every block after last up to head and returns the new
+// last processed block. On the first call only head itself is processed; gaps
+// larger than maxGap (0 for no limit) are truncated to the most recent blocks.
+func (c *Client) processBlocks(ctx context.Context, last, head, maxGap uint64, txHandler func(*models.Transaction)) uint64 {
+	from := gapStart(last, head, maxGap)
+	if last > 0 && from > last+1 {
+		log.Printf("WARNING: Skipping blocks %d-%d, gap exceeds %d blocks; run backfill to recover them", last+1, from-1, maxGap)
+	}
+	if head > from {
+		log.Printf("Filling block gap %d-%d", from, head)
+	}
+
+	for number := from; number <= head; {
+		if ctx.Err() != nil {
+			return last
+		}
+		err := c.processBlock(ctx, number, txHandler)
+		if reorg, ok := err.(*reorgError); ok {
+			// Reprocess the canonical branch from the common ancestor
+			last = reorg.ancestor
+			number = last + 1
+			continue
+		}
+		if err != nil {
+			log.Printf("Failed to process block: %v", err)
+			// Stop here so the block is retried with the next header
+			return last
+		}
+		last = number
+		number++
+	}
+	return last
+}
+
+// processBlock fetches a block with its receipts and hands each transaction to
+// txHandler, then reports the block as processed. It returns a *reorgError
+// without handling the block when its parent is not the remembered block.
+func (c *Client) processBlock(ctx context.Context, number uint64, txHandler func(*models.Transaction)) error {
+	log.Printf("New block: %d", number)
+
+	block, txs, err := c.fetchBlock(ctx, number)
+	if err != nil {
+		return err
+	}
+
+	if parent, ok := c.chain.get(number - 1); ok && parent.Hash != block.ParentHash().Hex() {
+		ancestor, err := c.rollback(ctx, number-1)
+		if err != nil {
+			return err
+		}
+		return &reorgError{ancestor: ancestor}
+	}
+
+	for _, tx := range txs {
+		txHandler(tx)
+	}
+
+	ref := c.blockRef(block)
+	c.chain.add(ref)
+	c.updateStatus(func(s *SubscriptionStatus) {
+		s.LastBlock = number
+		s.LastBlockAt = time.Now()
+	})
+
+	c.statusMu.Lock()
+	onBlock := c.onBlock
+	c.statusMu.Unlock()
+	if onBlock != nil {
+		onBlock(ref)
+	}
+	return nil
+}
+
+// FetchBlock gets a block by number with its receipts and converts its transactions
+func (c *Client) FetchBlock(ctx context.Context, number uint64) ([]*models.Transaction, error) {
+	_, txs, err := c.fetchBlock(ctx, number)
+	return txs, err
+}
+
+// fetchBlock is the fetch and convert stages of ingestion: it loads a block
+// with its receipts, then converts its transactions with up to
+// ConvertWorkers goroutines, since recovering senders is CPU bound
+func (c *Client) fetchBlock(ctx context.Context, number uint64) (*types.Block, []*models.Transaction, error) {
+	start := time.Now()
+	block, err := c.blockByNumber(ctx, number)
+	if err != nil {
+		return nil, nil, fmt.Errorf("block %d: %w", number, err)
+	}
+
+	receipts, err := c.fetchReceipts(ctx, block)
+	if err != nil {
+		log.Printf("Failed to get receipts for block %d: %v", block.NumberU64(), err)
+	}
+	blockTxs := block.Transactions()
+	c.observeStage("fetch", time.Since(start), len(blockTxs))
+
+	start = time.Now()
+	converted := make([]*models.Transaction, len(blockTxs))
+	var next int64 = -1
+	var wg sync.WaitGroup
+	for w := 0; w < ConvertWorkers && w < len(blockTxs); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := atomic.AddInt64(&next, 1); i < int64(len(blockTxs)); i = atomic.AddInt64(&next, 1) {
+				tx := blockTxs[i]
+				modelTx, err := c.convertTransaction(tx, block, receipts[tx.Hash()])
+				if err != nil {
+					log.Printf("Failed to convert transaction: %v", err)
+					continue
+				}
+				converted[i] = modelTx
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Keep block order, dropping transactions that failed to convert
+	txs := make([]*models.Transaction, 0, len(converted))
+	for _, tx := range converted {
+		if tx != nil {
+			txs = append(txs, tx)
+		}
+	}
+	c.observeStage("convert", time.Since(start), len(txs))
+	return block, txs, nil
+}
+
+// SetStageMetrics records the latency of the fetch and convert stages
+func (c *Client) SetStageMetrics(stages *metrics.Stages) {
+	c.statusMu.Lock()
+	defer c.statusMu.Unlock()
+	c.stages = stages
+}
+
+func (c *Client) observeStage(name string, elapsed time.Duration, items int) {
+	c.statusMu.Lock()
+	stages := c.stages
+	c.statusMu.Unlock()
+	if stages != nil {
+		stages.Observe(name, elapsed, items)
+	}
+}
+
+// ResumeFrom makes SubscribeToBlocks catch up on every block after last
+// before following new heads; call it before subscribing
+func (c *Client) ResumeFrom(last uint64) {
+	c.statusMu.Lock()
+	defer c.statusMu.Unlock()
+	c.resumeFrom = last
+}
+
+// OnBlockProcessed registers a callback invoked once every transaction of a
+// block has been passed to the transaction handler, e.g. to checkpoint
+// progress or to close the block in an asynchronous pipeline
+func (c *Client) OnBlockProcessed(fn func(block *models.Block)) {
+	c.statusMu.Lock()
+	defer c.statusMu.Unlock()
+	c.onBlock = fn
+}
+
+// gapStart returns the first block to process when head arrives after last
+func gapStart(last, head, maxGap uint64) uint64 {
+	switch {
+	case last == 0:
+		return head
+	case maxGap > 0 && head > last+maxGap:
+		return head - maxGap + 1
+	default:
+		return last + 1
+	}
+}
+
+// backoffDelay doubles the reconnect delay per attempt up to MaxReconnectDelay
+func backoffDelay(attempt int) time.Duration {
+	delay := MinReconnectDelay
+	for i := 0; i < attempt && delay < MaxReconnectDelay; i++ {
+		delay *= 2
+	}
+	if delay > MaxReconnectDelay {
+		return MaxReconnectDelay
+	}
+	return delay
+}
@@ -0,0 +1,47 @@
+package ethereum
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{0, time.Second},
+		{1, 2 * time.Second},
+		{5, 32 * time.Second},
+		{6, time.Minute},
+		{100, time.Minute},
+	}
+	for _, tt := range tests {
+		if got := backoffDelay(tt.attempt); got != tt.want {
+			t.Errorf("backoffDelay(%d) = %s, want %s", tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func TestGapStart(t *testing.T) {
+	tests := []struct {
+		name       string
+		last, head uint64
+		maxGap     uint64
+		want       uint64
+	}{
+		{"First header", 0, 500, MaxGapFill, 500},
+		{"Next block", 100, 101, MaxGapFill, 101},
+		{"Gap after reconnect", 100, 110, MaxGapFill, 101},
+		{"Already processed", 100, 100, MaxGapFill, 101},
+		{"Gap larger than cap", 100, 100 + MaxGapFill + 5, MaxGapFill, 106},
+		{"Uncapped catch-up", 100, 100 + MaxGapFill + 5, 0, 101},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := gapStart(tt.last, tt.head, tt.maxGap); got != tt.want {
+				t.Errorf("gapStart(%d, %d) = %d, want %d", tt.last, tt.head, got, tt.want)
+			}
+		})
+	}
+}
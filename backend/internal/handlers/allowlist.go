@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/minsix/backend/internal/address"
+	"github.com/minsix/backend/internal/allowlist"
+	"github.com/minsix/backend/internal/database"
+	"github.com/minsix/backend/internal/models"
+)
+
+// allowlistRequest is the body of allowlist writes; address and chain_id are
+// ignored on update
+type allowlistRequest struct {
+	ChainID     *int64   `json:"chain_id"`
+	Address     string   `json:"address"`
+	Label       string   `json:"label"`
+	Rules       []string `json:"rules"`
+	ScoreFactor float64  `json:"score_factor"`
+	Reason      string   `json:"reason"`
+}
+
+// GetAllowlist lists the allowlisted addresses, of one chain with chain_id
+func (h *Handler) GetAllowlist(w http.ResponseWriter, r *http.Request) {
+	chainID, ok := chainFilter(w, r)
+	if !ok {
+		return
+	}
+
+	entries, err := h.db.GetAllowlist(chainID)
+	if err != nil {
+		log.Printf("Error listing allowlist: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to fetch allowlist")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, entries)
+}
+
+// GetAllowlistEntry returns one entry
+func (h *Handler) GetAllowlistEntry(w http.ResponseWriter, r *http.Request) {
+	entry, ok := h.allowlistEntry(w, r)
+	if !ok {
+		return
+	}
+	respondJSON(w, http.StatusOK, entry)
+}
+
+// AddAllowlistEntry allowlists an address for one chain, or every chain when
+// chain_id is omitted. It suppresses the listed rules, or every rule when
+// rules is empty, scaling their scores by score_factor.
+func (h *Handler) AddAllowlistEntry(w http.ResponseWriter, r *http.Request) {
+	var req allowlistRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	listed, err := address.Parse(req.Address)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	entry := &models.AllowlistedAddress{
+		ChainID:     req.ChainID,
+		Address:     listed,
+		Label:       req.Label,
+		Rules:       req.Rules,
+		ScoreFactor: req.ScoreFactor,
+		Reason:      req.Reason,
+	}
+	if err := allowlist.Validate(entry); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = h.db.AddAllowlistEntry(entry)
+	if errors.Is(err, database.ErrAllowlisted) {
+		respondError(w, http.StatusConflict, "Address is already allowlisted")
+		return
+	}
+	if err != nil {
+		log.Printf("Error adding allowlist entry: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to save allowlist entry")
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, entry)
+}
+
+// UpdateAllowlistEntry replaces the label, rules, score factor and reason of an entry
+func (h *Handler) UpdateAllowlistEntry(w http.ResponseWriter, r *http.Request) {
+	entry, ok := h.allowlistEntry(w, r)
+	if !ok {
+		return
+	}
+	var req allowlistRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	entry.Label = req.Label
+	entry.Rules = req.Rules
+	entry.ScoreFactor = req.ScoreFactor
+	entry.Reason = req.Reason
+	if err := allowlist.Validate(entry); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	updated, err := h.db.UpdateAllowlistEntry(entry)
+	if err != nil {
+		log.Printf("Error updating allowlist entry: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to save allowlist entry")
+		return
+	}
+	if !updated {
+		respondError(w, http.StatusNotFound, "Allowlist entry not found")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, entry)
+}
+
+// DeleteAllowlistEntry removes an entry
+func (h *Handler) DeleteAllowlistEntry(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || id <= 0 {
+		respondError(w, http.StatusBadRequest, "Invalid allowlist entry ID")
+		return
+	}
+
+	deleted, err := h.db.DeleteAllowlistEntry(id)
+	if err != nil {
+		log.Printf("Error deleting allowlist entry: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to delete allowlist entry")
+		return
+	}
+	if !deleted {
+		respondError(w, http.StatusNotFound, "Allowlist entry not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// allowlistEntry loads the entry named by the id path parameter, responding
+// with 400 or 404 and returning false when there is none
+func (h *Handler) allowlistEntry(w http.ResponseWriter, r *http.Request) (*models.AllowlistedAddress, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || id <= 0 {
+		respondError(w, http.StatusBadRequest, "Invalid allowlist entry ID")
+		return nil, false
+	}
+
+	entry, err := h.db.GetAllowlistEntry(id)
+	if err != nil {
+		log.Printf("Error getting allowlist entry: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to fetch allowlist entry")
+		return nil, false
+	}
+	if entry == nil {
+		respondError(w, http.StatusNotFound, "Allowlist entry not found")
+		return nil, false
+	}
+	return entry, true
+}
@@ -0,0 +1,300 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/minsix/backend/internal/address"
+	"github.com/minsix/backend/internal/blacklist"
+	"github.com/minsix/backend/internal/database"
+	"github.com/minsix/backend/internal/models"
+)
+
+// Page sizes of the blacklist listing
+const (
+	DefaultBlacklistLimit = 50
+	MaxBlacklistLimit     = 500
+)
+
+// MaxFeedSize is the largest feed accepted by the import endpoint
+const MaxFeedSize = 64 << 20
+
+// blacklistRequest is the body of blacklist writes; address and chain_id are
+// ignored on update
+type blacklistRequest struct {
+	ChainID   *int64     `json:"chain_id"`
+	Address   string     `json:"address"`
+	Reason    string     `json:"reason"`
+	Source    string     `json:"source"`
+	Category  string     `json:"category"`
+	Severity  string     `json:"severity"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// GetBlacklist lists blacklist entries newest first. It filters by chain_id,
+// category and severity, searches address, reason and source with search,
+// pages with limit and offset, and includes removed and expired entries when
+// include_deleted is true.
+func (h *Handler) GetBlacklist(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	chainID, ok := chainFilter(w, r)
+	if !ok {
+		return
+	}
+	filter := database.BlacklistFilter{
+		ChainID:        chainID,
+		Search:         query.Get("search"),
+		Category:       query.Get("category"),
+		Severity:       query.Get("severity"),
+		IncludeDeleted: query.Get("include_deleted") == "true",
+		Limit:          DefaultBlacklistLimit,
+	}
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			respondError(w, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+		filter.Limit = min(limit, MaxBlacklistLimit)
+	}
+	if value := query.Get("offset"); value != "" {
+		offset, err := strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			respondError(w, http.StatusBadRequest, "Invalid offset")
+			return
+		}
+		filter.Offset = offset
+	}
+
+	entries, total, err := h.db.ListBlacklist(filter)
+	if err != nil {
+		log.Printf("Error listing blacklist: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to fetch blacklist")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"entries": entries,
+		"total":   total,
+		"limit":   filter.Limit,
+		"offset":  filter.Offset,
+	})
+}
+
+// GetBlacklistEntry returns one entry, removed or not
+func (h *Handler) GetBlacklistEntry(w http.ResponseWriter, r *http.Request) {
+	entry, ok := h.blacklistEntry(w, r)
+	if !ok {
+		return
+	}
+	respondJSON(w, http.StatusOK, entry)
+}
+
+// AddBlacklistEntry blacklists an address for one chain, or every chain when
+// chain_id is omitted; a removed or expired entry for it is restored
+func (h *Handler) AddBlacklistEntry(w http.ResponseWriter, r *http.Request) {
+	var req blacklistRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	listed, err := address.Parse(req.Address)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	entry := &models.BlacklistedAddress{
+		ChainID:   req.ChainID,
+		Address:   listed,
+		Reason:    req.Reason,
+		Source:    req.Source,
+		Category:  req.Category,
+		Severity:  req.Severity,
+		ExpiresAt: req.ExpiresAt,
+	}
+	if err := blacklist.Validate(entry); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = h.db.AddBlacklistEntry(entry)
+	if errors.Is(err, database.ErrBlacklisted) {
+		respondError(w, http.StatusConflict, "Address is already blacklisted")
+		return
+	}
+	if err != nil {
+		log.Printf("Error adding blacklist entry: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to save blacklist entry")
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, entry)
+}
+
+// UpdateBlacklistEntry replaces the reason, source, category, severity and
+// expiry of an entry
+func (h *Handler) UpdateBlacklistEntry(w http.ResponseWriter, r *http.Request) {
+	entry, ok := h.blacklistEntry(w, r)
+	if !ok {
+		return
+	}
+	var req blacklistRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	entry.Reason = req.Reason
+	entry.Source = req.Source
+	entry.Category = req.Category
+	entry.Severity = req.Severity
+	entry.ExpiresAt = req.ExpiresAt
+	if err := blacklist.Validate(entry); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	updated, err := h.db.UpdateBlacklistEntry(entry)
+	if err != nil {
+		log.Printf("Error updating blacklist entry: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to save blacklist entry")
+		return
+	}
+	if !updated {
+		respondError(w, http.StatusNotFound, "Blacklist entry not found")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, entry)
+}
+
+// DeleteBlacklistEntry removes an entry, keeping it in the history
+func (h *Handler) DeleteBlacklistEntry(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || id <= 0 {
+		respondError(w, http.StatusBadRequest, "Invalid blacklist entry ID")
+		return
+	}
+
+	removed, err := h.db.DeleteBlacklistEntry(id)
+	if err != nil {
+		log.Printf("Error removing blacklist entry: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to remove blacklist entry")
+		return
+	}
+	if !removed {
+		respondError(w, http.StatusNotFound, "Blacklist entry not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetBlacklistHistory returns every change made to an entry, oldest first
+func (h *Handler) GetBlacklistHistory(w http.ResponseWriter, r *http.Request) {
+	entry, ok := h.blacklistEntry(w, r)
+	if !ok {
+		return
+	}
+
+	changes, err := h.db.GetBlacklistHistory(entry.ID)
+	if err != nil {
+		log.Printf("Error getting blacklist history: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to fetch blacklist history")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, changes)
+}
+
+// ImportBlacklistFeed imports an address feed sent as the request body. The
+// source query parameter names the feed; format is detected from file_name
+// unless set. chain_id limits the entries to one chain, category and severity
+// default those of the entries, prune removes entries the feed dropped and
+// dry_run reports the changes without applying them.
+func (h *Handler) ImportBlacklistFeed(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	chainID, ok := chainFilter(w, r)
+	if !ok {
+		return
+	}
+	opts := blacklist.FeedOptions{
+		Source:   query.Get("source"),
+		Format:   query.Get("format"),
+		FileName: query.Get("file_name"),
+		Category: query.Get("category"),
+		Severity: query.Get("severity"),
+		Prune:    query.Get("prune") == "true",
+		DryRun:   query.Get("dry_run") == "true",
+	}
+	if chainID != 0 {
+		opts.ChainID = &chainID
+	}
+	if opts.Format == "" && opts.FileName == "" {
+		respondError(w, http.StatusBadRequest, "format or file_name is required")
+		return
+	}
+
+	result, err := blacklist.Import(h.db, http.MaxBytesReader(w, r.Body, MaxFeedSize), opts)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			respondError(w, http.StatusRequestEntityTooLarge, "Feed is too large")
+			return
+		}
+		if errors.Is(err, blacklist.ErrInvalidFeed) {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		log.Printf("Error importing blacklist feed: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to import feed")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, result)
+}
+
+// GetBlacklistImports lists the latest feed imports, of one feed with source
+func (h *Handler) GetBlacklistImports(w http.ResponseWriter, r *http.Request) {
+	limit := DefaultBlacklistLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		if l, err := strconv.Atoi(value); err == nil && l > 0 {
+			limit = min(l, MaxBlacklistLimit)
+		}
+	}
+
+	imports, err := h.db.GetBlacklistImports(r.URL.Query().Get("source"), limit)
+	if err != nil {
+		log.Printf("Error getting blacklist imports: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to fetch blacklist imports")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, imports)
+}
+
+// blacklistEntry loads the entry named by the id path parameter, responding
+// with 400 or 404 and returning false when there is none
+func (h *Handler) blacklistEntry(w http.ResponseWriter, r *http.Request) (*models.BlacklistedAddress, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || id <= 0 {
+		respondError(w, http.StatusBadRequest, "Invalid blacklist entry ID")
+		return nil, false
+	}
+
+	entry, err := h.db.GetBlacklistEntry(id)
+	if err != nil {
+		log.Printf("Error getting blacklist entry: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to fetch blacklist entry")
+		return nil, false
+	}
+	if entry == nil {
+		respondError(w, http.StatusNotFound, "Blacklist entry not found")
+		return nil, false
+	}
+	return entry, true
+}
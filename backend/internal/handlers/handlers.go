@@ -5,10 +5,17 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
+	"github.com/minsix/backend/internal/address"
 	"github.com/minsix/backend/internal/database"
+	"github.com/minsix/backend/internal/ethereum"
+	"github.com/minsix/backend/internal/metrics"
+	"github.com/minsix/backend/internal/models"
+	"github.com/minsix/backend/internal/tokens"
 	ws "github.com/minsix/backend/internal/websocket"
 )
 
@@ -20,9 +27,40 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
+// StaleIngestAfter is how long without a new block before ingestion is reported unhealthy
+const StaleIngestAfter = 5 * time.Minute
+
+// IngestStatus reports the health of block ingestion
+type IngestStatus interface {
+	SubscriptionStatus() ethereum.SubscriptionStatus
+}
+
+// PipelineStatus reports the latency of each ingestion stage
+type PipelineStatus interface {
+	PipelineStats() []metrics.StageStats
+}
+
+// chain is an ingested chain served by the API
+type chain struct {
+	id       int64
+	name     string
+	tokens   *tokens.Registry
+	ingest   IngestStatus
+	pipeline PipelineStatus
+}
+
+// ChainInfo describes an ingested chain
+type ChainInfo struct {
+	ChainID   int64                        `json:"chain_id"`
+	Name      string                       `json:"name"`
+	Ingestion *ethereum.SubscriptionStatus `json:"ingestion,omitempty"`
+	Pipeline  []metrics.StageStats         `json:"pipeline,omitempty"`
+}
+
 type Handler struct {
-	db  *database.DB
-	hub *ws.Hub
+	db     *database.DB
+	hub    *ws.Hub
+	chains []*chain
 }
 
 func NewHandler(db *database.DB, hub *ws.Hub) *Handler {
@@ -32,13 +70,60 @@ func NewHandler(db *database.DB, hub *ws.Hub) *Handler {
 	}
 }
 
-// HealthCheck handles health check requests
+// AddChain registers an ingested chain. Its token registry is kept in sync
+// with API edits and its ingestion and pipeline are reported by the health
+// check; any of them may be nil.
+func (h *Handler) AddChain(chainID int64, name string, registry *tokens.Registry, ingest IngestStatus, pipeline PipelineStatus) {
+	h.chains = append(h.chains, &chain{id: chainID, name: name, tokens: registry, ingest: ingest, pipeline: pipeline})
+}
+
+func (h *Handler) findChain(chainID int64) *chain {
+	for _, c := range h.chains {
+		if c.id == chainID {
+			return c
+		}
+	}
+	return nil
+}
+
+// HealthCheck handles health check requests; it returns 503 while the block
+// subscription of any chain is down or has not delivered a block for StaleIngestAfter
 func (h *Handler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 	response := map[string]interface{}{
-		"status": "healthy",
+		"status":  "healthy",
 		"clients": h.hub.GetClientCount(),
 	}
-	respondJSON(w, http.StatusOK, response)
+
+	code := http.StatusOK
+	chains := make([]ChainInfo, 0, len(h.chains))
+	for _, c := range h.chains {
+		info := ChainInfo{ChainID: c.id, Name: c.name}
+		if c.ingest != nil {
+			status := c.ingest.SubscriptionStatus()
+			info.Ingestion = &status
+			stale := !status.LastBlockAt.IsZero() && time.Since(status.LastBlockAt) > StaleIngestAfter
+			if !status.Connected || stale {
+				response["status"] = "degraded"
+				code = http.StatusServiceUnavailable
+			}
+		}
+		if c.pipeline != nil {
+			info.Pipeline = c.pipeline.PipelineStats()
+		}
+		chains = append(chains, info)
+	}
+	response["chains"] = chains
+
+	respondJSON(w, code, response)
+}
+
+// GetChains lists the ingested chains
+func (h *Handler) GetChains(w http.ResponseWriter, r *http.Request) {
+	chains := make([]ChainInfo, 0, len(h.chains))
+	for _, c := range h.chains {
+		chains = append(chains, ChainInfo{ChainID: c.id, Name: c.name})
+	}
+	respondJSON(w, http.StatusOK, chains)
 }
 
 // GetFlaggedTransactions returns recent flagged transactions
@@ -51,7 +136,12 @@ func (h *Handler) GetFlaggedTransactions(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
-	transactions, err := h.db.GetFlaggedTransactions(limit)
+	chainID, ok := chainFilter(w, r)
+	if !ok {
+		return
+	}
+
+	transactions, err := h.db.GetFlaggedTransactions(chainID, limit)
 	if err != nil {
 		log.Printf("Error getting flagged transactions: %v", err)
 		respondError(w, http.StatusInternalServerError, "Failed to fetch transactions")
@@ -63,39 +153,163 @@ func (h *Handler) GetFlaggedTransactions(w http.ResponseWriter, r *http.Request)
 
 // GetWalletAnalysis returns analysis for a specific wallet
 func (h *Handler) GetWalletAnalysis(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	address := vars["address"]
-
-	if address == "" {
-		respondError(w, http.StatusBadRequest, "Address is required")
+	wallet, ok := addressParam(w, r)
+	if !ok {
+		return
+	}
+	chainID, ok := chainFilter(w, r)
+	if !ok {
 		return
 	}
 
-	transactions, err := h.db.GetWalletTransactions(address, 100)
+	transactions, err := h.db.GetWalletTransactions(chainID, wallet, 100)
 	if err != nil {
 		log.Printf("Error getting wallet transactions: %v", err)
 		respondError(w, http.StatusInternalServerError, "Failed to fetch wallet data")
 		return
 	}
 
-	isBlacklisted, err := h.db.IsBlacklisted(address)
+	tokenTransfers, err := h.db.GetWalletTokenTransfers(chainID, wallet, 100)
+	if err != nil {
+		log.Printf("Error getting wallet token transfers: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to fetch wallet data")
+		return
+	}
+
+	isBlacklisted, err := h.db.IsBlacklisted(chainID, wallet)
 	if err != nil {
 		log.Printf("Error checking blacklist: %v", err)
 	}
 
 	response := map[string]interface{}{
-		"address":       address,
-		"transactions":  transactions,
-		"blacklisted":   isBlacklisted,
-		"tx_count":      len(transactions),
+		"address":         wallet,
+		"transactions":    transactions,
+		"token_transfers": tokenTransfers,
+		"blacklisted":     isBlacklisted,
+		"tx_count":        len(transactions),
 	}
 
 	respondJSON(w, http.StatusOK, response)
 }
 
+// GetSuspectedDrainers returns operators escalated to the suspected-drainer list
+func (h *Handler) GetSuspectedDrainers(w http.ResponseWriter, r *http.Request) {
+	limitStr := r.URL.Query().Get("limit")
+	limit := 50
+	if limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
+			limit = l
+		}
+	}
+
+	chainID, ok := chainFilter(w, r)
+	if !ok {
+		return
+	}
+
+	drainers, err := h.db.GetSuspectedDrainers(chainID, limit)
+	if err != nil {
+		log.Printf("Error getting suspected drainers: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to fetch suspected drainers")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, drainers)
+}
+
+// GetTokens returns the token metadata registry
+func (h *Handler) GetTokens(w http.ResponseWriter, r *http.Request) {
+	chainID, ok := chainFilter(w, r)
+	if !ok {
+		return
+	}
+
+	list, err := h.db.GetTokens(chainID)
+	if err != nil {
+		log.Printf("Error getting tokens: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to fetch tokens")
+		return
+	}
+
+	if list == nil {
+		list = []*models.Token{}
+	}
+	respondJSON(w, http.StatusOK, list)
+}
+
+// PutToken adds or updates a token's metadata
+func (h *Handler) PutToken(w http.ResponseWriter, r *http.Request) {
+	var token models.Token
+	if err := json.NewDecoder(r.Body).Decode(&token); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if tokenAddress, ok := mux.Vars(r)["address"]; ok {
+		parsed, err := address.Parse(tokenAddress)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		token.Address = parsed
+	}
+	if err := tokens.Validate(&token); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	c, ok := h.targetChain(w, r, token.ChainID)
+	if !ok {
+		return
+	}
+	token.ChainID = c.id
+
+	if err := h.db.UpsertToken(&token); err != nil {
+		log.Printf("Error saving token: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to save token")
+		return
+	}
+	if c.tokens != nil {
+		c.tokens.Put(&token)
+	}
+
+	respondJSON(w, http.StatusOK, token)
+}
+
+// DeleteToken removes a token from the registry
+func (h *Handler) DeleteToken(w http.ResponseWriter, r *http.Request) {
+	token, ok := addressParam(w, r)
+	if !ok {
+		return
+	}
+	c, ok := h.targetChain(w, r, 0)
+	if !ok {
+		return
+	}
+
+	deleted, err := h.db.DeleteToken(c.id, token)
+	if err != nil {
+		log.Printf("Error deleting token: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to delete token")
+		return
+	}
+	if !deleted {
+		respondError(w, http.StatusNotFound, "Token not found")
+		return
+	}
+	if c.tokens != nil {
+		c.tokens.Remove(token)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 // GetStatistics returns platform statistics
 func (h *Handler) GetStatistics(w http.ResponseWriter, r *http.Request) {
-	stats, err := h.db.GetStatistics()
+	chainID, ok := chainFilter(w, r)
+	if !ok {
+		return
+	}
+
+	stats, err := h.db.GetStatistics(chainID)
 	if err != nil {
 		log.Printf("Error getting statistics: %v", err)
 		respondError(w, http.StatusInternalServerError, "Failed to fetch statistics")
@@ -105,8 +319,22 @@ func (h *Handler) GetStatistics(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, stats)
 }
 
-// HandleWebSocket handles WebSocket connections
+// HandleWebSocket handles WebSocket connections; chain_id limits the stream
+// to a comma-separated list of chains and stream=watched to the alerts on
+// monitored wallets
 func (h *Handler) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
+	var chainIDs []int64
+	if value := r.URL.Query().Get("chain_id"); value != "" {
+		for _, part := range strings.Split(value, ",") {
+			id, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64)
+			if err != nil || id <= 0 {
+				respondError(w, http.StatusBadRequest, "Invalid chain_id")
+				return
+			}
+			chainIDs = append(chainIDs, id)
+		}
+	}
+
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Printf("WebSocket upgrade error: %v", err)
@@ -114,6 +342,10 @@ func (h *Handler) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 	}
 
 	client := ws.NewClient(h.hub, conn)
+	client.FilterChains(chainIDs)
+	if r.URL.Query().Get("stream") == "watched" {
+		client.FilterWatched()
+	}
 	h.hub.Register(client)
 
 	// Start client goroutines
@@ -122,6 +354,59 @@ func (h *Handler) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 }
 
 // Helper functions
+
+// chainFilter parses the optional chain_id query parameter; 0 means every
+// chain. It responds with 400 and returns false when the value is invalid.
+func chainFilter(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	value := r.URL.Query().Get("chain_id")
+	if value == "" {
+		return 0, true
+	}
+	chainID, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || chainID <= 0 {
+		respondError(w, http.StatusBadRequest, "Invalid chain_id")
+		return 0, false
+	}
+	return chainID, true
+}
+
+// addressParam parses the address path parameter, responding with 400 and
+// returning false when it is malformed
+func addressParam(w http.ResponseWriter, r *http.Request) (address.Address, bool) {
+	parsed, err := address.Parse(mux.Vars(r)["address"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return "", false
+	}
+	return parsed, true
+}
+
+// targetChain resolves the chain a write applies to from the request body or
+// the chain_id query parameter. It may be omitted when a single chain is
+// ingested.
+func (h *Handler) targetChain(w http.ResponseWriter, r *http.Request, bodyChainID int64) (*chain, bool) {
+	chainID, ok := chainFilter(w, r)
+	if !ok {
+		return nil, false
+	}
+	if chainID == 0 {
+		chainID = bodyChainID
+	}
+
+	if chainID == 0 {
+		if len(h.chains) != 1 {
+			respondError(w, http.StatusBadRequest, "chain_id is required")
+			return nil, false
+		}
+		return h.chains[0], true
+	}
+	c := h.findChain(chainID)
+	if c == nil {
+		respondError(w, http.StatusBadRequest, "Unknown chain_id")
+		return nil, false
+	}
+	return c, true
+}
 func respondJSON(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/minsix/backend/internal/address"
+	"github.com/minsix/backend/internal/database"
+	"github.com/minsix/backend/internal/models"
+	"github.com/minsix/backend/internal/watchlist"
+)
+
+// walletRequest is the body of monitored wallet writes; address and chain_id
+// are ignored on update
+type walletRequest struct {
+	ChainID *int64  `json:"chain_id"`
+	Address string  `json:"address"`
+	Label   *string `json:"label"`
+}
+
+// GetMonitoredWallets lists the monitored wallets, of one chain with chain_id
+func (h *Handler) GetMonitoredWallets(w http.ResponseWriter, r *http.Request) {
+	chainID, ok := chainFilter(w, r)
+	if !ok {
+		return
+	}
+
+	wallets, err := h.db.GetMonitoredWallets(chainID)
+	if err != nil {
+		log.Printf("Error listing monitored wallets: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to fetch monitored wallets")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, wallets)
+}
+
+// GetMonitoredWallet returns one monitored wallet
+func (h *Handler) GetMonitoredWallet(w http.ResponseWriter, r *http.Request) {
+	wallet, ok := h.monitoredWallet(w, r)
+	if !ok {
+		return
+	}
+	respondJSON(w, http.StatusOK, wallet)
+}
+
+// AddMonitoredWallet monitors a wallet on one chain, or every chain when
+// chain_id is omitted. Ingestion picks the change up through the database
+// and starts following the wallet.
+func (h *Handler) AddMonitoredWallet(w http.ResponseWriter, r *http.Request) {
+	var req walletRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	watched, err := address.Parse(req.Address)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	wallet := &models.MonitoredWallet{
+		ChainID: req.ChainID,
+		Address: watched,
+		Label:   req.Label,
+	}
+	if err := watchlist.Validate(wallet); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = h.db.AddMonitoredWallet(wallet)
+	if errors.Is(err, database.ErrWalletMonitored) {
+		respondError(w, http.StatusConflict, "Wallet is already monitored")
+		return
+	}
+	if err != nil {
+		log.Printf("Error adding monitored wallet: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to save monitored wallet")
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, wallet)
+}
+
+// UpdateMonitoredWallet replaces the label of a wallet
+func (h *Handler) UpdateMonitoredWallet(w http.ResponseWriter, r *http.Request) {
+	wallet, ok := h.monitoredWallet(w, r)
+	if !ok {
+		return
+	}
+	var req walletRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	wallet.Label = req.Label
+	if err := watchlist.Validate(wallet); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	updated, err := h.db.UpdateMonitoredWallet(wallet)
+	if err != nil {
+		log.Printf("Error updating monitored wallet: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to save monitored wallet")
+		return
+	}
+	if !updated {
+		respondError(w, http.StatusNotFound, "Monitored wallet not found")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, wallet)
+}
+
+// DeleteMonitoredWallet stops monitoring a wallet
+func (h *Handler) DeleteMonitoredWallet(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || id <= 0 {
+		respondError(w, http.StatusBadRequest, "Invalid monitored wallet ID")
+		return
+	}
+
+	deleted, err := h.db.DeleteMonitoredWallet(id)
+	if err != nil {
+		log.Printf("Error deleting monitored wallet: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to delete monitored wallet")
+		return
+	}
+	if !deleted {
+		respondError(w, http.StatusNotFound, "Monitored wallet not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// monitoredWallet loads the wallet named by the id path parameter, responding
+// with 400 or 404 and returning false when there is none
+func (h *Handler) monitoredWallet(w http.ResponseWriter, r *http.Request) (*models.MonitoredWallet, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || id <= 0 {
+		respondError(w, http.StatusBadRequest, "Invalid monitored wallet ID")
+		return nil, false
+	}
+
+	wallet, err := h.db.GetMonitoredWallet(id)
+	if err != nil {
+		log.Printf("Error getting monitored wallet: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to fetch monitored wallet")
+		return nil, false
+	}
+	if wallet == nil {
+		respondError(w, http.StatusNotFound, "Monitored wallet not found")
+		return nil, false
+	}
+	return wallet, true
+}
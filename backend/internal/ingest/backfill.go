@@ -0,0 +1,108 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/minsix/backend/internal/models"
+)
+
+// BlockFetchAttempts is how many times a block is fetched before it is reported as failed
+const BlockFetchAttempts = 3
+
+// retryDelay is multiplied by the attempt number between fetch attempts
+var retryDelay = time.Second
+
+// BlockFetcher loads the transactions of a block, implemented by ethereum.Client
+type BlockFetcher interface {
+	FetchBlock(ctx context.Context, number uint64) ([]*models.Transaction, error)
+}
+
+// fetchResult is a fetched block waiting to be handled in order
+type fetchResult struct {
+	number uint64
+	txs    []*models.Transaction
+	err    error
+}
+
+// Backfill fetches blocks from..to with up to concurrency requests in flight
+// and hands their transactions to handle strictly in block order, so
+// order-sensitive rules see the same sequence as live ingestion. It returns
+// the blocks that could not be fetched.
+func Backfill(ctx context.Context, fetcher BlockFetcher, from, to uint64, concurrency int, handle func(*models.Transaction)) ([]uint64, error) {
+	if from > to {
+		return nil, fmt.Errorf("invalid block range %d-%d", from, to)
+	}
+	if concurrency < 1 {
+		return nil, fmt.Errorf("concurrency must be at least 1")
+	}
+
+	// Each block gets its own result channel, queued in block order; the
+	// queue's capacity bounds how far fetching runs ahead of handling
+	ordered := make(chan chan fetchResult, concurrency)
+	go func() {
+		defer close(ordered)
+		sem := make(chan struct{}, concurrency)
+		for number := from; number <= to; number++ {
+			result := make(chan fetchResult, 1)
+			select {
+			case ordered <- result:
+			case <-ctx.Done():
+				return
+			}
+
+			sem <- struct{}{}
+			go func(number uint64) {
+				defer func() { <-sem }()
+				txs, err := fetchWithRetry(ctx, fetcher, number)
+				result <- fetchResult{number: number, txs: txs, err: err}
+			}(number)
+
+			if number == to {
+				return // avoid wrapping when to is the maximum uint64
+			}
+		}
+	}()
+
+	var failed []uint64
+	for result := range ordered {
+		r := <-result
+		if r.err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			log.Printf("Failed to backfill block %d: %v", r.number, r.err)
+			failed = append(failed, r.number)
+			continue
+		}
+		for _, tx := range r.txs {
+			handle(tx)
+		}
+		if r.number%100 == 0 {
+			log.Printf("Backfilled through block %d", r.number)
+		}
+	}
+
+	return failed, ctx.Err()
+}
+
+// fetchWithRetry retries transient fetch errors with a short linear backoff
+func fetchWithRetry(ctx context.Context, fetcher BlockFetcher, number uint64) ([]*models.Transaction, error) {
+	var err error
+	for attempt := 1; attempt <= BlockFetchAttempts; attempt++ {
+		var txs []*models.Transaction
+		if txs, err = fetcher.FetchBlock(ctx, number); err == nil {
+			return txs, nil
+		}
+		if attempt < BlockFetchAttempts {
+			select {
+			case <-time.After(time.Duration(attempt) * retryDelay):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+	}
+	return nil, err
+}
@@ -0,0 +1,78 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/minsix/backend/internal/models"
+)
+
+// fakeFetcher returns one transaction per block, finishing later blocks first
+type fakeFetcher struct {
+	mu       sync.Mutex
+	inFlight int
+	maxSeen  int
+	fail     uint64
+}
+
+func (f *fakeFetcher) FetchBlock(ctx context.Context, number uint64) ([]*models.Transaction, error) {
+	f.mu.Lock()
+	f.inFlight++
+	if f.inFlight > f.maxSeen {
+		f.maxSeen = f.inFlight
+	}
+	f.mu.Unlock()
+	defer func() {
+		f.mu.Lock()
+		f.inFlight--
+		f.mu.Unlock()
+	}()
+
+	time.Sleep(time.Duration(20-number%5*4) * time.Millisecond)
+	if number == f.fail {
+		return nil, errors.New("boom")
+	}
+	return []*models.Transaction{{BlockNumber: int64(number)}}, nil
+}
+
+func TestBackfillPreservesBlockOrder(t *testing.T) {
+	retryDelay = time.Millisecond
+	fetcher := &fakeFetcher{fail: 13}
+
+	var seen []int64
+	failed, err := Backfill(context.Background(), fetcher, 10, 19, 3, func(tx *models.Transaction) {
+		seen = append(seen, tx.BlockNumber)
+	})
+	if err != nil {
+		t.Fatalf("Backfill() error = %v", err)
+	}
+	if len(failed) != 1 || failed[0] != 13 {
+		t.Errorf("Backfill() failed = %v, want [13]", failed)
+	}
+
+	want := []int64{10, 11, 12, 14, 15, 16, 17, 18, 19}
+	if len(seen) != len(want) {
+		t.Fatalf("handled blocks %v, want %v", seen, want)
+	}
+	for i := range want {
+		if seen[i] != want[i] {
+			t.Fatalf("handled blocks %v, want %v", seen, want)
+		}
+	}
+	if fetcher.maxSeen > 3 {
+		t.Errorf("%d fetches in flight, want at most 3", fetcher.maxSeen)
+	}
+}
+
+func TestBackfillRejectsInvalidArguments(t *testing.T) {
+	handle := func(*models.Transaction) {}
+	if _, err := Backfill(context.Background(), &fakeFetcher{}, 5, 4, 1, handle); err == nil {
+		t.Error("Backfill() accepted an inverted range")
+	}
+	if _, err := Backfill(context.Background(), &fakeFetcher{}, 1, 2, 0, handle); err == nil {
+		t.Error("Backfill() accepted zero concurrency")
+	}
+}
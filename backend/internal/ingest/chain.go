@@ -0,0 +1,323 @@
+package ingest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/minsix/backend/internal/database"
+	"github.com/minsix/backend/internal/detector"
+	"github.com/minsix/backend/internal/ethereum"
+	"github.com/minsix/backend/internal/metrics"
+	"github.com/minsix/backend/internal/models"
+	"github.com/minsix/backend/internal/tokens"
+	"github.com/minsix/backend/internal/websocket"
+	"gopkg.in/yaml.v3"
+)
+
+// RulesWatchInterval is how often each chain checks the rules config for changes
+const RulesWatchInterval = 5 * time.Second
+
+// ChainConfig configures the ingestion of one chain
+type ChainConfig struct {
+	Name          string        `yaml:"name"`
+	ChainID       int64         `yaml:"chain_id"` // verified against the node when set
+	RPCURLs       []string      `yaml:"rpc_urls"`
+	BlockSource   string        `yaml:"block_source"`  // auto, subscribe or poll
+	PollInterval  time.Duration `yaml:"poll_interval"` // defaults to ethereum.DefaultPollInterval
+	Confirmations string        `yaml:"confirmations"` // block depth or "finalized"
+	EmitTentative *bool         `yaml:"emit_tentative"`
+	NativeSymbol  string        `yaml:"native_symbol"` // priced native currency, ETH by default
+	TokenList     string        `yaml:"token_list"`
+	TokenPrices   string        `yaml:"token_prices"`
+	Pending       bool          `yaml:"pending"` // analyze mempool transactions; needs a ws or ipc provider
+
+	// Pipeline sizes; zero uses the defaults
+	PersistWorkers int `yaml:"persist_workers"`
+	AnalyzeWorkers int `yaml:"analyze_workers"`
+	PipelineDepth  int `yaml:"pipeline_depth"`
+}
+
+// LoadChains reads the chains to ingest from a YAML file. ${VAR} references
+// are expanded from the environment so API keys can stay out of the file.
+func LoadChains(path string) ([]ChainConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chains config: %w", err)
+	}
+
+	var file struct {
+		Chains []ChainConfig `yaml:"chains"`
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader([]byte(os.ExpandEnv(string(data)))))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("failed to parse chains config: %w", err)
+	}
+
+	if len(file.Chains) == 0 {
+		return nil, fmt.Errorf("chains config %s lists no chains", path)
+	}
+	names := make(map[string]bool)
+	ids := make(map[int64]bool)
+	for i, chain := range file.Chains {
+		if err := chain.validate(); err != nil {
+			return nil, fmt.Errorf("chains[%d]: %w", i, err)
+		}
+		if names[chain.Name] {
+			return nil, fmt.Errorf("chains[%d]: duplicate name %q", i, chain.Name)
+		}
+		names[chain.Name] = true
+		if chain.ChainID != 0 {
+			if ids[chain.ChainID] {
+				return nil, fmt.Errorf("chains[%d]: duplicate chain_id %d", i, chain.ChainID)
+			}
+			ids[chain.ChainID] = true
+		}
+	}
+	return file.Chains, nil
+}
+
+func (c ChainConfig) validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(c.RPCURLs) == 0 {
+		return fmt.Errorf("chain %s: rpc_urls is required", c.Name)
+	}
+	switch c.BlockSource {
+	case "", ethereum.BlockSourceAuto, ethereum.BlockSourceSubscribe, ethereum.BlockSourcePoll:
+	default:
+		return fmt.Errorf("chain %s: unknown block_source %q", c.Name, c.BlockSource)
+	}
+	if c.PollInterval < 0 {
+		return fmt.Errorf("chain %s: poll_interval must be positive", c.Name)
+	}
+	if c.PersistWorkers < 0 || c.AnalyzeWorkers < 0 || c.PipelineDepth < 0 {
+		return fmt.Errorf("chain %s: pipeline sizes must not be negative", c.Name)
+	}
+	if _, err := ParseConfirmationPolicy(c.Confirmations, true); err != nil {
+		return fmt.Errorf("chain %s: %w", c.Name, err)
+	}
+	return nil
+}
+
+// Chain is the ingestion pipeline of one chain: its RPC client, a detector
+// with the chain's rule overrides and blacklist scope, its token registry
+// and a processor
+type Chain struct {
+	ID        int64
+	Name      string
+	Client    *ethereum.Client
+	Detector  *detector.FraudDetector
+	Tokens    *tokens.Registry
+	Processor *Processor
+	Pipeline  *Pipeline
+	Wallets   *WalletMonitor // nil until MonitorWallets is called
+
+	config ChainConfig
+	rules  *detector.ConfigWatcher
+}
+
+// OpenChain connects to a chain and prepares its pipeline, restoring the sync
+// checkpoint and recent blocks and registering the checkpoint and reorg
+// handlers. rulesPath may be empty; hub may be nil when nothing is broadcast.
+func OpenChain(db *database.DB, hub *websocket.Hub, cfg ChainConfig, rulesPath string) (*Chain, error) {
+	emitTentative := cfg.EmitTentative == nil || *cfg.EmitTentative
+	policy, err := ParseConfirmationPolicy(cfg.Confirmations, emitTentative)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := ethereum.Dial(cfg.RPCURLs)
+	if err != nil {
+		return nil, err
+	}
+	chainID := client.ChainID().Int64()
+	if cfg.ChainID != 0 && cfg.ChainID != chainID {
+		client.Close()
+		return nil, fmt.Errorf("chain %s: node reports chain ID %d, want %d", cfg.Name, chainID, cfg.ChainID)
+	}
+	if cfg.Name == "" {
+		cfg.Name = strconv.FormatInt(chainID, 10)
+	}
+
+	c := &Chain{ID: chainID, Name: cfg.Name, Client: client, config: cfg}
+	if err := c.setup(db, hub, policy, rulesPath); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("chain %s: %w", cfg.Name, err)
+	}
+	log.Printf("Chain %s (ID %d) ready, confirmation policy: %s", c.Name, c.ID, policy)
+	return c, nil
+}
+
+func (c *Chain) setup(db *database.DB, hub *websocket.Hub, policy ConfirmationPolicy, rulesPath string) error {
+	c.Detector = detector.NewFraudDetector(db)
+	c.Detector.SetChainID(c.ID)
+	if err := TrackSuspectedDrainers(db, c.Detector); err != nil {
+		return fmt.Errorf("failed to set up suspected drainers: %w", err)
+	}
+
+	registry, err := LoadTokenRegistry(db, c.Detector, c.config.TokenList, c.config.TokenPrices)
+	if err != nil {
+		return fmt.Errorf("failed to load token registry: %w", err)
+	}
+	if c.config.NativeSymbol != "" {
+		registry.SetNativeSymbol(c.config.NativeSymbol)
+	}
+	c.Tokens = registry
+
+	// Invalid rules are fatal at startup; later reloads keep the last good config
+	if rulesPath != "" {
+		c.rules, err = detector.NewConfigWatcher(rulesPath, c.Detector)
+		if err != nil {
+			return fmt.Errorf("failed to load rules config: %w", err)
+		}
+	}
+	c.Detector.SetContractInspector(c.Client)
+
+	c.Processor = NewProcessor(db, c.Detector, hub)
+	c.Processor.SetConfirmationPolicy(policy, c.Client)
+	if c.config.Pending {
+		c.Processor.EnablePending()
+	}
+
+	// Resume from the last fully processed block and checkpoint as blocks complete
+	lastBlock, synced, err := db.GetSyncState(c.ID)
+	if err != nil {
+		return fmt.Errorf("failed to load sync state: %w", err)
+	}
+	if synced {
+		log.Printf("Resuming chain %s after block %d", c.Name, lastBlock)
+		c.Client.ResumeFrom(lastBlock)
+	}
+	recentBlocks, err := db.GetRecentBlocks(c.ID, ethereum.MaxReorgDepth)
+	if err != nil {
+		return fmt.Errorf("failed to load recent blocks: %w", err)
+	}
+	c.Client.RememberBlocks(recentBlocks)
+
+	// Blocks are handed to the pipeline as they are fetched and checkpointed
+	// once published
+	stages := metrics.NewStages(PipelineStages...)
+	c.Client.SetStageMetrics(stages)
+	c.Pipeline = NewPipeline(c.Processor, PipelineConfig{
+		PersistWorkers: c.config.PersistWorkers,
+		AnalyzeWorkers: c.config.AnalyzeWorkers,
+		Depth:          c.config.PipelineDepth,
+	}, stages)
+	c.Client.OnBlockProcessed(c.Pipeline.EndBlock)
+	c.Pipeline.OnBlockDone(func(block *models.Block) {
+		if err := db.SaveBlock(block); err != nil {
+			log.Printf("Failed to save block: %v", err)
+		}
+		if err := db.SaveSyncState(c.ID, block.Number); err != nil {
+			log.Printf("Failed to save sync state: %v", err)
+		}
+		if block.Number > ethereum.MaxReorgDepth {
+			if err := db.PruneBlocks(c.ID, block.Number-ethereum.MaxReorgDepth); err != nil {
+				log.Printf("Failed to prune blocks: %v", err)
+			}
+		}
+		c.Processor.HandleBlock(block)
+	})
+
+	// Roll back orphaned blocks once the pipeline has finished with them; the
+	// canonical branch is then reprocessed
+	c.Client.OnReorg(func(ancestor uint64, orphaned []*models.Block) {
+		c.Pipeline.Drain()
+
+		// Forget what the detector learned from orphaned transactions before
+		// the canonical ones are analyzed
+		orphanedTxs, err := db.GetOrphanedTransactions(c.ID, orphaned)
+		if err != nil {
+			log.Printf("Failed to load orphaned transactions: %v", err)
+		}
+		c.Detector.ForgetTransactions(orphanedTxs)
+
+		txCount, flagCount, err := db.RollbackBlocks(c.ID, orphaned)
+		if err != nil {
+			log.Printf("Failed to roll back orphaned blocks: %v", err)
+		}
+		if err := db.SaveSyncState(c.ID, ancestor); err != nil {
+			log.Printf("Failed to save sync state: %v", err)
+		}
+		db.IncrementStatistic(c.ID, "total_transactions", -float64(txCount))
+		db.IncrementStatistic(c.ID, "total_flagged", -float64(flagCount))
+
+		log.Printf("WARNING: Reorg on chain %s after block %d rolled back %d transactions and %d flags", c.Name, ancestor, txCount, flagCount)
+		if hub != nil {
+			hub.BroadcastReorg(&models.ReorgPayload{
+				ChainID:        c.ID,
+				CommonAncestor: ancestor,
+				Orphaned:       orphaned,
+				RolledBackTxs:  txCount,
+				ReorgedFlags:   flagCount,
+				Timestamp:      time.Now(),
+			})
+		}
+	})
+	return nil
+}
+
+// Start follows new blocks and, when enabled, pending transactions, monitors
+// the RPC providers and watches the rules config until ctx is cancelled
+func (c *Chain) Start(ctx context.Context) error {
+	pollInterval := c.config.PollInterval
+	if pollInterval == 0 {
+		pollInterval = ethereum.DefaultPollInterval
+	}
+	c.Pipeline.Start(ctx)
+	if err := c.Client.StartBlocks(ctx, c.config.BlockSource, pollInterval, c.Pipeline.Submit); err != nil {
+		return fmt.Errorf("chain %s: failed to start block source: %w", c.Name, err)
+	}
+	if c.config.Pending {
+		if err := c.Client.SubscribePendingTransactions(ctx, c.Processor.HandlePending); err != nil {
+			return fmt.Errorf("chain %s: failed to follow pending transactions: %w", c.Name, err)
+		}
+		go c.Processor.AnalyzePending(ctx)
+		go c.Processor.WatchPending(ctx, PendingSweepInterval, c.Client)
+	}
+	go c.Client.MonitorProviders(ctx, ethereum.HealthCheckInterval)
+	if c.rules != nil {
+		go c.rules.Watch(ctx, RulesWatchInterval)
+	}
+	return nil
+}
+
+// MonitorWallets holds the chain's transactions involving monitored wallets
+// to the stricter watched-wallet threshold and follows the wallets through
+// subscriptions, starting and stopping them as the watchlist changes. What
+// they see is alerted on ahead of block ingestion through
+// Processor.HandleWatched. Call it before the watchlist starts watching for
+// changes.
+func (c *Chain) MonitorWallets(ctx context.Context, wallets Watchlist) {
+	c.Detector.SetWatchlist(wallets)
+	c.Wallets = NewWalletMonitor(c.Client, c.Processor.HandleWatched)
+	sync := func() {
+		c.Wallets.Sync(ctx, wallets.Wallets(c.ID))
+	}
+	wallets.OnChange(sync)
+	sync()
+}
+
+// PipelineStats returns the latency of each ingestion stage
+func (c *Chain) PipelineStats() []metrics.StageStats {
+	return c.Pipeline.Stats()
+}
+
+// ReloadRules re-reads the rules config, e.g. on SIGHUP
+func (c *Chain) ReloadRules() {
+	if c.rules != nil {
+		c.rules.Reload()
+	}
+}
+
+// Close disconnects from the chain's RPC providers
+func (c *Chain) Close() {
+	c.Client.Close()
+}
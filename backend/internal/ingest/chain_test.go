@@ -0,0 +1,70 @@
+package ingest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeChains(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "chains.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadChains(t *testing.T) {
+	t.Setenv("TEST_RPC_KEY", "secret")
+	path := writeChains(t, `
+chains:
+  - name: mainnet
+    chain_id: 1
+    rpc_urls: ["wss://eth.example/${TEST_RPC_KEY}"]
+    confirmations: "12"
+  - name: polygon
+    chain_id: 137
+    rpc_urls: ["https://polygon.example"]
+    block_source: poll
+    poll_interval: 2s
+    native_symbol: POL
+`)
+	chains, err := LoadChains(path)
+	if err != nil {
+		t.Fatalf("LoadChains() error = %v", err)
+	}
+	if len(chains) != 2 {
+		t.Fatalf("LoadChains() returned %d chains, want 2", len(chains))
+	}
+	if got := chains[0].RPCURLs[0]; got != "wss://eth.example/secret" {
+		t.Errorf("rpc url = %q, want the expanded key", got)
+	}
+	if chains[1].PollInterval != 2*time.Second || chains[1].NativeSymbol != "POL" {
+		t.Errorf("polygon = %+v", chains[1])
+	}
+}
+
+func TestLoadChainsInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"No chains", "chains: []"},
+		{"Missing name", "chains: [{rpc_urls: [ws://a]}]"},
+		{"Missing RPC", "chains: [{name: a}]"},
+		{"Unknown source", "chains: [{name: a, rpc_urls: [ws://a], block_source: websocket}]"},
+		{"Bad confirmations", "chains: [{name: a, rpc_urls: [ws://a], confirmations: safe}]"},
+		{"Duplicate name", "chains: [{name: a, rpc_urls: [ws://a]}, {name: a, rpc_urls: [ws://b]}]"},
+		{"Duplicate chain ID", "chains: [{name: a, chain_id: 1, rpc_urls: [ws://a]}, {name: b, chain_id: 1, rpc_urls: [ws://b]}]"},
+		{"Unknown field", "chains: [{name: a, rpc_urls: [ws://a], network: mainnet}]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadChains(writeChains(t, tt.content)); err == nil {
+				t.Error("LoadChains() accepted an invalid config")
+			}
+		})
+	}
+}
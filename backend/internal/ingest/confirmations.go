@@ -0,0 +1,117 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/minsix/backend/internal/models"
+)
+
+// ConfirmationPolicy decides when a transaction is confirmed. The zero value
+// confirms transactions on arrival.
+type ConfirmationPolicy struct {
+	Depth     uint64 // blocks including the transaction's own, 0 when unused
+	Finalized bool   // wait for the block to be tagged finalized
+	// EmitTentative sends an unconfirmed alert on arrival in addition to the
+	// confirmed alert; when false, alerts wait for confirmation
+	EmitTentative bool
+}
+
+// ParseConfirmationPolicy parses a depth such as "12" or the tag "finalized"
+func ParseConfirmationPolicy(value string, emitTentative bool) (ConfirmationPolicy, error) {
+	policy := ConfirmationPolicy{EmitTentative: emitTentative}
+	value = strings.TrimSpace(strings.ToLower(value))
+	switch value {
+	case "", "0", "1":
+		return ConfirmationPolicy{}, nil
+	case "finalized":
+		policy.Finalized = true
+		return policy, nil
+	}
+
+	depth, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return ConfirmationPolicy{}, fmt.Errorf("invalid confirmation policy %q: want a block depth or \"finalized\"", value)
+	}
+	policy.Depth = depth
+	return policy, nil
+}
+
+// Immediate reports whether transactions are confirmed as soon as they arrive
+func (p ConfirmationPolicy) Immediate() bool {
+	return p.Depth <= 1 && !p.Finalized
+}
+
+func (p ConfirmationPolicy) String() string {
+	switch {
+	case p.Finalized:
+		return "finalized"
+	case p.Immediate():
+		return "immediate"
+	default:
+		return fmt.Sprintf("%d confirmations", p.Depth)
+	}
+}
+
+// FinalitySource reports the latest finalized block, implemented by ethereum.Client
+type FinalitySource interface {
+	FinalizedBlock(ctx context.Context) (uint64, error)
+}
+
+// SetConfirmationPolicy makes new transactions tentative until the policy
+// confirms them; finality is only needed for the finalized policy
+func (p *Processor) SetConfirmationPolicy(policy ConfirmationPolicy, finality FinalitySource) {
+	p.policy = policy
+	p.finality = finality
+}
+
+// confirmedUpTo returns the highest block whose transactions are confirmed once head is processed
+func (p *Processor) confirmedUpTo(ctx context.Context, head uint64) (uint64, bool) {
+	if p.policy.Finalized {
+		if p.finality == nil {
+			return 0, false
+		}
+		finalized, err := p.finality.FinalizedBlock(ctx)
+		if err != nil {
+			log.Printf("Failed to get finalized block: %v", err)
+			return 0, false
+		}
+		return finalized, true
+	}
+	if head+1 < p.policy.Depth {
+		return 0, false
+	}
+	return head + 1 - p.policy.Depth, true
+}
+
+// HandleBlock confirms the tentative transactions that reached the policy's
+// depth or finality with head and emits their confirmed alerts
+func (p *Processor) HandleBlock(head *models.Block) {
+	if p.policy.Immediate() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	upTo, ok := p.confirmedUpTo(ctx, head.Number)
+	if !ok {
+		return
+	}
+
+	flags, err := p.db.ConfirmTransactions(head.ChainID, upTo)
+	if err != nil {
+		log.Printf("Failed to confirm transactions: %v", err)
+		return
+	}
+	for _, flagged := range flags {
+		if p.hub != nil {
+			p.broadcastAlert(flagged, true)
+		}
+		log.Printf("WARNING: Confirmed flagged transaction %s (Risk: %d)", flagged.TxHash, flagged.RiskScore)
+	}
+}
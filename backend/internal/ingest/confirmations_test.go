@@ -0,0 +1,66 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type fakeFinality struct {
+	block uint64
+	err   error
+}
+
+func (f fakeFinality) FinalizedBlock(ctx context.Context) (uint64, error) {
+	return f.block, f.err
+}
+
+func TestParseConfirmationPolicy(t *testing.T) {
+	tests := []struct {
+		value     string
+		want      ConfirmationPolicy
+		immediate bool
+	}{
+		{"", ConfirmationPolicy{}, true},
+		{"1", ConfirmationPolicy{}, true},
+		{"12", ConfirmationPolicy{Depth: 12, EmitTentative: true}, false},
+		{" Finalized ", ConfirmationPolicy{Finalized: true, EmitTentative: true}, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseConfirmationPolicy(tt.value, true)
+		if err != nil {
+			t.Fatalf("ParseConfirmationPolicy(%q) error = %v", tt.value, err)
+		}
+		if got != tt.want || got.Immediate() != tt.immediate {
+			t.Errorf("ParseConfirmationPolicy(%q) = %+v, want %+v", tt.value, got, tt.want)
+		}
+	}
+
+	for _, value := range []string{"safe", "-3", "12 blocks"} {
+		if _, err := ParseConfirmationPolicy(value, true); err == nil {
+			t.Errorf("ParseConfirmationPolicy(%q) should fail", value)
+		}
+	}
+}
+
+func TestConfirmedUpTo(t *testing.T) {
+	ctx := context.Background()
+
+	p := &Processor{policy: ConfirmationPolicy{Depth: 12}}
+	if _, ok := p.confirmedUpTo(ctx, 10); ok {
+		t.Error("confirmedUpTo() confirmed blocks before the chain is deep enough")
+	}
+	// Block 89 has 12 confirmations once block 100 is processed
+	if upTo, ok := p.confirmedUpTo(ctx, 100); !ok || upTo != 89 {
+		t.Errorf("confirmedUpTo(100) = %d, %v, want 89", upTo, ok)
+	}
+
+	p.SetConfirmationPolicy(ConfirmationPolicy{Finalized: true}, fakeFinality{block: 64})
+	if upTo, ok := p.confirmedUpTo(ctx, 100); !ok || upTo != 64 {
+		t.Errorf("confirmedUpTo() finalized = %d, %v, want 64", upTo, ok)
+	}
+	p.SetConfirmationPolicy(ConfirmationPolicy{Finalized: true}, fakeFinality{err: errors.New("unsupported")})
+	if _, ok := p.confirmedUpTo(ctx, 100); ok {
+		t.Error("confirmedUpTo() confirmed blocks without a finalized block")
+	}
+}
@@ -0,0 +1,308 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minsix/backend/internal/address"
+	"github.com/minsix/backend/internal/models"
+)
+
+const (
+	// MaxPendingAlerts caps how many pending alerts are tracked at once;
+	// risky transactions beyond it are not reported until mined
+	MaxPendingAlerts = 10000
+
+	// PendingSweepInterval is how often tracked alerts are checked for drops
+	PendingSweepInterval = time.Minute
+
+	// PendingCheckAfter is how long an alert waits to be mined before the
+	// node is asked whether its transaction is still in the mempool
+	PendingCheckAfter = 5 * time.Minute
+
+	// PendingExpireAfter is how long an alert may stay pending before it is
+	// reported as dropped regardless of the mempool
+	PendingExpireAfter = 3 * time.Hour
+
+	// PendingQueueSize bounds the mempool transactions waiting to be analyzed;
+	// transactions arriving while it is full are skipped
+	PendingQueueSize = 1024
+)
+
+// PendingLookup reports whether a transaction is unknown to the node, still
+// in the mempool or mined, implemented by ethereum.Client
+type PendingLookup interface {
+	PendingState(ctx context.Context, txHash string) (known, pending bool, err error)
+}
+
+// pendingTracker holds the pending alerts waiting for their transaction to be
+// mined, replaced or dropped
+type pendingTracker struct {
+	mu      sync.Mutex
+	alerts  map[string]*models.PendingAlert // lowercase tx hash -> alert
+	senders map[string][]string             // sender and nonce -> tx hashes
+}
+
+func newPendingTracker() *pendingTracker {
+	return &pendingTracker{
+		alerts:  make(map[string]*models.PendingAlert),
+		senders: make(map[string][]string),
+	}
+}
+
+func senderKey(from address.Address, nonce uint64) string {
+	return fmt.Sprintf("%s/%d", from, nonce)
+}
+
+// tracked reports whether an alert was already raised for the transaction
+func (t *pendingTracker) tracked(txHash string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, ok := t.alerts[strings.ToLower(txHash)]
+	return ok
+}
+
+// track starts tracking an alert; it returns false when the alert is already
+// tracked or the tracker is full
+func (t *pendingTracker) track(alert *models.PendingAlert) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	hash := strings.ToLower(alert.TxHash)
+	if _, ok := t.alerts[hash]; ok || len(t.alerts) >= MaxPendingAlerts {
+		return false
+	}
+	t.alerts[hash] = alert
+	key := senderKey(alert.FromAddress, alert.Nonce)
+	t.senders[key] = append(t.senders[key], hash)
+	return true
+}
+
+// resolve settles the alerts a mined transaction decides: its own alert is
+// mined and any other alert with the same sender and nonce was replaced
+func (t *pendingTracker) resolve(tx *models.Transaction, now time.Time) []*models.PendingAlert {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	minedHash := strings.ToLower(tx.TxHash)
+	hashes := []string{minedHash}
+	if tx.Nonce != nil {
+		hashes = t.senders[senderKey(tx.FromAddress, *tx.Nonce)]
+	}
+
+	var resolved []*models.PendingAlert
+	for _, hash := range hashes {
+		alert, ok := t.alerts[hash]
+		if !ok {
+			continue
+		}
+		t.remove(hash)
+		if hash == minedHash {
+			alert.Status = models.PendingStatusMined
+			alert.BlockNumber = tx.BlockNumber
+		} else {
+			alert.Status = models.PendingStatusReplaced
+			replacedBy := tx.TxHash
+			alert.ReplacedBy = &replacedBy
+		}
+		alert.ResolvedAt = &now
+		resolved = append(resolved, alert)
+	}
+	return resolved
+}
+
+// sweep returns the hashes of alerts due for a mempool check and removes the
+// alerts that have expired, reporting them as dropped
+func (t *pendingTracker) sweep(now time.Time) ([]string, []*models.PendingAlert) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var check []string
+	var dropped []*models.PendingAlert
+	for hash, alert := range t.alerts {
+		age := now.Sub(alert.SeenAt)
+		switch {
+		case age >= PendingExpireAfter:
+			t.remove(hash)
+			alert.Status = models.PendingStatusDropped
+			alert.ResolvedAt = &now
+			dropped = append(dropped, alert)
+		case age >= PendingCheckAfter:
+			check = append(check, hash)
+		}
+	}
+	return check, dropped
+}
+
+// drop reports a tracked alert as dropped; it returns nil if the alert was
+// resolved meanwhile
+func (t *pendingTracker) drop(txHash string, now time.Time) *models.PendingAlert {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	hash := strings.ToLower(txHash)
+	alert, ok := t.alerts[hash]
+	if !ok {
+		return nil
+	}
+	t.remove(hash)
+	alert.Status = models.PendingStatusDropped
+	alert.ResolvedAt = &now
+	return alert
+}
+
+// remove forgets an alert; the caller must hold t.mu
+func (t *pendingTracker) remove(hash string) {
+	alert := t.alerts[hash]
+	delete(t.alerts, hash)
+
+	key := senderKey(alert.FromAddress, alert.Nonce)
+	hashes := t.senders[key]
+	for i, h := range hashes {
+		if h == hash {
+			hashes = append(hashes[:i], hashes[i+1:]...)
+			break
+		}
+	}
+	if len(hashes) == 0 {
+		delete(t.senders, key)
+	} else {
+		t.senders[key] = hashes
+	}
+}
+
+// EnablePending makes the processor analyze mempool transactions passed to
+// HandlePending and reconcile their alerts with mined transactions
+func (p *Processor) EnablePending() {
+	p.pending = newPendingTracker()
+	p.pendingQueue = make(chan *models.Transaction, PendingQueueSize)
+}
+
+// HandlePending queues a mempool transaction for AnalyzePending. It never
+// blocks, so a slow analysis can't stall the subscription; transactions are
+// skipped while the queue is full.
+func (p *Processor) HandlePending(tx *models.Transaction) {
+	if p.pending == nil || tx.Nonce == nil {
+		return
+	}
+	select {
+	case p.pendingQueue <- tx:
+	default:
+		p.pendingSkipped.Add(1)
+	}
+}
+
+// AnalyzePending analyzes queued mempool transactions until ctx is cancelled
+func (p *Processor) AnalyzePending(ctx context.Context) {
+	if p.pending == nil {
+		return
+	}
+	for {
+		select {
+		case tx := <-p.pendingQueue:
+			p.analyzePending(tx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// analyzePending analyzes a mempool transaction and broadcasts a
+// pending_alert if it is risky. Nothing is stored; the alert is tracked until
+// the transaction is mined, replaced or dropped, which is broadcast as a
+// pending_update.
+func (p *Processor) analyzePending(tx *models.Transaction) {
+	if p.pending.tracked(tx.TxHash) {
+		return
+	}
+
+	flagged, err := p.detector.AnalyzeTransaction(tx)
+	if err != nil {
+		log.Printf("Failed to analyze pending transaction: %v", err)
+		return
+	}
+	if flagged == nil {
+		return
+	}
+
+	alert := &models.PendingAlert{
+		ChainID:       tx.ChainID,
+		TxHash:        tx.TxHash,
+		FromAddress:   tx.FromAddress,
+		ToAddress:     tx.ToAddress,
+		Nonce:         *tx.Nonce,
+		RiskScore:     flagged.RiskScore,
+		Reasons:       flagged.Reasons,
+		ReasonDetails: flagged.ReasonDetails,
+		Status:        models.PendingStatusPending,
+		SeenAt:        tx.Timestamp,
+	}
+	if !p.pending.track(alert) {
+		return
+	}
+
+	if p.hub != nil {
+		p.hub.BroadcastPendingAlert(alert)
+	}
+	log.Printf("WARNING: Flagged pending transaction %s (Risk: %d)", alert.TxHash, alert.RiskScore)
+}
+
+// reconcilePending resolves the pending alerts decided by a mined transaction
+func (p *Processor) reconcilePending(tx *models.Transaction) {
+	if p.pending == nil {
+		return
+	}
+	for _, alert := range p.pending.resolve(tx, time.Now()) {
+		p.publishPendingUpdate(alert)
+	}
+}
+
+// WatchPending reports pending alerts as dropped once the node no longer
+// knows their transaction or they expire. It blocks until ctx is cancelled.
+func (p *Processor) WatchPending(ctx context.Context, interval time.Duration, lookup PendingLookup) {
+	if p.pending == nil {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if skipped := p.pendingSkipped.Swap(0); skipped > 0 {
+				log.Printf("Skipped %d pending transactions while the analysis queue was full", skipped)
+			}
+			check, dropped := p.pending.sweep(time.Now())
+			for _, alert := range dropped {
+				p.publishPendingUpdate(alert)
+			}
+			for _, hash := range check {
+				known, _, err := lookup.PendingState(ctx, hash)
+				if err != nil {
+					log.Printf("Failed to check pending transaction %s: %v", hash, err)
+					continue
+				}
+				// Mined transactions are resolved when their block is processed
+				if known {
+					continue
+				}
+				if alert := p.pending.drop(hash, time.Now()); alert != nil {
+					p.publishPendingUpdate(alert)
+				}
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (p *Processor) publishPendingUpdate(alert *models.PendingAlert) {
+	log.Printf("Pending transaction %s %s", alert.TxHash, alert.Status)
+	if p.hub != nil {
+		p.hub.BroadcastPendingUpdate(alert)
+	}
+}
@@ -0,0 +1,95 @@
+package ingest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/minsix/backend/internal/address"
+	"github.com/minsix/backend/internal/models"
+)
+
+func pendingAlert(hash string, from address.Address, nonce uint64, seen time.Time) *models.PendingAlert {
+	return &models.PendingAlert{TxHash: hash, FromAddress: from, Nonce: nonce, Status: models.PendingStatusPending, SeenAt: seen}
+}
+
+func TestPendingTrackerResolve(t *testing.T) {
+	sender := address.FromHex("0xAbC0000000000000000000000000000000000001")
+	now := time.Now()
+	tracker := newPendingTracker()
+	if !tracker.track(pendingAlert("0xAA", sender, 7, now)) || !tracker.track(pendingAlert("0xbb", sender, 7, now)) {
+		t.Fatal("track() rejected a new alert")
+	}
+	if tracker.track(pendingAlert("0xaa", sender, 7, now)) {
+		t.Error("track() accepted a duplicate alert")
+	}
+	tracker.track(pendingAlert("0xcc", sender, 8, now))
+
+	// The speed-up with the same nonce is mined, replacing the original
+	nonce := uint64(7)
+	resolved := tracker.resolve(&models.Transaction{TxHash: "0xBB", FromAddress: "0xabc0000000000000000000000000000000000001", Nonce: &nonce, BlockNumber: 100}, now)
+	if len(resolved) != 2 {
+		t.Fatalf("resolve() = %d alerts, want 2", len(resolved))
+	}
+	for _, alert := range resolved {
+		switch alert.TxHash {
+		case "0xbb":
+			if alert.Status != models.PendingStatusMined || alert.BlockNumber != 100 {
+				t.Errorf("mined alert = %+v", alert)
+			}
+		case "0xAA":
+			if alert.Status != models.PendingStatusReplaced || alert.ReplacedBy == nil || *alert.ReplacedBy != "0xBB" {
+				t.Errorf("replaced alert = %+v", alert)
+			}
+		}
+	}
+	if tracker.tracked("0xaa") || tracker.tracked("0xbb") || !tracker.tracked("0xCC") {
+		t.Error("resolve() removed the wrong alerts")
+	}
+
+	// Transactions without a nonce resolve only their own alert
+	if resolved := tracker.resolve(&models.Transaction{TxHash: "0xcc", FromAddress: sender}, now); len(resolved) != 1 {
+		t.Errorf("resolve() without nonce = %d alerts, want 1", len(resolved))
+	}
+	if len(tracker.alerts) != 0 || len(tracker.senders) != 0 {
+		t.Errorf("tracker not empty: %d alerts, %d senders", len(tracker.alerts), len(tracker.senders))
+	}
+}
+
+func TestPendingTrackerSweep(t *testing.T) {
+	now := time.Now()
+	tracker := newPendingTracker()
+	tracker.track(pendingAlert("0x01", "0xa", 1, now))
+	tracker.track(pendingAlert("0x02", "0xa", 2, now.Add(-PendingCheckAfter)))
+	tracker.track(pendingAlert("0x03", "0xa", 3, now.Add(-PendingExpireAfter)))
+
+	check, dropped := tracker.sweep(now)
+	if len(check) != 1 || check[0] != "0x02" {
+		t.Errorf("sweep() check = %v, want [0x02]", check)
+	}
+	if len(dropped) != 1 || dropped[0].TxHash != "0x03" || dropped[0].Status != models.PendingStatusDropped {
+		t.Errorf("sweep() dropped = %+v, want 0x03", dropped)
+	}
+
+	if alert := tracker.drop("0x02", now); alert == nil || alert.Status != models.PendingStatusDropped {
+		t.Errorf("drop() = %+v", alert)
+	}
+	if alert := tracker.drop("0x02", now); alert != nil {
+		t.Error("drop() returned an alert that was already resolved")
+	}
+}
+
+func TestHandlePendingQueueFull(t *testing.T) {
+	p := &Processor{}
+	p.EnablePending()
+
+	nonce := uint64(1)
+	for i := 0; i <= PendingQueueSize; i++ {
+		p.HandlePending(&models.Transaction{TxHash: "0x01", Nonce: &nonce})
+	}
+	// Transactions without a nonce are not queued at all
+	p.HandlePending(&models.Transaction{TxHash: "0x02"})
+
+	if len(p.pendingQueue) != PendingQueueSize || p.pendingSkipped.Load() != 1 {
+		t.Errorf("queued %d, skipped %d; want %d queued, 1 skipped", len(p.pendingQueue), p.pendingSkipped.Load(), PendingQueueSize)
+	}
+}
@@ -0,0 +1,308 @@
+package ingest
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/minsix/backend/internal/address"
+	"github.com/minsix/backend/internal/metrics"
+	"github.com/minsix/backend/internal/models"
+)
+
+// Stages of live ingestion; fetch and convert run in the ethereum client
+var PipelineStages = []string{"fetch", "convert", "persist", "analyze", "publish"}
+
+// Default pipeline sizes
+const (
+	DefaultPersistWorkers = 8
+	DefaultAnalyzeWorkers = 4
+
+	// DefaultPipelineDepth is how many blocks may wait between two stages
+	// before the block source is held back
+	DefaultPipelineDepth = 4
+)
+
+// PipelineConfig sizes the worker pools and queues of a Pipeline; zero
+// values use the defaults
+type PipelineConfig struct {
+	PersistWorkers int // blocks inserted at once
+	AnalyzeWorkers int
+	Depth          int
+}
+
+// stageProcessor is the work of each stage, implemented by Processor
+type stageProcessor interface {
+	persistBlock(txs []*models.Transaction) []bool
+	reconcilePending(tx *models.Transaction)
+	flag(tx *models.Transaction) *models.FlaggedTransaction
+	publish(tx *models.Transaction, flagged *models.FlaggedTransaction)
+	count(chainID int64, stored, flagged int)
+}
+
+// blockBatch is a block moving through the pipeline with its transactions
+type blockBatch struct {
+	block   *models.Block
+	txs     []*models.Transaction
+	stored  []bool // txs newly stored by persist
+	flagged []*models.FlaggedTransaction
+}
+
+// Pipeline processes blocks through the persist, analyze and publish stages,
+// each working on a different block at a time. Up to PersistWorkers blocks
+// are bulk inserted at once, each in a single database transaction so a block
+// is stored whole or not at all. Within a block, transactions are analyzed by
+// workers sharded by sender, so each sender's transactions are analyzed in
+// order. Blocks leave every stage in order; bounded queues between the stages
+// hold the block source back when a stage falls behind.
+type Pipeline struct {
+	processor stageProcessor
+	config    PipelineConfig
+	stages    *metrics.Stages
+	onBlock   func(*models.Block)
+
+	mu      sync.Mutex
+	current []*models.Transaction // submitted for the block being handed over
+
+	senders []sync.Mutex // held while analyzing each sender shard
+
+	persistQ, analyzeQ, publishQ chan *blockBatch
+	inFlight                     sync.WaitGroup
+	ctx                          context.Context
+}
+
+// NewPipeline creates a pipeline around a processor; stages may be shared
+// with the ethereum client to report fetch and convert latency as well
+func NewPipeline(processor *Processor, config PipelineConfig, stages *metrics.Stages) *Pipeline {
+	return newPipeline(processor, config, stages)
+}
+
+func newPipeline(processor stageProcessor, config PipelineConfig, stages *metrics.Stages) *Pipeline {
+	if config.PersistWorkers < 1 {
+		config.PersistWorkers = DefaultPersistWorkers
+	}
+	if config.AnalyzeWorkers < 1 {
+		config.AnalyzeWorkers = DefaultAnalyzeWorkers
+	}
+	if config.Depth < 1 {
+		config.Depth = DefaultPipelineDepth
+	}
+	if stages == nil {
+		stages = metrics.NewStages(PipelineStages...)
+	}
+	return &Pipeline{
+		processor: processor,
+		config:    config,
+		stages:    stages,
+		senders:   make([]sync.Mutex, config.AnalyzeWorkers),
+		persistQ:  make(chan *blockBatch, config.Depth),
+		analyzeQ:  make(chan *blockBatch, config.Depth),
+		publishQ:  make(chan *blockBatch, config.Depth),
+	}
+}
+
+// OnBlockDone registers a callback invoked, in block order, once every
+// transaction of a block has been published, e.g. to checkpoint progress
+func (p *Pipeline) OnBlockDone(fn func(*models.Block)) {
+	p.onBlock = fn
+}
+
+// Start runs the stages until ctx is cancelled; blocks still in the pipeline
+// then are not checkpointed and are processed again after a restart
+func (p *Pipeline) Start(ctx context.Context) {
+	p.ctx = ctx
+	go p.run(ctx, p.persistQ, p.analyzeQ, "persist", p.config.PersistWorkers, p.persistBatch)
+	go p.run(ctx, p.analyzeQ, p.publishQ, "analyze", 1, p.analyzeBatch)
+	go p.run(ctx, p.publishQ, nil, "publish", 1, p.publishBatch)
+}
+
+// Submit adds a transaction to the block being handed over, for use as the
+// block source's transaction handler
+func (p *Pipeline) Submit(tx *models.Transaction) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.current = append(p.current, tx)
+}
+
+// EndBlock sends the submitted transactions into the pipeline as one block,
+// waiting while the persist queue is full
+func (p *Pipeline) EndBlock(block *models.Block) {
+	p.mu.Lock()
+	batch := &blockBatch{block: block, txs: p.current}
+	p.current = nil
+	p.mu.Unlock()
+
+	p.inFlight.Add(1)
+	select {
+	case p.persistQ <- batch:
+	case <-p.ctx.Done():
+		p.inFlight.Done()
+	}
+}
+
+// Drain waits until every block sent into the pipeline has been published,
+// e.g. before rolling blocks back, or until the pipeline is stopped
+func (p *Pipeline) Drain() {
+	drained := make(chan struct{})
+	go func() {
+		p.inFlight.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-p.ctx.Done():
+	}
+}
+
+// Stats returns the latency of every stage and the blocks queued before each
+func (p *Pipeline) Stats() []metrics.StageStats {
+	stats := p.stages.Snapshot()
+	for i := range stats {
+		switch stats[i].Stage {
+		case "persist":
+			stats[i].Queued = len(p.persistQ)
+		case "analyze":
+			stats[i].Queued = len(p.analyzeQ)
+		case "publish":
+			stats[i].Queued = len(p.publishQ)
+		}
+	}
+	return stats
+}
+
+// run feeds the batches of one stage through fn on up to workers blocks at
+// once, passing them on in the order they arrived
+func (p *Pipeline) run(ctx context.Context, in, out chan *blockBatch, stage string, workers int, fn func(*blockBatch)) {
+	slots := make(chan struct{}, workers)
+	ordered := make(chan chan *blockBatch, workers) // results in arrival order
+	go p.forward(ctx, ordered, out)
+
+	for {
+		select {
+		case batch := <-in:
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			result := make(chan *blockBatch, 1)
+			select {
+			case ordered <- result:
+			case <-ctx.Done():
+				return
+			}
+
+			go func() {
+				start := time.Now()
+				fn(batch)
+				p.stages.Observe(stage, time.Since(start), len(batch.txs))
+				<-slots
+				result <- batch
+			}()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// forward passes the batches of a stage on to the next one as each finishes,
+// in order, or marks them done after the last stage
+func (p *Pipeline) forward(ctx context.Context, ordered chan chan *blockBatch, out chan *blockBatch) {
+	for {
+		var batch *blockBatch
+		select {
+		case result := <-ordered:
+			select {
+			case batch = <-result:
+			case <-ctx.Done():
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+
+		if out == nil {
+			p.inFlight.Done()
+			continue
+		}
+		select {
+		case out <- batch:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// persistBatch stores a block's transactions in one database transaction
+func (p *Pipeline) persistBatch(batch *blockBatch) {
+	batch.stored = make([]bool, len(batch.txs))
+	if len(batch.txs) == 0 {
+		return
+	}
+	copy(batch.stored, p.processor.persistBlock(batch.txs))
+}
+
+func (p *Pipeline) analyzeBatch(batch *blockBatch) {
+	batch.flagged = make([]*models.FlaggedTransaction, len(batch.txs))
+
+	// Shard by sender so each sender's transactions keep their block order
+	shards := make([][]int, p.config.AnalyzeWorkers)
+	for i, tx := range batch.txs {
+		if !batch.stored[i] {
+			continue
+		}
+		shard := senderShard(tx.FromAddress, len(shards))
+		shards[shard] = append(shards[shard], i)
+	}
+
+	var wg sync.WaitGroup
+	for s, shard := range shards {
+		if len(shard) == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func(lock *sync.Mutex, indexes []int) {
+			defer wg.Done()
+			lock.Lock()
+			defer lock.Unlock()
+			for _, i := range indexes {
+				batch.flagged[i] = p.processor.flag(batch.txs[i])
+			}
+		}(&p.senders[s], shard)
+	}
+	wg.Wait()
+}
+
+// publishBatch resolves the pending alerts decided by a block and publishes
+// its transactions. Blocks are published one at a time in order, so alerts
+// resolve in block order even though transactions are persisted concurrently.
+func (p *Pipeline) publishBatch(batch *blockBatch) {
+	for _, tx := range batch.txs {
+		p.processor.reconcilePending(tx)
+	}
+
+	stored, flagged := 0, 0
+	for i, tx := range batch.txs {
+		if !batch.stored[i] {
+			continue
+		}
+		stored++
+		if batch.flagged[i] != nil {
+			flagged++
+		}
+		p.processor.publish(tx, batch.flagged[i])
+	}
+
+	p.processor.count(batch.block.ChainID, stored, flagged)
+	if p.onBlock != nil {
+		p.onBlock(batch.block)
+	}
+}
+
+// senderShard maps a sender to one of n shards
+func senderShard(sender address.Address, n int) int {
+	h := fnv.New32a()
+	h.Write([]byte(sender))
+	return int(h.Sum32() % uint32(n))
+}
@@ -0,0 +1,204 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/minsix/backend/internal/address"
+	"github.com/minsix/backend/internal/models"
+)
+
+// fakeStages records the work of each stage
+type fakeStages struct {
+	mu        sync.Mutex
+	analyzed  map[address.Address][]string // sender -> tx hashes in analysis order
+	resolved  []string                     // tx hashes in reconciliation order
+	published []string
+	stored    int
+	flagged   int
+	persisted []int // sizes of the persisted blocks
+}
+
+func (f *fakeStages) persistBlock(txs []*models.Transaction) []bool {
+	// Larger blocks take longer, so later small blocks finish first
+	time.Sleep(time.Millisecond + time.Duration(len(txs))*20*time.Microsecond)
+	f.mu.Lock()
+	f.persisted = append(f.persisted, len(txs))
+	f.mu.Unlock()
+
+	stored := make([]bool, len(txs))
+	for i, tx := range txs {
+		stored[i] = !strings.HasSuffix(tx.TxHash, "-dup")
+	}
+	return stored
+}
+
+func (f *fakeStages) reconcilePending(tx *models.Transaction) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.resolved = append(f.resolved, tx.TxHash)
+}
+
+func (f *fakeStages) flag(tx *models.Transaction) *models.FlaggedTransaction {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.analyzed[tx.FromAddress] = append(f.analyzed[tx.FromAddress], tx.TxHash)
+	if strings.HasSuffix(tx.TxHash, "-risky") {
+		return &models.FlaggedTransaction{TxHash: tx.TxHash}
+	}
+	return nil
+}
+
+func (f *fakeStages) publish(tx *models.Transaction, flagged *models.FlaggedTransaction) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.published = append(f.published, tx.TxHash)
+}
+
+func (f *fakeStages) count(chainID int64, stored, flagged int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.stored += stored
+	f.flagged += flagged
+}
+
+func TestPipeline(t *testing.T) {
+	fake := &fakeStages{analyzed: make(map[address.Address][]string)}
+	p := newPipeline(fake, PipelineConfig{PersistWorkers: 4, AnalyzeWorkers: 3, Depth: 1}, nil)
+
+	var done []uint64
+	p.OnBlockDone(func(block *models.Block) { done = append(done, block.Number) })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p.Start(ctx)
+
+	senders := []address.Address{"0xaaa", "0xbbb", "0xccc", "0xddd", "0xeee"}
+	var want, submitted []string
+	for number := uint64(1); number <= 5; number++ {
+		for i := 0; i < 10; i++ {
+			hash := fmt.Sprintf("%d-%d", number, i)
+			switch {
+			case i == 3:
+				hash += "-risky"
+			case i == 7:
+				hash += "-dup"
+			}
+			if i != 7 {
+				want = append(want, hash)
+			}
+			submitted = append(submitted, hash)
+			p.Submit(&models.Transaction{TxHash: hash, FromAddress: senders[i%len(senders)]})
+		}
+		p.EndBlock(&models.Block{Number: number})
+	}
+	p.Drain()
+
+	if fmt.Sprint(done) != "[1 2 3 4 5]" {
+		t.Errorf("blocks done = %v, want in order", done)
+	}
+	if strings.Join(fake.published, ",") != strings.Join(want, ",") {
+		t.Errorf("published = %v, want block order without duplicates", fake.published)
+	}
+	if strings.Join(fake.resolved, ",") != strings.Join(submitted, ",") {
+		t.Errorf("reconciled = %v, want block order", fake.resolved)
+	}
+	if fake.stored != 45 || fake.flagged != 5 {
+		t.Errorf("counted %d stored and %d flagged, want 45 and 5", fake.stored, fake.flagged)
+	}
+
+	// Each sender's transactions are analyzed in submission order
+	for sender, hashes := range fake.analyzed {
+		var expected []string
+		for _, hash := range want {
+			var number, i int
+			fmt.Sscanf(hash, "%d-%d", &number, &i)
+			if senders[i%len(senders)] == sender {
+				expected = append(expected, hash)
+			}
+		}
+		if strings.Join(hashes, ",") != strings.Join(expected, ",") {
+			t.Errorf("sender %s analyzed %v, want %v", sender, hashes, expected)
+		}
+	}
+
+	stats := p.Stats()
+	for _, stat := range stats {
+		if stat.Stage == "persist" && (stat.Runs != 5 || stat.Items != 50) {
+			t.Errorf("persist stats = %+v, want 5 runs over 50 items", stat)
+		}
+	}
+}
+
+func TestPipelinePersistWholeBlocks(t *testing.T) {
+	fake := &fakeStages{analyzed: make(map[address.Address][]string)}
+	p := newPipeline(fake, PipelineConfig{PersistWorkers: 4}, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p.Start(ctx)
+
+	for i := 0; i < 230; i++ {
+		hash := fmt.Sprintf("1-%d", i)
+		if i%10 == 0 {
+			hash += "-dup"
+		}
+		p.Submit(&models.Transaction{TxHash: hash, FromAddress: "0xAAA"})
+	}
+	p.EndBlock(&models.Block{Number: 1})
+	p.Submit(&models.Transaction{TxHash: "2-0", FromAddress: "0xAAA"})
+	p.EndBlock(&models.Block{Number: 2})
+	p.Drain()
+
+	// Block 2 is stored first but still published after block 1
+	if fmt.Sprint(fake.persisted) != "[1 230]" {
+		t.Errorf("persisted blocks of %v transactions, want each block in one call", fake.persisted)
+	}
+	if last := fake.published[len(fake.published)-1]; last != "2-0" {
+		t.Errorf("last published %s, want block 2 after block 1", last)
+	}
+	if fake.stored != 208 {
+		t.Errorf("counted %d stored, want 208", fake.stored)
+	}
+	if len(fake.analyzed["0xAAA"]) != 208 {
+		t.Errorf("analyzed %d transactions, want 208", len(fake.analyzed["0xAAA"]))
+	}
+}
+
+func TestPipelineDrainStopped(t *testing.T) {
+	fake := &fakeStages{analyzed: make(map[address.Address][]string)}
+	p := newPipeline(fake, PipelineConfig{}, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.Start(ctx)
+	cancel()
+
+	// A block sent just before the stop is never published
+	p.inFlight.Add(1)
+
+	drained := make(chan struct{})
+	go func() {
+		p.Drain()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-time.After(time.Second):
+		t.Fatal("Drain() blocked after the pipeline stopped")
+	}
+}
+
+func TestSenderShard(t *testing.T) {
+	if senderShard(address.FromHex("0xAbCd"), 7) != senderShard("0xabcd", 7) {
+		t.Error("senderShard() depends on address case")
+	}
+	for _, sender := range []address.Address{"0x1", "0x2", "0x3"} {
+		if shard := senderShard(sender, 4); shard < 0 || shard >= 4 {
+			t.Errorf("senderShard(%q) = %d, out of range", sender, shard)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package ingest
+
+import (
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/minsix/backend/internal/database"
+	"github.com/minsix/backend/internal/detector"
+	"github.com/minsix/backend/internal/models"
+	"github.com/minsix/backend/internal/websocket"
+)
+
+// Processor persists, analyzes and publishes ingested transactions
+type Processor struct {
+	db       *database.DB
+	detector *detector.FraudDetector
+	hub      *websocket.Hub // nil when nothing is broadcast, e.g. during backfill
+	policy   ConfirmationPolicy
+	finality FinalitySource
+	pending  *pendingTracker // nil unless mempool transactions are analyzed
+
+	pendingQueue   chan *models.Transaction
+	pendingSkipped atomic.Int64 // mempool transactions skipped since the last sweep
+}
+
+func NewProcessor(db *database.DB, fd *detector.FraudDetector, hub *websocket.Hub) *Processor {
+	return &Processor{
+		db:       db,
+		detector: fd,
+		hub:      hub,
+	}
+}
+
+// HandleTransaction saves a transaction with its logs and token transfers,
+// flags it if it is risky and broadcasts the result. Transactions that are
+// already stored are skipped, so replaying a block range does not duplicate flags.
+// Under a confirmation policy the transaction is stored as tentative and its
+// alert is held back or sent unconfirmed until HandleBlock confirms it.
+// Live ingestion runs the same steps concurrently through a Pipeline.
+func (p *Processor) HandleTransaction(tx *models.Transaction) {
+	stored := p.persist(tx)
+	p.reconcilePending(tx)
+	if !stored {
+		return
+	}
+	flagged := p.flag(tx)
+	flagCount := 0
+	if flagged != nil {
+		flagCount = 1
+	}
+	p.count(tx.ChainID, 1, flagCount)
+	p.publish(tx, flagged)
+}
+
+// HandleWatched raises the watched-wallet alert on a monitored wallet's
+// transaction seen ahead of block ingestion, such as by a WalletMonitor. The
+// transaction is only previewed: it is neither stored nor recorded in the
+// detector's order-sensitive history, and its spenders are not looked up, so
+// block ingestion still persists, analyzes and alerts on it in block order.
+// The early alert is unconfirmed; a reorg leaves nothing to roll back.
+// Transactions block ingestion already stored are skipped.
+func (p *Processor) HandleWatched(tx *models.Transaction) {
+	if p.hub == nil {
+		return
+	}
+	stored, err := p.db.HasTransaction(tx.ChainID, tx.TxHash)
+	if err != nil {
+		log.Printf("Failed to look up monitored wallet transaction: %v", err)
+	}
+	if stored {
+		return
+	}
+
+	flagged, err := p.detector.PreviewTransaction(tx)
+	if err != nil {
+		log.Printf("Failed to analyze monitored wallet transaction: %v", err)
+		return
+	}
+	if flagged == nil || len(flagged.WatchedWallets) == 0 {
+		return
+	}
+	flagged.FlaggedAt = time.Now()
+	p.hub.BroadcastWatchedAlert(flagged, p.detector.MonitoredWallets(flagged.WatchedWallets), false)
+	log.Printf("WARNING: Flagged monitored wallet transaction %s ahead of its block (Risk: %d)", flagged.TxHash, flagged.RiskScore)
+}
+
+// persist saves a transaction with its logs and token transfers. It returns
+// false unless the transaction was newly stored.
+func (p *Processor) persist(tx *models.Transaction) bool {
+	tx.Finality = models.FinalityConfirmed
+	if !p.policy.Immediate() {
+		tx.Finality = models.FinalityTentative
+	}
+
+	if err := p.db.SaveTransaction(tx); err != nil {
+		log.Printf("Failed to save transaction: %v", err)
+		return false
+	}
+	if tx.ID == 0 {
+		return false
+	}
+	if err := p.db.SaveTransactionLogs(tx); err != nil {
+		log.Printf("Failed to save transaction logs: %v", err)
+	}
+	if err := p.db.SaveTokenTransfers(tx); err != nil {
+		log.Printf("Failed to save token transfers: %v", err)
+	}
+	return true
+}
+
+// persistBlock saves a block's transactions in one database transaction and
+// reports which were newly stored. If the bulk insert fails, the transactions
+// are saved one by one so a single bad row doesn't lose the rest.
+func (p *Processor) persistBlock(txs []*models.Transaction) []bool {
+	finality := models.FinalityConfirmed
+	if !p.policy.Immediate() {
+		finality = models.FinalityTentative
+	}
+	for _, tx := range txs {
+		tx.Finality = finality
+	}
+
+	stored := make([]bool, len(txs))
+	if err := p.db.SaveTransactions(txs); err != nil {
+		log.Printf("Failed to save %d transactions in bulk, saving them one by one: %v", len(txs), err)
+		for i, tx := range txs {
+			stored[i] = p.persist(tx)
+		}
+		return stored
+	}
+	for i, tx := range txs {
+		stored[i] = tx.ID != 0
+	}
+	return stored
+}
+
+// flag analyzes a stored transaction and saves the flag if it is risky,
+// recording when the monitored wallets it involves were last checked
+func (p *Processor) flag(tx *models.Transaction) *models.FlaggedTransaction {
+	if watched := p.detector.WatchedWallets(tx); len(watched) > 0 {
+		ids := make([]int, len(watched))
+		for i, wallet := range watched {
+			ids[i] = wallet.ID
+		}
+		if err := p.db.TouchMonitoredWallets(ids); err != nil {
+			log.Printf("Failed to record monitored wallet activity: %v", err)
+		}
+	}
+
+	flagged, err := p.detector.AnalyzeTransaction(tx)
+	if err != nil {
+		log.Printf("Failed to analyze transaction: %v", err)
+		return nil
+	}
+	if flagged == nil {
+		return nil
+	}
+
+	if err := p.db.FlagTransaction(flagged); err != nil {
+		log.Printf("Failed to flag transaction: %v", err)
+		return nil
+	}
+	log.Printf("WARNING: Flagged transaction %s (Risk: %d)", flagged.TxHash, flagged.RiskScore)
+	return flagged
+}
+
+// count adds a block's newly stored and flagged transactions to the statistics
+func (p *Processor) count(chainID int64, stored, flagged int) {
+	if stored > 0 {
+		p.db.IncrementStatistic(chainID, "total_transactions", float64(stored))
+	}
+	if flagged > 0 {
+		p.db.IncrementStatistic(chainID, "total_flagged", float64(flagged))
+	}
+}
+
+// publish broadcasts a stored transaction and its alert, if any
+func (p *Processor) publish(tx *models.Transaction, flagged *models.FlaggedTransaction) {
+	if p.hub == nil {
+		return
+	}
+
+	confirmed := p.policy.Immediate()
+	if flagged != nil && (confirmed || p.policy.EmitTentative) {
+		p.broadcastAlert(flagged, confirmed)
+	}
+	p.hub.BroadcastTransaction(tx)
+}
+
+// broadcastAlert sends a flag's alert, and for flags involving monitored
+// wallets also its watched-wallet alert
+func (p *Processor) broadcastAlert(flagged *models.FlaggedTransaction, confirmed bool) {
+	p.hub.BroadcastAlert(flagged, confirmed)
+	if len(flagged.WatchedWallets) > 0 {
+		p.hub.BroadcastWatchedAlert(flagged, p.detector.MonitoredWallets(flagged.WatchedWallets), confirmed)
+	}
+}
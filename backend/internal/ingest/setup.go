@@ -0,0 +1,72 @@
+package ingest
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/minsix/backend/internal/address"
+	"github.com/minsix/backend/internal/database"
+	"github.com/minsix/backend/internal/detector"
+	"github.com/minsix/backend/internal/models"
+	"github.com/minsix/backend/internal/tokens"
+)
+
+// TrackSuspectedDrainers restores the suspected-drainer list of the detector's
+// chain and persists new escalations
+func TrackSuspectedDrainers(db *database.DB, fd *detector.FraudDetector) error {
+	drainers, err := db.GetSuspectedDrainers(fd.ChainID(), 100000)
+	if err != nil {
+		return fmt.Errorf("failed to load suspected drainers: %w", err)
+	}
+	addresses := make([]address.Address, 0, len(drainers))
+	for _, d := range drainers {
+		addresses = append(addresses, d.Address)
+	}
+	fd.LoadSuspectedDrainers(addresses)
+
+	fd.OnDrainerSuspected(func(drainer *models.SuspectedDrainer) {
+		if err := db.SaveSuspectedDrainer(drainer); err != nil {
+			log.Printf("Failed to save suspected drainer: %v", err)
+			return
+		}
+		log.Printf("WARNING: Suspected drainer %s (%d victims)", drainer.Address, drainer.VictimCount)
+	})
+	return nil
+}
+
+// LoadTokenRegistry seeds the tokens of the detector's chain from listPath,
+// if set, and loads them into a registry priced from pricesPath. The detector
+// values transfers in USD only when a price file is given.
+func LoadTokenRegistry(db *database.DB, fd *detector.FraudDetector, listPath, pricesPath string) (*tokens.Registry, error) {
+	var prices tokens.PriceSource
+	if pricesPath != "" {
+		staticPrices, err := tokens.LoadStaticPrices(pricesPath)
+		if err != nil {
+			return nil, err
+		}
+		prices = staticPrices
+		log.Printf("Loaded token prices from %s", pricesPath)
+	}
+
+	if listPath != "" {
+		bundled, err := tokens.LoadFile(listPath)
+		if err != nil {
+			return nil, err
+		}
+		if err := db.SeedTokens(fd.ChainID(), bundled); err != nil {
+			return nil, err
+		}
+	}
+
+	list, err := db.GetTokens(fd.ChainID())
+	if err != nil {
+		return nil, err
+	}
+
+	registry := tokens.NewRegistry(prices)
+	registry.Load(list)
+	if prices != nil {
+		fd.SetTokenValuer(registry)
+	}
+	return registry, nil
+}
@@ -0,0 +1,142 @@
+package ingest
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/minsix/backend/internal/address"
+	"github.com/minsix/backend/internal/models"
+)
+
+// AddressMonitor follows the transactions of addresses until ctx is
+// cancelled, then calls onDone; implemented by ethereum.Client.
+// MonitorAddress follows the logs of one address, MonitorTransfers the
+// transactions sent by or to any watched address that emit no such logs.
+type AddressMonitor interface {
+	MonitorAddress(ctx context.Context, addr address.Address, txHandler func(*models.Transaction), onDone func()) error
+	MonitorTransfers(ctx context.Context, watched func(address.Address) bool, txHandler func(*models.Transaction), onDone func()) error
+}
+
+// Watchlist is the set of monitored wallets, implemented by watchlist.Cache
+type Watchlist interface {
+	Watched(chainID int64, addr address.Address) *models.MonitoredWallet
+	Wallets(chainID int64) []*models.MonitoredWallet
+	OnChange(fn func())
+}
+
+// WalletMonitor keeps one log subscription per monitored wallet and one head
+// subscription for the native transfers of them all, so wallet activity is
+// alerted on as soon as it is mined even while block ingestion lags behind.
+type WalletMonitor struct {
+	monitor AddressMonitor
+	handle  func(*models.Transaction)
+
+	mu        sync.Mutex
+	listed    map[address.Address]bool         // wallets to follow
+	watching  map[address.Address]*walletWatch // address -> its subscription
+	transfers *walletWatch                     // nil while transfers aren't followed
+}
+
+// walletWatch is the subscription of one monitored wallet
+type walletWatch struct {
+	cancel context.CancelFunc
+}
+
+func NewWalletMonitor(monitor AddressMonitor, handle func(*models.Transaction)) *WalletMonitor {
+	return &WalletMonitor{
+		monitor:  monitor,
+		handle:   handle,
+		watching: make(map[address.Address]*walletWatch),
+	}
+}
+
+// Sync subscribes to the wallets not yet followed and stops following those
+// no longer listed; transfers are followed while any wallet is listed.
+// Subscriptions that fail, e.g. on a provider without subscriptions, or whose
+// monitoring stopped on its own are made again on the next Sync.
+func (m *WalletMonitor) Sync(ctx context.Context, wallets []*models.MonitoredWallet) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	listed := make(map[address.Address]bool, len(wallets))
+	for _, wallet := range wallets {
+		watched := wallet.Address
+		listed[watched] = true
+		if _, ok := m.watching[watched]; ok {
+			continue
+		}
+
+		walletCtx, cancel := context.WithCancel(ctx)
+		watch := &walletWatch{cancel: cancel}
+		if err := m.monitor.MonitorAddress(walletCtx, watched, m.handle, m.forget(watched, watch)); err != nil {
+			cancel()
+			log.Printf("Failed to monitor wallet %s: %v", watched, err)
+			continue
+		}
+		m.watching[watched] = watch
+	}
+
+	for watched, watch := range m.watching {
+		if !listed[watched] {
+			watch.cancel()
+			delete(m.watching, watched)
+		}
+	}
+
+	m.listed = listed
+	switch {
+	case len(listed) > 0 && m.transfers == nil:
+		transfersCtx, cancel := context.WithCancel(ctx)
+		watch := &walletWatch{cancel: cancel}
+		if err := m.monitor.MonitorTransfers(transfersCtx, m.isListed, m.handle, m.forgetTransfers(watch)); err != nil {
+			cancel()
+			log.Printf("Failed to monitor wallet transfers: %v", err)
+			return
+		}
+		m.transfers = watch
+	case len(listed) == 0 && m.transfers != nil:
+		m.transfers.cancel()
+		m.transfers = nil
+	}
+}
+
+// isListed reports whether a wallet is monitored, for matching transfers
+func (m *WalletMonitor) isListed(addr address.Address) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.listed[addr]
+}
+
+// forgetTransfers returns the onDone callback of the transfer subscription,
+// which drops it unless it has been made again since
+func (m *WalletMonitor) forgetTransfers(watch *walletWatch) func() {
+	return func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if m.transfers == watch {
+			watch.cancel()
+			m.transfers = nil
+		}
+	}
+}
+
+// forget returns the onDone callback of a wallet's subscription, which drops
+// the wallet unless it has been subscribed again since
+func (m *WalletMonitor) forget(watched address.Address, watch *walletWatch) func() {
+	return func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if m.watching[watched] == watch {
+			watch.cancel()
+			delete(m.watching, watched)
+		}
+	}
+}
+
+// Len returns the number of wallets followed
+func (m *WalletMonitor) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.watching)
+}
@@ -0,0 +1,141 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/minsix/backend/internal/address"
+	"github.com/minsix/backend/internal/models"
+)
+
+// fakeMonitor records the addresses it follows until their context ends
+type fakeMonitor struct {
+	mu       sync.Mutex
+	fail     map[address.Address]bool
+	contexts map[address.Address]context.Context
+	done     map[address.Address]func()
+
+	transfers     context.Context // of the last transfer subscription
+	watched       func(address.Address) bool
+	transfersDone func()
+}
+
+func (f *fakeMonitor) MonitorAddress(ctx context.Context, addr address.Address, txHandler func(*models.Transaction), onDone func()) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.fail[addr] {
+		return errors.New("subscriptions not supported")
+	}
+	f.contexts[addr] = ctx
+	f.done[addr] = onDone
+	return nil
+}
+
+func (f *fakeMonitor) MonitorTransfers(ctx context.Context, watched func(address.Address) bool, txHandler func(*models.Transaction), onDone func()) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.transfers = ctx
+	f.watched = watched
+	f.transfersDone = onDone
+	return nil
+}
+
+func TestWalletMonitorSync(t *testing.T) {
+	first := address.FromHex("0xabc0000000000000000000000000000000000001")
+	second := address.FromHex("0xabc0000000000000000000000000000000000002")
+	monitor := &fakeMonitor{fail: map[address.Address]bool{second: true}, contexts: map[address.Address]context.Context{}, done: map[address.Address]func(){}}
+	wallets := NewWalletMonitor(monitor, func(*models.Transaction) {})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	wallets.Sync(ctx, []*models.MonitoredWallet{{Address: address.FromHex("0xABC0000000000000000000000000000000000001")}, {Address: second}})
+	if wallets.Len() != 1 || monitor.contexts[first] == nil {
+		t.Fatalf("following %d wallets, want only %s", wallets.Len(), first)
+	}
+
+	// The failed wallet is retried, the followed one left alone
+	delete(monitor.fail, second)
+	firstCtx := monitor.contexts[first]
+	wallets.Sync(ctx, []*models.MonitoredWallet{{Address: first}, {Address: second}})
+	if wallets.Len() != 2 || monitor.contexts[first] != firstCtx {
+		t.Fatalf("following %d wallets after retry, want 2", wallets.Len())
+	}
+
+	// A removed wallet's subscription is stopped
+	wallets.Sync(ctx, []*models.MonitoredWallet{{Address: second}})
+	if wallets.Len() != 1 {
+		t.Fatalf("following %d wallets after removal, want 1", wallets.Len())
+	}
+	if firstCtx.Err() == nil {
+		t.Error("removed wallet is still followed")
+	}
+	if monitor.contexts[second].Err() != nil {
+		t.Error("remaining wallet was stopped")
+	}
+}
+
+func TestWalletMonitorStopped(t *testing.T) {
+	watched := address.FromHex("0xabc0000000000000000000000000000000000001")
+	monitor := &fakeMonitor{contexts: map[address.Address]context.Context{}, done: map[address.Address]func(){}}
+	wallets := NewWalletMonitor(monitor, func(*models.Transaction) {})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	list := []*models.MonitoredWallet{{Address: watched}}
+
+	// Monitoring that stops on its own is dropped and resubscribed
+	wallets.Sync(ctx, list)
+	stopped := monitor.done[watched]
+	stopped()
+	if wallets.Len() != 0 {
+		t.Fatalf("following %d wallets after monitoring stopped, want 0", wallets.Len())
+	}
+	wallets.Sync(ctx, list)
+	if wallets.Len() != 1 || monitor.contexts[watched].Err() != nil {
+		t.Fatal("stopped wallet was not subscribed again")
+	}
+
+	// A stale callback leaves the new subscription alone
+	stopped()
+	if wallets.Len() != 1 {
+		t.Error("stale onDone dropped the new subscription")
+	}
+}
+
+func TestWalletMonitorTransfers(t *testing.T) {
+	first := address.FromHex("0xabc0000000000000000000000000000000000001")
+	second := address.FromHex("0xabc0000000000000000000000000000000000002")
+	monitor := &fakeMonitor{contexts: map[address.Address]context.Context{}, done: map[address.Address]func(){}}
+	wallets := NewWalletMonitor(monitor, func(*models.Transaction) {})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// One transfer subscription follows every listed wallet
+	wallets.Sync(ctx, []*models.MonitoredWallet{{Address: first}})
+	transfers := monitor.transfers
+	if transfers == nil || !monitor.watched(first) || monitor.watched(second) {
+		t.Fatal("transfers of the listed wallet are not followed")
+	}
+	wallets.Sync(ctx, []*models.MonitoredWallet{{Address: first}, {Address: second}})
+	if monitor.transfers != transfers || !monitor.watched(second) {
+		t.Fatal("a new wallet's transfers are not followed on the same subscription")
+	}
+
+	// Transfers stop being followed with the last wallet
+	wallets.Sync(ctx, nil)
+	if transfers.Err() == nil {
+		t.Fatal("transfers are followed without wallets")
+	}
+
+	// Monitoring that stops on its own is made again on the next Sync
+	wallets.Sync(ctx, []*models.MonitoredWallet{{Address: first}})
+	monitor.transfersDone()
+	wallets.Sync(ctx, []*models.MonitoredWallet{{Address: first}})
+	if monitor.transfers.Err() != nil {
+		t.Error("stopped transfer monitoring was not made again")
+	}
+}
@@ -0,0 +1,94 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+)
+
+// StageStats summarizes the latency of one processing stage
+type StageStats struct {
+	Stage  string  `json:"stage"`
+	Runs   int64   `json:"runs"`  // batches processed, e.g. blocks
+	Items  int64   `json:"items"` // items across all runs, e.g. transactions
+	LastMS float64 `json:"last_ms"`
+	AvgMS  float64 `json:"avg_ms"`
+	MaxMS  float64 `json:"max_ms"`
+	Queued int     `json:"queued"` // runs waiting for the stage, when it has a queue
+}
+
+type stage struct {
+	runs, items int64
+	last, max   time.Duration
+	total       time.Duration
+}
+
+// Stages records per-stage latency for a processing pipeline; it is safe for
+// concurrent use
+type Stages struct {
+	mu     sync.Mutex
+	order  []string
+	stages map[string]*stage
+}
+
+// NewStages creates a recorder reporting the named stages in order; stages
+// observed later are appended
+func NewStages(names ...string) *Stages {
+	s := &Stages{stages: make(map[string]*stage)}
+	for _, name := range names {
+		s.get(name)
+	}
+	return s
+}
+
+// get returns a stage, adding it if needed; the caller must hold s.mu
+func (s *Stages) get(name string) *stage {
+	st, ok := s.stages[name]
+	if !ok {
+		st = &stage{}
+		s.stages[name] = st
+		s.order = append(s.order, name)
+	}
+	return st
+}
+
+// Observe records one run of a stage over items
+func (s *Stages) Observe(name string, elapsed time.Duration, items int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st := s.get(name)
+	st.runs++
+	st.items += int64(items)
+	st.last = elapsed
+	st.total += elapsed
+	if elapsed > st.max {
+		st.max = elapsed
+	}
+}
+
+// Snapshot returns the stats of every stage in order
+func (s *Stages) Snapshot() []StageStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := make([]StageStats, 0, len(s.order))
+	for _, name := range s.order {
+		st := s.stages[name]
+		stat := StageStats{
+			Stage:  name,
+			Runs:   st.runs,
+			Items:  st.items,
+			LastMS: milliseconds(st.last),
+			MaxMS:  milliseconds(st.max),
+		}
+		if st.runs > 0 {
+			stat.AvgMS = milliseconds(st.total / time.Duration(st.runs))
+		}
+		stats = append(stats, stat)
+	}
+	return stats
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
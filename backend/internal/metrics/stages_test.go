@@ -0,0 +1,26 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStages(t *testing.T) {
+	s := NewStages("fetch", "persist")
+	s.Observe("persist", 10*time.Millisecond, 100)
+	s.Observe("persist", 30*time.Millisecond, 50)
+	s.Observe("publish", time.Millisecond, 1)
+
+	stats := s.Snapshot()
+	if len(stats) != 3 || stats[0].Stage != "fetch" || stats[1].Stage != "persist" || stats[2].Stage != "publish" {
+		t.Fatalf("Snapshot() = %+v, want fetch, persist and publish in order", stats)
+	}
+	if stats[0].Runs != 0 || stats[0].AvgMS != 0 {
+		t.Errorf("unobserved stage = %+v", stats[0])
+	}
+
+	persist := stats[1]
+	if persist.Runs != 2 || persist.Items != 150 || persist.LastMS != 30 || persist.AvgMS != 20 || persist.MaxMS != 30 {
+		t.Errorf("persist = %+v", persist)
+	}
+}
@@ -1,52 +1,244 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
+
+	"github.com/minsix/backend/internal/address"
 )
 
 type Transaction struct {
-	ID          int       `json:"id"`
-	TxHash      string    `json:"tx_hash"`
-	BlockNumber int64     `json:"block_number"`
-	FromAddress string    `json:"from_address"`
-	ToAddress   *string   `json:"to_address"`
-	Value       string    `json:"value"`
-	GasPrice    string    `json:"gas_price"`
-	GasUsed     int64     `json:"gas_used"`
-	InputData   *string   `json:"input_data"`
-	Timestamp   time.Time `json:"timestamp"`
-	CreatedAt   time.Time `json:"created_at"`
+	ID                int               `json:"id"`
+	ChainID           int64             `json:"chain_id"`
+	TxHash            string            `json:"tx_hash"`
+	BlockNumber       int64             `json:"block_number"`
+	BlockHash         *string           `json:"block_hash"`
+	FromAddress       address.Address   `json:"from_address"`
+	ToAddress         *address.Address  `json:"to_address"`
+	Nonce             *uint64           `json:"nonce"`
+	Value             string            `json:"value"`
+	GasPrice          string            `json:"gas_price"`
+	GasUsed           int64             `json:"gas_used"`
+	Status            *string           `json:"status"`
+	EffectiveGasPrice *string           `json:"effective_gas_price"`
+	ContractAddress   *address.Address  `json:"contract_address"`
+	InputData         *string           `json:"input_data"`
+	Finality          string            `json:"finality"`
+	Timestamp         time.Time         `json:"timestamp"`
+	CreatedAt         time.Time         `json:"created_at"`
+	Logs              []*TransactionLog `json:"logs,omitempty"`
+	TokenTransfers    []*TokenTransfer  `json:"token_transfers,omitempty"`
+}
+
+// Block is the identity of a processed block, kept to detect reorganizations
+type Block struct {
+	ChainID    int64     `json:"chain_id"`
+	Number     uint64    `json:"number"`
+	Hash       string    `json:"hash"`
+	ParentHash string    `json:"parent_hash"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// Finality of a stored transaction under the confirmation policy
+const (
+	FinalityTentative = "tentative"
+	FinalityConfirmed = "confirmed"
+
+	// FinalityPending marks a mempool transaction that has not been mined; it
+	// is analyzed but never stored
+	FinalityPending = "pending"
+)
+
+// Receipt statuses stored on transactions
+const (
+	TxStatusSuccess  = "success"
+	TxStatusReverted = "reverted"
+)
+
+// TransactionLog is an event log emitted by a transaction, decoded when it is a known token event
+type TransactionLog struct {
+	ID            int               `json:"id"`
+	ChainID       int64             `json:"chain_id"`
+	TransactionID *int              `json:"transaction_id"`
+	TxHash        string            `json:"tx_hash"`
+	LogIndex      int               `json:"log_index"`
+	BlockNumber   int64             `json:"block_number"`
+	Address       address.Address   `json:"address"`
+	Topics        []string          `json:"topics"`
+	Data          string            `json:"data"`
+	Event         *string           `json:"event"`
+	Decoded       map[string]string `json:"decoded,omitempty"`
+}
+
+// TokenTransfer is an ERC-20 Transfer event; Amount is in raw token units
+type TokenTransfer struct {
+	ID            int             `json:"id"`
+	ChainID       int64           `json:"chain_id"`
+	TransactionID *int            `json:"transaction_id"`
+	TxHash        string          `json:"tx_hash"`
+	LogIndex      int             `json:"log_index"`
+	BlockNumber   int64           `json:"block_number"`
+	Token         address.Address `json:"token"`
+	FromAddress   address.Address `json:"from_address"`
+	ToAddress     address.Address `json:"to_address"`
+	Amount        string          `json:"amount"`
+	Timestamp     time.Time       `json:"timestamp"`
+}
+
+// Token is ERC-20 metadata used to scale raw amounts and price them
+type Token struct {
+	ChainID   int64           `json:"chain_id"`
+	Address   address.Address `json:"address"`
+	Symbol    string          `json:"symbol"`
+	Name      string          `json:"name"`
+	Decimals  int             `json:"decimals"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
 }
 
 type FlaggedTransaction struct {
-	ID            int       `json:"id"`
-	TransactionID *int      `json:"transaction_id"`
-	TxHash        string    `json:"tx_hash"`
-	RiskScore     int       `json:"risk_score"`
-	Reasons       []string  `json:"reasons"`
-	FlaggedAt     time.Time `json:"flagged_at"`
-	Status        string    `json:"status"`
+	ID            int          `json:"id"`
+	ChainID       int64        `json:"chain_id"`
+	TransactionID *int         `json:"transaction_id"`
+	TxHash        string       `json:"tx_hash"`
+	RiskScore     int          `json:"risk_score"`
+	Reasons       []string     `json:"reasons"`
+	ReasonDetails []FlagReason `json:"reason_details"`
+	FlaggedAt     time.Time    `json:"flagged_at"`
+	Status        string       `json:"status"`
 	Transaction   *Transaction `json:"transaction,omitempty"`
+
+	// WatchedWallets are the addresses of the monitored wallets involved
+	WatchedWallets []address.Address `json:"watched_wallets"`
+}
+
+// Severity levels attached to flag reasons
+const (
+	SeverityLow      = "low"
+	SeverityMedium   = "medium"
+	SeverityHigh     = "high"
+	SeverityCritical = "critical"
+)
+
+// FlagReason is the machine-readable form of a single reason a transaction was flagged
+type FlagReason struct {
+	RuleID      string                 `json:"rule_id"`
+	RuleVersion string                 `json:"rule_version"`
+	Score       int                    `json:"score"`
+	Severity    string                 `json:"severity"`
+	Message     string                 `json:"message"`
+	Evidence    map[string]interface{} `json:"evidence,omitempty"`
 }
 
 type BlacklistedAddress struct {
-	ID      int       `json:"id"`
-	Address string    `json:"address"`
-	Reason  string    `json:"reason"`
-	Source  string    `json:"source"`
-	AddedAt time.Time `json:"added_at"`
+	ID        int             `json:"id"`
+	ChainID   *int64          `json:"chain_id"` // nil applies to every chain
+	Address   address.Address `json:"address"`
+	Reason    string          `json:"reason"`
+	Source    string          `json:"source"`
+	Category  string          `json:"category"`
+	Severity  string          `json:"severity"`
+	ExpiresAt *time.Time      `json:"expires_at,omitempty"` // nil never expires
+	AddedAt   time.Time       `json:"added_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+	DeletedAt *time.Time      `json:"deleted_at,omitempty"` // set once removed
+}
+
+// Active reports whether the entry is neither removed nor expired at now
+func (b *BlacklistedAddress) Active(now time.Time) bool {
+	return b.DeletedAt == nil && (b.ExpiresAt == nil || b.ExpiresAt.After(now))
+}
+
+// Blacklist history actions
+const (
+	BlacklistAdded   = "added"
+	BlacklistUpdated = "updated"
+	BlacklistRemoved = "removed"
+)
+
+// BlacklistChange records a change to a blacklist entry with the entry as it
+// was afterwards
+type BlacklistChange struct {
+	ID        int             `json:"id"`
+	EntryID   int             `json:"entry_id"`
+	Action    string          `json:"action"`
+	Entry     json.RawMessage `json:"entry"`
+	ChangedAt time.Time       `json:"changed_at"`
+}
+
+// BlacklistImport summarizes an import of an address feed, diffed against
+// the feed's previous import
+type BlacklistImport struct {
+	ID         int       `json:"id"`
+	Source     string    `json:"source"`
+	Format     string    `json:"format"`
+	FileName   string    `json:"file_name"`
+	Checksum   string    `json:"checksum"` // SHA-256 of the feed file
+	ChainID    *int64    `json:"chain_id"`
+	Total      int       `json:"total"` // distinct valid addresses in the feed
+	Added      int       `json:"added"`
+	Removed    int       `json:"removed"`
+	Unchanged  int       `json:"unchanged"`
+	Invalid    int       `json:"invalid"`
+	Listed     int       `json:"listed"` // entries added to the blacklist
+	Pruned     int       `json:"pruned"` // entries removed from the blacklist
+	ImportedAt time.Time `json:"imported_at"`
+
+	// Details of this run only, not stored
+	DryRun           bool              `json:"dry_run,omitempty"`
+	AddedAddresses   []address.Address `json:"added_addresses,omitempty"`
+	RemovedAddresses []address.Address `json:"removed_addresses,omitempty"`
+	InvalidAddresses []string          `json:"invalid_addresses,omitempty"`
+}
+
+// AllowlistedAddress is a trusted counterparty, such as an exchange hot
+// wallet or a router, whose transactions score lower on the rules it covers
+type AllowlistedAddress struct {
+	ID          int             `json:"id"`
+	ChainID     *int64          `json:"chain_id"` // nil applies to every chain
+	Address     address.Address `json:"address"`
+	Label       string          `json:"label"`
+	Rules       []string        `json:"rules"`        // empty covers every rule
+	ScoreFactor float64         `json:"score_factor"` // 0 zeroes the covered rules out
+	Reason      string          `json:"reason"`
+	AddedAt     time.Time       `json:"added_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// Covers reports whether the entry applies to a rule
+func (a *AllowlistedAddress) Covers(rule string) bool {
+	if len(a.Rules) == 0 {
+		return true
+	}
+	for _, r := range a.Rules {
+		if r == rule {
+			return true
+		}
+	}
+	return false
+}
+
+type SuspectedDrainer struct {
+	ID          int             `json:"id"`
+	ChainID     int64           `json:"chain_id"`
+	Address     address.Address `json:"address"`
+	VictimCount int             `json:"victim_count"`
+	Reason      string          `json:"reason"`
+	EscalatedAt time.Time       `json:"escalated_at"`
 }
 
 type MonitoredWallet struct {
-	ID          int        `json:"id"`
-	Address     string     `json:"address"`
-	Label       *string    `json:"label"`
-	AddedAt     time.Time  `json:"added_at"`
-	LastChecked *time.Time `json:"last_checked"`
+	ID          int             `json:"id"`
+	ChainID     *int64          `json:"chain_id"` // nil watches every chain
+	Address     address.Address `json:"address"`
+	Label       *string         `json:"label"`
+	AddedAt     time.Time       `json:"added_at"`
+	LastChecked *time.Time      `json:"last_checked"`
 }
 
 type Statistics struct {
 	ID          int       `json:"id"`
+	ChainID     int64     `json:"chain_id"`
 	MetricName  string    `json:"metric_name"`
 	MetricValue float64   `json:"metric_value"`
 	UpdatedAt   time.Time `json:"updated_at"`
@@ -57,9 +249,54 @@ type WebSocketMessage struct {
 	Payload interface{} `json:"payload"`
 }
 
+// ReorgPayload describes blocks replaced by a chain reorganization
+type ReorgPayload struct {
+	ChainID        int64     `json:"chain_id"`
+	CommonAncestor uint64    `json:"common_ancestor"`
+	Orphaned       []*Block  `json:"orphaned"`
+	RolledBackTxs  int       `json:"rolled_back_transactions"`
+	ReorgedFlags   int       `json:"reorged_flags"`
+	Timestamp      time.Time `json:"timestamp"`
+}
+
+// Outcomes of a pending alert
+const (
+	PendingStatusPending  = "pending"
+	PendingStatusMined    = "mined"
+	PendingStatusReplaced = "replaced" // another transaction with the same sender and nonce was mined
+	PendingStatusDropped  = "dropped"  // evicted from the mempool
+)
+
+// PendingAlert is a risky mempool transaction, reported before it is mined
+// and tracked until it is mined, replaced or dropped
+type PendingAlert struct {
+	ChainID       int64            `json:"chain_id"`
+	TxHash        string           `json:"tx_hash"`
+	FromAddress   address.Address  `json:"from_address"`
+	ToAddress     *address.Address `json:"to_address"`
+	Nonce         uint64           `json:"nonce"`
+	RiskScore     int              `json:"risk_score"`
+	Reasons       []string         `json:"reasons"`
+	ReasonDetails []FlagReason     `json:"reason_details"`
+	Status        string           `json:"status"`
+	ReplacedBy    *string          `json:"replaced_by,omitempty"`
+	BlockNumber   int64            `json:"block_number,omitempty"`
+	SeenAt        time.Time        `json:"seen_at"`
+	ResolvedAt    *time.Time       `json:"resolved_at,omitempty"`
+}
+
+// WatchedAlertPayload is an alert on a transaction involving monitored wallets
+type WatchedAlertPayload struct {
+	AlertPayload
+	Wallets []*MonitoredWallet `json:"wallets"`
+}
+
 type AlertPayload struct {
-	TxHash    string   `json:"tx_hash"`
-	RiskScore int      `json:"risk_score"`
-	Reasons   []string `json:"reasons"`
-	Timestamp time.Time `json:"timestamp"`
+	ChainID       int64        `json:"chain_id"`
+	TxHash        string       `json:"tx_hash"`
+	RiskScore     int          `json:"risk_score"`
+	Reasons       []string     `json:"reasons"`
+	ReasonDetails []FlagReason `json:"reason_details"`
+	Confirmed     bool         `json:"confirmed"`
+	Timestamp     time.Time    `json:"timestamp"`
 }
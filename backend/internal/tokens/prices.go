@@ -0,0 +1,76 @@
+package tokens
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/minsix/backend/internal/address"
+	"github.com/minsix/backend/internal/models"
+)
+
+// PriceSource quotes the USD price of one whole unit of a token. The native
+// currency is passed as a token without an address, Native unless the
+// registry names another symbol.
+type PriceSource interface {
+	USDPrice(token *models.Token) (float64, bool)
+}
+
+// StaticPrices is a fixed price list keyed by token address or symbol
+type StaticPrices struct {
+	byAddress map[address.Address]float64
+	bySymbol  map[string]float64 // uppercase symbol -> USD
+}
+
+// NewStaticPrices builds a price list from address or symbol keys, e.g.
+// {"ETH": 3000, "0xA0b8...eB48": 1}
+func NewStaticPrices(prices map[string]float64) (*StaticPrices, error) {
+	s := &StaticPrices{
+		byAddress: make(map[address.Address]float64),
+		bySymbol:  make(map[string]float64),
+	}
+	for key, price := range prices {
+		if price < 0 {
+			return nil, fmt.Errorf("price for %s must not be negative", key)
+		}
+		if strings.HasPrefix(key, "0x") {
+			token, err := address.Parse(key)
+			if err != nil {
+				return nil, fmt.Errorf("invalid token price: %w", err)
+			}
+			s.byAddress[token] = price
+		} else {
+			s.bySymbol[strings.ToUpper(key)] = price
+		}
+	}
+	return s, nil
+}
+
+// LoadStaticPrices reads a JSON object of prices from a file
+func LoadStaticPrices(path string) (*StaticPrices, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read price file: %w", err)
+	}
+
+	var prices map[string]float64
+	if err := json.Unmarshal(data, &prices); err != nil {
+		return nil, fmt.Errorf("failed to parse price file %s: %w", path, err)
+	}
+	return NewStaticPrices(prices)
+}
+
+// USDPrice prefers an address match so a token cannot borrow the price of a
+// better-known token by reusing its symbol
+func (s *StaticPrices) USDPrice(token *models.Token) (float64, bool) {
+	if token.Address != "" {
+		if price, ok := s.byAddress[token.Address]; ok {
+			return price, true
+		}
+		// Only the native currency may be priced by symbol alone
+		return 0, false
+	}
+	price, ok := s.bySymbol[strings.ToUpper(token.Symbol)]
+	return price, ok
+}
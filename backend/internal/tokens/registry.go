@@ -0,0 +1,173 @@
+package tokens
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/minsix/backend/internal/address"
+	"github.com/minsix/backend/internal/models"
+)
+
+// MaxDecimals is the largest decimals value that still fits a uint256 amount
+const MaxDecimals = 77
+
+// Native describes the default native currency, priced under its symbol
+var Native = &models.Token{Symbol: "ETH", Name: "Ether", Decimals: 18}
+
+// Registry holds token metadata in memory and values amounts through a price source
+type Registry struct {
+	mu     sync.RWMutex
+	tokens map[address.Address]*models.Token
+	native *models.Token
+	prices PriceSource
+}
+
+// NewRegistry creates an empty registry; prices may be nil, in which case
+// nothing can be valued in USD
+func NewRegistry(prices PriceSource) *Registry {
+	return &Registry{
+		tokens: make(map[address.Address]*models.Token),
+		native: Native,
+		prices: prices,
+	}
+}
+
+// SetNativeSymbol names the chain's native currency, e.g. POL on Polygon, so
+// it is priced under that symbol; native currencies have 18 decimals
+func (r *Registry) SetNativeSymbol(symbol string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.native = &models.Token{Symbol: strings.ToUpper(symbol), Name: symbol, Decimals: 18}
+}
+
+// Validate normalizes a token's address to its canonical form and checks its metadata
+func Validate(token *models.Token) error {
+	tokenAddress, err := address.Parse(token.Address.String())
+	if err != nil {
+		return fmt.Errorf("invalid token: %w", err)
+	}
+	token.Address = tokenAddress
+	token.Symbol = strings.TrimSpace(token.Symbol)
+	if token.Symbol == "" {
+		return fmt.Errorf("token %s: symbol is required", token.Address)
+	}
+	if token.Decimals < 0 || token.Decimals > MaxDecimals {
+		return fmt.Errorf("token %s: decimals must be between 0 and %d", token.Address, MaxDecimals)
+	}
+	return nil
+}
+
+// LoadFile reads a JSON array of tokens, such as the bundled config/tokens.json
+func LoadFile(path string) ([]*models.Token, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token list: %w", err)
+	}
+
+	var list []*models.Token
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse token list %s: %w", path, err)
+	}
+
+	var errs []error
+	for _, token := range list {
+		if err := Validate(token); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid token list %s: %w", path, errors.Join(errs...))
+	}
+	return list, nil
+}
+
+// Load replaces the registry contents
+func (r *Registry) Load(list []*models.Token) {
+	tokens := make(map[address.Address]*models.Token, len(list))
+	for _, token := range list {
+		tokens[token.Address] = token
+	}
+
+	r.mu.Lock()
+	r.tokens = tokens
+	r.mu.Unlock()
+}
+
+// Put adds or replaces a single token
+func (r *Registry) Put(token *models.Token) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tokens[token.Address] = token
+}
+
+// Remove drops a token from the registry
+func (r *Registry) Remove(token address.Address) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.tokens, token)
+}
+
+// Lookup returns the metadata for a token address
+func (r *Registry) Lookup(tokenAddress address.Address) (*models.Token, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	token, ok := r.tokens[tokenAddress]
+	return token, ok
+}
+
+// SetPriceSource swaps the price source, e.g. after reloading a price file
+func (r *Registry) SetPriceSource(prices PriceSource) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.prices = prices
+}
+
+func (r *Registry) priceSource() PriceSource {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.prices
+}
+
+// NativeUSDValue converts a wei amount to USD
+func (r *Registry) NativeUSDValue(wei *big.Int) (float64, bool) {
+	r.mu.RLock()
+	native := r.native
+	r.mu.RUnlock()
+	return r.usdValue(native, wei)
+}
+
+// TokenUSDValue converts a raw token amount to USD; it reports false for
+// tokens without metadata or a price
+func (r *Registry) TokenUSDValue(token address.Address, amount *big.Int) (float64, bool) {
+	metadata, ok := r.Lookup(token)
+	if !ok {
+		return 0, false
+	}
+	return r.usdValue(metadata, amount)
+}
+
+func (r *Registry) usdValue(token *models.Token, amount *big.Int) (float64, bool) {
+	prices := r.priceSource()
+	if prices == nil || amount == nil {
+		return 0, false
+	}
+	price, ok := prices.USDPrice(token)
+	if !ok {
+		return 0, false
+	}
+
+	value := new(big.Float).Mul(Scale(amount, token.Decimals), big.NewFloat(price))
+	usd, _ := value.Float64()
+	return usd, true
+}
+
+// Scale converts a raw amount to whole token units
+func Scale(amount *big.Int, decimals int) *big.Float {
+	unit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+	return new(big.Float).Quo(new(big.Float).SetInt(amount), new(big.Float).SetInt(unit))
+}
@@ -0,0 +1,98 @@
+package tokens
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/minsix/backend/internal/address"
+	"github.com/minsix/backend/internal/models"
+)
+
+func TestRegistryUSDValue(t *testing.T) {
+	const usdc = "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48"
+	prices, err := NewStaticPrices(map[string]float64{"eth": 2000, "POL": 0.5, usdc: 1})
+	if err != nil {
+		t.Fatalf("NewStaticPrices() error = %v", err)
+	}
+
+	r := NewRegistry(prices)
+	r.Load([]*models.Token{
+		{Address: address.MustParse(usdc), Symbol: "USDC", Decimals: 6},
+		{Address: "0x1234567890123456789012345678901234567890", Symbol: "ETH", Decimals: 18},
+	})
+
+	if usd, ok := r.NativeUSDValue(big.NewInt(5e17)); !ok || usd != 1000 {
+		t.Errorf("NativeUSDValue(0.5 ETH) = %v, %v, want 1000", usd, ok)
+	}
+	if usd, ok := r.TokenUSDValue("0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48", big.NewInt(2500000000)); !ok || usd != 2500 {
+		t.Errorf("TokenUSDValue(2500 USDC) = %v, %v, want 2500", usd, ok)
+	}
+	// A token reusing the native symbol is not priced as ETH
+	if _, ok := r.TokenUSDValue("0x1234567890123456789012345678901234567890", big.NewInt(1e18)); ok {
+		t.Error("TokenUSDValue() priced a token by symbol")
+	}
+	if _, ok := r.TokenUSDValue("0x6B175474E89094C44Da98b954EedeAC495271d0F", big.NewInt(1)); ok {
+		t.Error("TokenUSDValue() priced an unregistered token")
+	}
+
+	r.Remove(address.MustParse(usdc))
+	if _, ok := r.Lookup(address.MustParse(usdc)); ok {
+		t.Error("Lookup() found a removed token")
+	}
+	r.SetNativeSymbol("pol")
+	if usd, ok := r.NativeUSDValue(big.NewInt(4e18)); !ok || usd != 2 {
+		t.Errorf("NativeUSDValue(4 POL) = %v, %v, want 2", usd, ok)
+	}
+	if _, ok := NewRegistry(nil).NativeUSDValue(big.NewInt(1)); ok {
+		t.Error("NativeUSDValue() without a price source should report false")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	token := &models.Token{Address: "0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48", Symbol: " USDC ", Decimals: 6}
+	if err := Validate(token); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if token.Address != "0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48" || token.Symbol != "USDC" {
+		t.Errorf("Validate() normalized to %+v", token)
+	}
+
+	invalid := []*models.Token{
+		{Address: "0x1234", Symbol: "BAD", Decimals: 18},
+		{Address: "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48", Decimals: 6},
+		{Address: "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48", Symbol: "BAD", Decimals: 78},
+	}
+	for _, token := range invalid {
+		if err := Validate(token); err == nil {
+			t.Errorf("Validate(%+v) should fail", token)
+		}
+	}
+}
+
+func TestBundledFiles(t *testing.T) {
+	list, err := LoadFile("../../config/tokens.json")
+	if err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+	prices, err := LoadStaticPrices("../../config/prices.json")
+	if err != nil {
+		t.Fatalf("LoadStaticPrices() error = %v", err)
+	}
+	for _, token := range list {
+		if _, ok := prices.USDPrice(token); !ok {
+			t.Errorf("bundled token %s has no price", token.Symbol)
+		}
+	}
+}
+
+func TestLoadFileRejectsInvalidTokens(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	if err := os.WriteFile(path, []byte(`[{"address": "0xnope", "symbol": "X", "decimals": 18}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFile(path); err == nil {
+		t.Error("LoadFile() should reject an invalid address")
+	}
+}
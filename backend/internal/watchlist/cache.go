@@ -0,0 +1,128 @@
+package watchlist
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/minsix/backend/internal/address"
+	"github.com/minsix/backend/internal/database"
+	"github.com/minsix/backend/internal/models"
+)
+
+// Source loads the monitored wallets; chainID 0 returns every wallet.
+// Implemented by database.DB.
+type Source interface {
+	GetMonitoredWallets(chainID int64) ([]*models.MonitoredWallet, error)
+}
+
+// Cache keeps the monitored wallets in memory so the detector can check every
+// transaction without querying the database. It implements
+// detector.WalletWatcher and is safe for concurrent use.
+type Cache struct {
+	source Source
+
+	mu       sync.RWMutex
+	wallets  map[address.Address][]*models.MonitoredWallet // address -> wallets, replaced on reload
+	onChange []func()
+}
+
+// NewCache creates an empty cache; call Load before the first lookup
+func NewCache(source Source) *Cache {
+	return &Cache{
+		source:  source,
+		wallets: map[address.Address][]*models.MonitoredWallet{},
+	}
+}
+
+// OnChange registers a callback run after every load, e.g. to start and stop
+// monitoring the wallets; call it before Watch
+func (c *Cache) OnChange(fn func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onChange = append(c.onChange, fn)
+}
+
+// Load replaces the cached wallets with the source's current ones
+func (c *Cache) Load() error {
+	wallets, err := c.source.GetMonitoredWallets(0)
+	if err != nil {
+		return fmt.Errorf("failed to load monitored wallets: %w", err)
+	}
+
+	next := make(map[address.Address][]*models.MonitoredWallet, len(wallets))
+	for _, wallet := range wallets {
+		next[wallet.Address] = append(next[wallet.Address], wallet)
+	}
+
+	c.mu.Lock()
+	c.wallets = next
+	onChange := c.onChange
+	c.mu.Unlock()
+
+	for _, fn := range onChange {
+		fn()
+	}
+	return nil
+}
+
+// Watched returns the wallet monitored at an address on a chain, either for
+// that chain alone or for every chain, or nil if it isn't monitored; chainID
+// 0 matches any chain. A wallet monitored for the chain itself wins.
+func (c *Cache) Watched(chainID int64, addr address.Address) *models.MonitoredWallet {
+	c.mu.RLock()
+	wallets := c.wallets[addr]
+	c.mu.RUnlock()
+
+	var match *models.MonitoredWallet
+	for _, wallet := range wallets {
+		if wallet.ChainID == nil {
+			if match == nil {
+				match = wallet
+			}
+			continue
+		}
+		if chainID == 0 || *wallet.ChainID == chainID {
+			match = wallet
+		}
+	}
+	return match
+}
+
+// Wallets returns the wallets monitored on a chain, including those monitored
+// on every chain
+func (c *Cache) Wallets(chainID int64) []*models.MonitoredWallet {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var wallets []*models.MonitoredWallet
+	for _, entries := range c.wallets {
+		for _, wallet := range entries {
+			if wallet.ChainID == nil || *wallet.ChainID == chainID {
+				wallets = append(wallets, wallet)
+			}
+		}
+	}
+	return wallets
+}
+
+// Len returns the number of distinct monitored addresses
+func (c *Cache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.wallets)
+}
+
+// Watch reloads the wallets whenever monitored_wallets changes, and every
+// database.RefreshInterval in case a notification was lost, until ctx is
+// cancelled
+func (c *Cache) Watch(ctx context.Context, notifier database.Notifier) error {
+	return database.WatchReload(ctx, notifier, database.WalletsChannel, func() error {
+		if err := c.Load(); err != nil {
+			return err
+		}
+		log.Printf("Monitored wallets refreshed, %d addresses", c.Len())
+		return nil
+	})
+}
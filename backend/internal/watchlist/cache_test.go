@@ -0,0 +1,87 @@
+package watchlist
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/minsix/backend/internal/address"
+	"github.com/minsix/backend/internal/database"
+	"github.com/minsix/backend/internal/database/dbtest"
+	"github.com/minsix/backend/internal/models"
+)
+
+type fakeSource struct {
+	*dbtest.List[*models.MonitoredWallet]
+}
+
+func (f fakeSource) GetMonitoredWallets(chainID int64) ([]*models.MonitoredWallet, error) {
+	return f.Load()
+}
+
+func TestCacheWatched(t *testing.T) {
+	cache := NewCache(fakeSource{dbtest.NewList(
+		&models.MonitoredWallet{ID: 1, Address: "0xabc0000000000000000000000000000000000001"},
+		&models.MonitoredWallet{ID: 2, Address: "0xabc0000000000000000000000000000000000001", ChainID: dbtest.ChainID(137)},
+		&models.MonitoredWallet{ID: 3, Address: "0xabc0000000000000000000000000000000000002", ChainID: dbtest.ChainID(1)},
+	)})
+	if err := cache.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		chainID int64
+		address address.Address
+		want    int // wallet ID, 0 for none
+	}{
+		{1, "0xabc0000000000000000000000000000000000001", 1},
+		{137, address.FromHex("0xABC0000000000000000000000000000000000001"), 2},
+		{1, "0xabc0000000000000000000000000000000000002", 3},
+		{137, "0xabc0000000000000000000000000000000000002", 0},
+		{0, "0xabc0000000000000000000000000000000000002", 3},
+		{1, "0xabc0000000000000000000000000000000000003", 0},
+	}
+	for _, tt := range tests {
+		got := 0
+		if wallet := cache.Watched(tt.chainID, tt.address); wallet != nil {
+			got = wallet.ID
+		}
+		if got != tt.want {
+			t.Errorf("Watched(%d, %s) = wallet %d, want %d", tt.chainID, tt.address, got, tt.want)
+		}
+	}
+
+	if got := len(cache.Wallets(1)); got != 2 {
+		t.Errorf("Wallets(1) returned %d wallets, want 2", got)
+	}
+	if got := len(cache.Wallets(137)); got != 2 {
+		t.Errorf("Wallets(137) returned %d wallets, want 2", got)
+	}
+}
+
+func TestCacheWatch(t *testing.T) {
+	source := fakeSource{dbtest.NewList[*models.MonitoredWallet]()}
+	cache := NewCache(source)
+	notifier := &dbtest.Notifier{}
+
+	changed := make(chan int, 1)
+	cache.OnChange(func() { changed <- cache.Len() })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := cache.Watch(ctx, notifier); err != nil {
+		t.Fatal(err)
+	}
+
+	source.Set(&models.MonitoredWallet{Address: "0xabc0000000000000000000000000000000000001"})
+	notifier.Notify(database.WalletsChannel, "INSERT")
+
+	select {
+	case n := <-changed:
+		if n != 1 {
+			t.Errorf("OnChange saw %d wallets, want 1", n)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("watchlist was not reloaded after a notification")
+	}
+}
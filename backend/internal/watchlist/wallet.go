@@ -0,0 +1,37 @@
+package watchlist
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/minsix/backend/internal/address"
+	"github.com/minsix/backend/internal/models"
+)
+
+// MaxLabelLength is the column size of monitored_wallets.label
+const MaxLabelLength = 255
+
+// Validate checks a wallet submitted for monitoring and normalizes it: the
+// address to its canonical form and a blank label to none
+func Validate(wallet *models.MonitoredWallet) error {
+	watched, err := address.Parse(wallet.Address.String())
+	if err != nil {
+		return err
+	}
+	wallet.Address = watched
+	if wallet.ChainID != nil && *wallet.ChainID <= 0 {
+		return fmt.Errorf("invalid chain_id %d", *wallet.ChainID)
+	}
+
+	if wallet.Label != nil {
+		label := strings.TrimSpace(*wallet.Label)
+		if len(label) > MaxLabelLength {
+			return fmt.Errorf("label must be at most %d characters", MaxLabelLength)
+		}
+		wallet.Label = &label
+		if label == "" {
+			wallet.Label = nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package watchlist
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/minsix/backend/internal/database/dbtest"
+	"github.com/minsix/backend/internal/models"
+)
+
+func TestValidate(t *testing.T) {
+	zero := int64(0)
+	long := strings.Repeat("a", MaxLabelLength+1)
+
+	tests := []struct {
+		name    string
+		wallet  models.MonitoredWallet
+		wantErr bool
+	}{
+		{"every chain", models.MonitoredWallet{Address: "0x28C6c06298d514Db089934071355E5743bf21d60"}, false},
+		{"one chain", models.MonitoredWallet{Address: "0x28c6c06298d514db089934071355e5743bf21d60", ChainID: dbtest.ChainID(137)}, false},
+		{"bad address", models.MonitoredWallet{Address: "0x28c6"}, true},
+		{"bad chain", models.MonitoredWallet{Address: "0x28c6c06298d514db089934071355e5743bf21d60", ChainID: &zero}, true},
+		{"long label", models.MonitoredWallet{Address: "0x28c6c06298d514db089934071355e5743bf21d60", Label: &long}, true},
+	}
+	for _, tt := range tests {
+		wallet := tt.wallet
+		err := Validate(&wallet)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	label := " Treasury "
+	wallet := models.MonitoredWallet{Address: "0x28C6c06298d514Db089934071355E5743bf21d60", Label: &label}
+	if err := Validate(&wallet); err != nil {
+		t.Fatal(err)
+	}
+	if wallet.Address != "0x28c6c06298d514db089934071355e5743bf21d60" || wallet.Label == nil || *wallet.Label != "Treasury" {
+		t.Errorf("wallet not normalized: %+v", wallet)
+	}
+
+	blank := "  "
+	wallet.Label = &blank
+	if err := Validate(&wallet); err != nil || wallet.Label != nil {
+		t.Errorf("blank label = %v, %v, want none", wallet.Label, err)
+	}
+}
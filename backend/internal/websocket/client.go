@@ -15,9 +15,12 @@ const (
 )
 
 type Client struct {
-	hub  *Hub
-	conn *websocket.Conn
-	send chan []byte
+	hub    *Hub
+	conn   *websocket.Conn
+	send   chan []byte
+	chains map[int64]bool // nil receives every chain
+
+	watchedOnly bool
 }
 
 func NewClient(hub *Hub, conn *websocket.Conn) *Client {
@@ -28,6 +31,33 @@ func NewClient(hub *Hub, conn *websocket.Conn) *Client {
 	}
 }
 
+// FilterChains limits the client to messages about the given chains; call it
+// before registering the client
+func (c *Client) FilterChains(chainIDs []int64) {
+	if len(chainIDs) == 0 {
+		c.chains = nil
+		return
+	}
+	c.chains = make(map[int64]bool, len(chainIDs))
+	for _, id := range chainIDs {
+		c.chains[id] = true
+	}
+}
+
+// FilterWatched limits the client to alerts on monitored wallets; call it
+// before registering the client
+func (c *Client) FilterWatched() {
+	c.watchedOnly = true
+}
+
+// wants reports whether a message should be sent to the client
+func (c *Client) wants(msg message) bool {
+	if c.watchedOnly && !msg.watched {
+		return false
+	}
+	return msg.chainID == 0 || c.chains == nil || c.chains[msg.chainID]
+}
+
 // ReadPump pumps messages from the websocket connection to the hub
 func (c *Client) ReadPump() {
 	defer func() {
@@ -8,10 +8,19 @@ import (
 	"github.com/minsix/backend/internal/models"
 )
 
+// message is an encoded broadcast; chainID is 0 for messages about every
+// chain, and watched marks alerts on monitored wallets
+type message struct {
+	chainID int64
+	watched bool
+	data    []byte
+}
+
 // Hub maintains the set of active clients and broadcasts messages
 type Hub struct {
 	clients    map[*Client]bool
-	broadcast  chan []byte
+	broadcast  chan message
+	priority   chan message // sent before anything waiting in broadcast
 	register   chan *Client
 	unregister chan *Client
 	mu         sync.RWMutex
@@ -20,7 +29,8 @@ type Hub struct {
 func NewHub() *Hub {
 	return &Hub{
 		clients:    make(map[*Client]bool),
-		broadcast:  make(chan []byte, 256),
+		broadcast:  make(chan message, 256),
+		priority:   make(chan message, 256),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
 	}
@@ -29,6 +39,14 @@ func NewHub() *Hub {
 // Run starts the hub
 func (h *Hub) Run() {
 	for {
+		// Priority messages skip ahead of queued broadcasts
+		select {
+		case msg := <-h.priority:
+			h.send(msg)
+			continue
+		default:
+		}
+
 		select {
 		case client := <-h.register:
 			h.mu.Lock()
@@ -45,30 +63,46 @@ func (h *Hub) Run() {
 			}
 			h.mu.Unlock()
 
-		case message := <-h.broadcast:
-			h.mu.RLock()
-			for client := range h.clients {
-				select {
-				case client.send <- message:
-				default:
-					close(client.send)
-					delete(h.clients, client)
-				}
-			}
-			h.mu.RUnlock()
+		case msg := <-h.priority:
+			h.send(msg)
+
+		case msg := <-h.broadcast:
+			h.send(msg)
+		}
+	}
+}
+
+// send delivers a message to the clients that want it, dropping clients
+// that can't keep up
+func (h *Hub) send(msg message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for client := range h.clients {
+		if !client.wants(msg) {
+			continue
+		}
+		select {
+		case client.send <- msg.data:
+		default:
+			close(client.send)
+			delete(h.clients, client)
 		}
 	}
 }
 
-// BroadcastAlert sends an alert to all connected clients
-func (h *Hub) BroadcastAlert(flagged *models.FlaggedTransaction) {
+// BroadcastAlert sends an alert to all connected clients; confirmed is false
+// for alerts on transactions that have not reached the confirmation depth
+func (h *Hub) BroadcastAlert(flagged *models.FlaggedTransaction, confirmed bool) {
 	msg := models.WebSocketMessage{
 		Type: "fraud_alert",
 		Payload: models.AlertPayload{
-			TxHash:    flagged.TxHash,
-			RiskScore: flagged.RiskScore,
-			Reasons:   flagged.Reasons,
-			Timestamp: flagged.FlaggedAt,
+			ChainID:       flagged.ChainID,
+			TxHash:        flagged.TxHash,
+			RiskScore:     flagged.RiskScore,
+			Reasons:       flagged.Reasons,
+			ReasonDetails: flagged.ReasonDetails,
+			Confirmed:     confirmed,
+			Timestamp:     flagged.FlaggedAt,
 		},
 	}
 
@@ -78,7 +112,36 @@ func (h *Hub) BroadcastAlert(flagged *models.FlaggedTransaction) {
 		return
 	}
 
-	h.broadcast <- data
+	h.broadcast <- message{chainID: flagged.ChainID, data: data}
+}
+
+// BroadcastWatchedAlert sends an alert on a transaction involving monitored
+// wallets ahead of other messages, to every client including those following
+// only monitored wallets
+func (h *Hub) BroadcastWatchedAlert(flagged *models.FlaggedTransaction, wallets []*models.MonitoredWallet, confirmed bool) {
+	msg := models.WebSocketMessage{
+		Type: "watched_wallet_alert",
+		Payload: models.WatchedAlertPayload{
+			AlertPayload: models.AlertPayload{
+				ChainID:       flagged.ChainID,
+				TxHash:        flagged.TxHash,
+				RiskScore:     flagged.RiskScore,
+				Reasons:       flagged.Reasons,
+				ReasonDetails: flagged.ReasonDetails,
+				Confirmed:     confirmed,
+				Timestamp:     flagged.FlaggedAt,
+			},
+			Wallets: wallets,
+		},
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("Failed to marshal watched wallet alert: %v", err)
+		return
+	}
+
+	h.priority <- message{chainID: flagged.ChainID, watched: true, data: data}
 }
 
 // BroadcastTransaction sends a new transaction to all connected clients
@@ -94,7 +157,50 @@ func (h *Hub) BroadcastTransaction(tx *models.Transaction) {
 		return
 	}
 
-	h.broadcast <- data
+	h.broadcast <- message{chainID: tx.ChainID, data: data}
+}
+
+// BroadcastReorg tells clients that blocks were replaced and their flags reorged
+func (h *Hub) BroadcastReorg(reorg *models.ReorgPayload) {
+	msg := models.WebSocketMessage{
+		Type:    "reorg",
+		Payload: reorg,
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("Failed to marshal reorg: %v", err)
+		return
+	}
+
+	h.broadcast <- message{chainID: reorg.ChainID, data: data}
+}
+
+// BroadcastPendingAlert sends an alert on a risky transaction that has not
+// been mined yet
+func (h *Hub) BroadcastPendingAlert(alert *models.PendingAlert) {
+	h.broadcastPending("pending_alert", alert)
+}
+
+// BroadcastPendingUpdate tells clients a pending alert's transaction was
+// mined, replaced or dropped
+func (h *Hub) BroadcastPendingUpdate(alert *models.PendingAlert) {
+	h.broadcastPending("pending_update", alert)
+}
+
+func (h *Hub) broadcastPending(msgType string, alert *models.PendingAlert) {
+	msg := models.WebSocketMessage{
+		Type:    msgType,
+		Payload: alert,
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("Failed to marshal pending alert: %v", err)
+		return
+	}
+
+	h.broadcast <- message{chainID: alert.ChainID, data: data}
 }
 
 // BroadcastStats sends updated statistics to all connected clients
@@ -110,7 +216,7 @@ func (h *Hub) BroadcastStats(stats map[string]float64) {
 		return
 	}
 
-	h.broadcast <- data
+	h.broadcast <- message{data: data}
 }
 
 // Register adds a client to the hub
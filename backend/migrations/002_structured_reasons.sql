@@ -0,0 +1,13 @@
+-- Machine-readable flag reasons with rule metadata and evidence
+ALTER TABLE flagged_transactions
+    ADD COLUMN IF NOT EXISTS reason_details JSONB NOT NULL DEFAULT '[]'::jsonb;
+
+CREATE INDEX IF NOT EXISTS idx_reason_details ON flagged_transactions USING GIN (reason_details);
+
+-- Carry over reasons recorded before structured details existed
+UPDATE flagged_transactions
+SET reason_details = (
+    SELECT jsonb_agg(jsonb_build_object('rule_id', 'legacy', 'rule_version', '', 'message', reason))
+    FROM unnest(reasons) AS reason
+)
+WHERE reason_details = '[]'::jsonb AND cardinality(reasons) > 0;
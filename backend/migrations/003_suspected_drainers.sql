@@ -0,0 +1,10 @@
+-- Operators escalated after receiving setApprovalForAll from many victims
+CREATE TABLE IF NOT EXISTS suspected_drainers (
+    id SERIAL PRIMARY KEY,
+    address VARCHAR(42) UNIQUE NOT NULL,
+    victim_count INTEGER NOT NULL,
+    reason TEXT NOT NULL,
+    escalated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_suspected_drainer_address ON suspected_drainers(address);
@@ -0,0 +1,23 @@
+-- Receipt fields captured at ingestion
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS status VARCHAR(10) CHECK (status IN ('success', 'reverted'));
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS effective_gas_price NUMERIC(78, 0);
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS contract_address VARCHAR(42);
+
+-- Event logs from transaction receipts
+CREATE TABLE IF NOT EXISTS transaction_logs (
+    id SERIAL PRIMARY KEY,
+    transaction_id INTEGER REFERENCES transactions(id) ON DELETE CASCADE,
+    tx_hash VARCHAR(66) NOT NULL,
+    log_index INTEGER NOT NULL,
+    block_number BIGINT NOT NULL,
+    address VARCHAR(42) NOT NULL,
+    topics TEXT[] NOT NULL,
+    data TEXT NOT NULL,
+    event VARCHAR(64),
+    decoded JSONB,
+    UNIQUE (tx_hash, log_index)
+);
+
+CREATE INDEX IF NOT EXISTS idx_log_transaction_id ON transaction_logs(transaction_id);
+CREATE INDEX IF NOT EXISTS idx_log_address ON transaction_logs(address);
+CREATE INDEX IF NOT EXISTS idx_log_event ON transaction_logs(event);
@@ -0,0 +1,19 @@
+-- ERC-20 token movements parsed from Transfer logs
+CREATE TABLE IF NOT EXISTS token_transfers (
+    id SERIAL PRIMARY KEY,
+    transaction_id INTEGER REFERENCES transactions(id) ON DELETE CASCADE,
+    tx_hash VARCHAR(66) NOT NULL,
+    log_index INTEGER NOT NULL,
+    block_number BIGINT NOT NULL,
+    token VARCHAR(42) NOT NULL,
+    from_address VARCHAR(42) NOT NULL,
+    to_address VARCHAR(42) NOT NULL,
+    amount NUMERIC(78, 0) NOT NULL,
+    timestamp TIMESTAMP NOT NULL,
+    UNIQUE (tx_hash, log_index)
+);
+
+CREATE INDEX IF NOT EXISTS idx_token_transfer_from ON token_transfers(from_address);
+CREATE INDEX IF NOT EXISTS idx_token_transfer_to ON token_transfers(to_address);
+CREATE INDEX IF NOT EXISTS idx_token_transfer_token ON token_transfers(token);
+CREATE INDEX IF NOT EXISTS idx_token_transfer_timestamp ON token_transfers(timestamp);
@@ -0,0 +1,11 @@
+-- ERC-20 metadata, seeded from config/tokens.json and extendable through the API
+CREATE TABLE IF NOT EXISTS tokens (
+    address VARCHAR(42) PRIMARY KEY,
+    symbol VARCHAR(32) NOT NULL,
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    decimals SMALLINT NOT NULL CHECK (decimals BETWEEN 0 AND 77),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_token_symbol ON tokens(symbol);
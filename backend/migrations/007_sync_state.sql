@@ -0,0 +1,6 @@
+-- Last fully processed block per network, used to resume ingestion after a restart
+CREATE TABLE IF NOT EXISTS sync_state (
+    network VARCHAR(64) PRIMARY KEY,
+    last_block BIGINT NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
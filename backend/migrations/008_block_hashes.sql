@@ -0,0 +1,27 @@
+-- Block identity for reorg detection
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS block_hash VARCHAR(66);
+CREATE INDEX IF NOT EXISTS idx_block_hash ON transactions(block_hash);
+
+CREATE TABLE IF NOT EXISTS blocks (
+    network VARCHAR(64) NOT NULL,
+    number BIGINT NOT NULL,
+    hash VARCHAR(66) NOT NULL,
+    parent_hash VARCHAR(66) NOT NULL,
+    timestamp TIMESTAMP NOT NULL,
+    PRIMARY KEY (network, number)
+);
+
+-- Flags of transactions rolled back by a reorg are kept with status 'reorged'.
+-- The constraint is replaced once, since adding it revalidates the table.
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint
+        WHERE conname = 'flagged_transactions_status_check'
+          AND pg_get_constraintdef(oid) LIKE '%reorged%'
+    ) THEN
+        ALTER TABLE flagged_transactions DROP CONSTRAINT IF EXISTS flagged_transactions_status_check;
+        ALTER TABLE flagged_transactions ADD CONSTRAINT flagged_transactions_status_check
+            CHECK (status IN ('pending', 'reviewed', 'false_positive', 'confirmed', 'reorged'));
+    END IF;
+END $$;
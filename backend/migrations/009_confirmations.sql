@@ -0,0 +1,4 @@
+-- Transactions are tentative until they reach the configured confirmation depth
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS finality VARCHAR(10) NOT NULL DEFAULT 'confirmed'
+    CHECK (finality IN ('tentative', 'confirmed'));
+CREATE INDEX IF NOT EXISTS idx_tentative_block ON transactions(block_number) WHERE finality = 'tentative';
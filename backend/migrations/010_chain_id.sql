@@ -0,0 +1,106 @@
+-- Scope every table by chain so one server can ingest several chains.
+-- Each table is converted once, the first time this runs; rows stored before
+-- then belong to the chain recorded in sync_state, or mainnet if none was.
+DO $$
+DECLARE
+    legacy BIGINT := 1;
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'sync_state' AND column_name = 'network') THEN
+        SELECT network::bigint INTO legacy FROM sync_state
+        WHERE network ~ '^[0-9]+$'
+        ORDER BY updated_at DESC
+        LIMIT 1;
+        legacy := COALESCE(legacy, 1);
+    END IF;
+
+    -- Ingested data: unique per chain instead of globally
+    IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'transactions' AND column_name = 'chain_id') THEN
+        ALTER TABLE transactions ADD COLUMN chain_id BIGINT;
+        UPDATE transactions SET chain_id = legacy;
+        ALTER TABLE transactions ALTER COLUMN chain_id SET NOT NULL;
+        ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_tx_hash_key;
+    END IF;
+
+    IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'transaction_logs' AND column_name = 'chain_id') THEN
+        ALTER TABLE transaction_logs ADD COLUMN chain_id BIGINT;
+        UPDATE transaction_logs SET chain_id = legacy;
+        ALTER TABLE transaction_logs ALTER COLUMN chain_id SET NOT NULL;
+        ALTER TABLE transaction_logs DROP CONSTRAINT IF EXISTS transaction_logs_tx_hash_log_index_key;
+    END IF;
+
+    IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'token_transfers' AND column_name = 'chain_id') THEN
+        ALTER TABLE token_transfers ADD COLUMN chain_id BIGINT;
+        UPDATE token_transfers SET chain_id = legacy;
+        ALTER TABLE token_transfers ALTER COLUMN chain_id SET NOT NULL;
+        ALTER TABLE token_transfers DROP CONSTRAINT IF EXISTS token_transfers_tx_hash_log_index_key;
+    END IF;
+
+    IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'flagged_transactions' AND column_name = 'chain_id') THEN
+        ALTER TABLE flagged_transactions ADD COLUMN chain_id BIGINT;
+        UPDATE flagged_transactions SET chain_id = legacy;
+        ALTER TABLE flagged_transactions ALTER COLUMN chain_id SET NOT NULL;
+    END IF;
+
+    IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'suspected_drainers' AND column_name = 'chain_id') THEN
+        ALTER TABLE suspected_drainers ADD COLUMN chain_id BIGINT;
+        UPDATE suspected_drainers SET chain_id = legacy;
+        ALTER TABLE suspected_drainers ALTER COLUMN chain_id SET NOT NULL;
+        ALTER TABLE suspected_drainers DROP CONSTRAINT IF EXISTS suspected_drainers_address_key;
+    END IF;
+
+    IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'tokens' AND column_name = 'chain_id') THEN
+        ALTER TABLE tokens ADD COLUMN chain_id BIGINT;
+        UPDATE tokens SET chain_id = legacy;
+        ALTER TABLE tokens ALTER COLUMN chain_id SET NOT NULL;
+        ALTER TABLE tokens DROP CONSTRAINT IF EXISTS tokens_pkey;
+        ALTER TABLE tokens ADD PRIMARY KEY (chain_id, address);
+    END IF;
+
+    -- Checkpoints and block identity: chain_id replaces the network column
+    IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'sync_state' AND column_name = 'network') THEN
+        ALTER TABLE sync_state ADD COLUMN chain_id BIGINT;
+        UPDATE sync_state SET chain_id = network::bigint WHERE network ~ '^[0-9]+$';
+        DELETE FROM sync_state WHERE chain_id IS NULL;
+        ALTER TABLE sync_state DROP CONSTRAINT IF EXISTS sync_state_pkey;
+        ALTER TABLE sync_state DROP COLUMN network;
+        ALTER TABLE sync_state ADD PRIMARY KEY (chain_id);
+    END IF;
+
+    IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'blocks' AND column_name = 'network') THEN
+        ALTER TABLE blocks ADD COLUMN chain_id BIGINT;
+        UPDATE blocks SET chain_id = network::bigint WHERE network ~ '^[0-9]+$';
+        DELETE FROM blocks WHERE chain_id IS NULL;
+        ALTER TABLE blocks DROP CONSTRAINT IF EXISTS blocks_pkey;
+        ALTER TABLE blocks DROP COLUMN network;
+        ALTER TABLE blocks ADD PRIMARY KEY (chain_id, number);
+    END IF;
+
+    -- Lists where NULL means every chain
+    IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'blacklisted_addresses' AND column_name = 'chain_id') THEN
+        ALTER TABLE blacklisted_addresses ADD COLUMN chain_id BIGINT;
+        ALTER TABLE blacklisted_addresses DROP CONSTRAINT IF EXISTS blacklisted_addresses_address_key;
+    END IF;
+
+    IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'monitored_wallets' AND column_name = 'chain_id') THEN
+        ALTER TABLE monitored_wallets ADD COLUMN chain_id BIGINT;
+        ALTER TABLE monitored_wallets DROP CONSTRAINT IF EXISTS monitored_wallets_address_key;
+    END IF;
+
+    -- Counters per chain; the totals are summed across chains
+    IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'statistics' AND column_name = 'chain_id') THEN
+        ALTER TABLE statistics ADD COLUMN chain_id BIGINT;
+        UPDATE statistics SET chain_id = legacy;
+        ALTER TABLE statistics ALTER COLUMN chain_id SET DEFAULT 0;
+        ALTER TABLE statistics ALTER COLUMN chain_id SET NOT NULL;
+        ALTER TABLE statistics DROP CONSTRAINT IF EXISTS statistics_metric_name_key;
+    END IF;
+END $$;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_transaction_chain_hash ON transactions(chain_id, tx_hash);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_log_chain_hash_index ON transaction_logs(chain_id, tx_hash, log_index);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_token_transfer_chain_hash_index ON token_transfers(chain_id, tx_hash, log_index);
+CREATE INDEX IF NOT EXISTS idx_flagged_chain ON flagged_transactions(chain_id, flagged_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_suspected_drainer_chain_address ON suspected_drainers(chain_id, address);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_blacklist_chain_address ON blacklisted_addresses(COALESCE(chain_id, 0), address);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_wallet_chain_address ON monitored_wallets(COALESCE(chain_id, 0), address);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_statistics_chain_metric ON statistics(chain_id, metric_name);
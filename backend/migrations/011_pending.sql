@@ -0,0 +1,3 @@
+-- Sender nonce, used to tell which mempool transaction a mined one replaced;
+-- NULL for transactions stored before it was recorded
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS nonce BIGINT;
@@ -0,0 +1,13 @@
+-- Notify listeners such as the detector's in-memory blacklist whenever the
+-- blacklist changes; once per statement so bulk imports send one notification
+CREATE OR REPLACE FUNCTION notify_blacklist_changed() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('blacklist_changed', TG_OP);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS blacklist_changed ON blacklisted_addresses;
+CREATE TRIGGER blacklist_changed
+    AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON blacklisted_addresses
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_blacklist_changed();
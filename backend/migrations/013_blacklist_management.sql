@@ -0,0 +1,20 @@
+-- Provenance, expiry and soft deletion of blacklist entries. Removed entries
+-- keep their row with deleted_at set; re-adding the address restores it.
+ALTER TABLE blacklisted_addresses ADD COLUMN IF NOT EXISTS category VARCHAR(50) NOT NULL DEFAULT '';
+ALTER TABLE blacklisted_addresses ADD COLUMN IF NOT EXISTS severity VARCHAR(20) NOT NULL DEFAULT 'high';
+ALTER TABLE blacklisted_addresses ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP;
+ALTER TABLE blacklisted_addresses ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE blacklisted_addresses ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_blacklist_active ON blacklisted_addresses(added_at) WHERE deleted_at IS NULL;
+
+-- Every change to an entry with the entry as it was afterwards
+CREATE TABLE IF NOT EXISTS blacklist_history (
+    id SERIAL PRIMARY KEY,
+    entry_id INTEGER NOT NULL REFERENCES blacklisted_addresses(id) ON DELETE CASCADE,
+    action VARCHAR(20) NOT NULL,
+    entry JSONB NOT NULL,
+    changed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_blacklist_history_entry ON blacklist_history(entry_id, changed_at);
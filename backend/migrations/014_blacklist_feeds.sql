@@ -0,0 +1,34 @@
+-- Addresses each imported feed lists, kept after they drop out of the feed
+-- so successive imports can be diffed
+CREATE TABLE IF NOT EXISTS blacklist_feed_entries (
+    id SERIAL PRIMARY KEY,
+    source VARCHAR(100) NOT NULL,
+    address VARCHAR(42) NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    first_seen_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    removed_at TIMESTAMP,
+    UNIQUE (source, address)
+);
+
+CREATE INDEX IF NOT EXISTS idx_blacklist_feed_address ON blacklist_feed_entries(address) WHERE removed_at IS NULL;
+
+-- One row per feed import
+CREATE TABLE IF NOT EXISTS blacklist_imports (
+    id SERIAL PRIMARY KEY,
+    source VARCHAR(100) NOT NULL,
+    format VARCHAR(20) NOT NULL,
+    file_name TEXT NOT NULL DEFAULT '',
+    checksum CHAR(64) NOT NULL,
+    chain_id BIGINT,
+    total INTEGER NOT NULL DEFAULT 0,
+    added INTEGER NOT NULL DEFAULT 0,
+    removed INTEGER NOT NULL DEFAULT 0,
+    unchanged INTEGER NOT NULL DEFAULT 0,
+    invalid INTEGER NOT NULL DEFAULT 0,
+    listed INTEGER NOT NULL DEFAULT 0,
+    pruned INTEGER NOT NULL DEFAULT 0,
+    imported_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_blacklist_imports_source ON blacklist_imports(source, imported_at);
@@ -0,0 +1,12 @@
+-- Scope feed entries by chain like the blacklist itself, so importing a feed
+-- for one chain doesn't diff against or prune its import for another.
+-- Entries stored before then were imported for every chain.
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'blacklist_feed_entries' AND column_name = 'chain_id') THEN
+        ALTER TABLE blacklist_feed_entries ADD COLUMN chain_id BIGINT;
+        ALTER TABLE blacklist_feed_entries DROP CONSTRAINT IF EXISTS blacklist_feed_entries_source_address_key;
+    END IF;
+END $$;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_blacklist_feed_source_chain_address ON blacklist_feed_entries(source, COALESCE(chain_id, 0), address);
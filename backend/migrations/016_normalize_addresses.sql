@@ -0,0 +1,68 @@
+-- Store every address in canonical form: 0x followed by lowercase hex.
+-- Each table is converted once, the first time this runs; a check constraint
+-- then keeps differently cased addresses from being stored again.
+DO $$
+BEGIN
+    -- Ingested data
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'transactions_address_lowercase') THEN
+        UPDATE transactions
+        SET from_address = LOWER(from_address), to_address = LOWER(to_address), contract_address = LOWER(contract_address)
+        WHERE from_address <> LOWER(from_address) OR to_address <> LOWER(to_address) OR contract_address <> LOWER(contract_address);
+        ALTER TABLE transactions ADD CONSTRAINT transactions_address_lowercase CHECK (
+            from_address = LOWER(from_address) AND to_address = LOWER(to_address) AND contract_address = LOWER(contract_address)
+        );
+    END IF;
+
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'transaction_logs_address_lowercase') THEN
+        UPDATE transaction_logs SET address = LOWER(address) WHERE address <> LOWER(address);
+        ALTER TABLE transaction_logs ADD CONSTRAINT transaction_logs_address_lowercase CHECK (address = LOWER(address));
+    END IF;
+
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'token_transfers_address_lowercase') THEN
+        UPDATE token_transfers
+        SET token = LOWER(token), from_address = LOWER(from_address), to_address = LOWER(to_address)
+        WHERE token <> LOWER(token) OR from_address <> LOWER(from_address) OR to_address <> LOWER(to_address);
+        ALTER TABLE token_transfers ADD CONSTRAINT token_transfers_address_lowercase CHECK (
+            token = LOWER(token) AND from_address = LOWER(from_address) AND to_address = LOWER(to_address)
+        );
+    END IF;
+
+    -- Reference data: drop rows that only differ by case, keeping one
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'tokens_address_lowercase') THEN
+        DELETE FROM tokens t USING tokens o
+        WHERE t.chain_id = o.chain_id AND LOWER(t.address) = LOWER(o.address) AND t.ctid > o.ctid;
+        UPDATE tokens SET address = LOWER(address) WHERE address <> LOWER(address);
+        ALTER TABLE tokens ADD CONSTRAINT tokens_address_lowercase CHECK (address = LOWER(address));
+    END IF;
+
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'blacklisted_addresses_address_lowercase') THEN
+        DELETE FROM blacklisted_addresses b USING blacklisted_addresses o
+        WHERE COALESCE(b.chain_id, 0) = COALESCE(o.chain_id, 0) AND LOWER(b.address) = LOWER(o.address) AND b.id > o.id;
+        UPDATE blacklisted_addresses SET address = LOWER(address) WHERE address <> LOWER(address);
+        ALTER TABLE blacklisted_addresses ADD CONSTRAINT blacklisted_addresses_address_lowercase CHECK (address = LOWER(address));
+    END IF;
+
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'monitored_wallets_address_lowercase') THEN
+        DELETE FROM monitored_wallets w USING monitored_wallets o
+        WHERE COALESCE(w.chain_id, 0) = COALESCE(o.chain_id, 0) AND LOWER(w.address) = LOWER(o.address) AND w.id > o.id;
+        UPDATE monitored_wallets SET address = LOWER(address) WHERE address <> LOWER(address);
+        ALTER TABLE monitored_wallets ADD CONSTRAINT monitored_wallets_address_lowercase CHECK (address = LOWER(address));
+    END IF;
+
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'suspected_drainers_address_lowercase') THEN
+        UPDATE suspected_drainers d SET victim_count = o.victim_count
+        FROM suspected_drainers o
+        WHERE d.chain_id = o.chain_id AND LOWER(d.address) = LOWER(o.address) AND o.victim_count > d.victim_count;
+        DELETE FROM suspected_drainers d USING suspected_drainers o
+        WHERE d.chain_id = o.chain_id AND LOWER(d.address) = LOWER(o.address) AND d.id > o.id;
+        UPDATE suspected_drainers SET address = LOWER(address) WHERE address <> LOWER(address);
+        ALTER TABLE suspected_drainers ADD CONSTRAINT suspected_drainers_address_lowercase CHECK (address = LOWER(address));
+    END IF;
+
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'blacklist_feed_entries_address_lowercase') THEN
+        DELETE FROM blacklist_feed_entries f USING blacklist_feed_entries o
+        WHERE f.source = o.source AND COALESCE(f.chain_id, 0) = COALESCE(o.chain_id, 0) AND LOWER(f.address) = LOWER(o.address) AND f.id > o.id;
+        UPDATE blacklist_feed_entries SET address = LOWER(address) WHERE address <> LOWER(address);
+        ALTER TABLE blacklist_feed_entries ADD CONSTRAINT blacklist_feed_entries_address_lowercase CHECK (address = LOWER(address));
+    END IF;
+END $$;
@@ -0,0 +1,29 @@
+-- Trusted counterparties such as exchange hot wallets and routers. An entry
+-- scales the score of the listed rules, or of every rule when rules is
+-- empty, for transactions it takes part in; a factor of 0 zeroes them out.
+CREATE TABLE IF NOT EXISTS allowlisted_addresses (
+    id SERIAL PRIMARY KEY,
+    chain_id BIGINT,
+    address VARCHAR(42) NOT NULL CHECK (address = LOWER(address)),
+    label VARCHAR(255) NOT NULL,
+    rules TEXT[] NOT NULL DEFAULT '{}',
+    score_factor DOUBLE PRECISION NOT NULL DEFAULT 0 CHECK (score_factor >= 0 AND score_factor < 1),
+    reason TEXT NOT NULL DEFAULT '',
+    added_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_allowlist_chain_address ON allowlisted_addresses(COALESCE(chain_id, 0), address);
+
+-- Notify the detectors' in-memory allowlist, as for the blacklist
+CREATE OR REPLACE FUNCTION notify_allowlist_changed() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('allowlist_changed', TG_OP);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS allowlist_changed ON allowlisted_addresses;
+CREATE TRIGGER allowlist_changed
+    AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON allowlisted_addresses
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_allowlist_changed();
@@ -0,0 +1,17 @@
+-- Notify the detectors' in-memory watchlist when wallets are added, relabeled
+-- or removed; recording last_checked doesn't notify
+CREATE OR REPLACE FUNCTION notify_wallets_changed() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('wallets_changed', TG_OP);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS wallets_changed ON monitored_wallets;
+CREATE TRIGGER wallets_changed
+    AFTER INSERT OR DELETE OR TRUNCATE OR UPDATE OF chain_id, address, label ON monitored_wallets
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_wallets_changed();
+
+-- Flags record the monitored wallets they involve, so their alerts stay on
+-- the watched-wallet stream when confirmed later
+ALTER TABLE flagged_transactions ADD COLUMN IF NOT EXISTS watched_wallets TEXT[] NOT NULL DEFAULT '{}';
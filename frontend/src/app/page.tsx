@@ -39,7 +39,17 @@ export default function Home() {
             // Add new flagged transaction to the top
             fetchFlaggedTransactions()
             break
+
+          case 'watched_wallet_alert':
+            // Sent alongside fraud_alert, ahead of other alerts
+            fetchFlaggedTransactions()
+            break
           
+          case 'reorg':
+            // Flags from orphaned blocks are now marked as reorged
+            fetchFlaggedTransactions()
+            break
+
           case 'stats_update':
             setStatistics(message.payload as Statistics)
             break
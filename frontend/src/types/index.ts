@@ -1,26 +1,41 @@
 export interface Transaction {
   id: number
+  chain_id: number
   tx_hash: string
   block_number: number
   from_address: string
   to_address: string | null
+  nonce: number | null
   value: string
   gas_price: string
   gas_used: number
+  finality?: 'tentative' | 'confirmed'
   timestamp: string
 }
 
 export interface FlaggedTransaction {
   id: number
+  chain_id: number
   transaction_id: number | null
   tx_hash: string
   risk_score: number
   reasons: string[]
+  reason_details: FlagReason[]
   flagged_at: string
-  status: 'pending' | 'reviewed' | 'false_positive' | 'confirmed'
+  status: 'pending' | 'reviewed' | 'false_positive' | 'confirmed' | 'reorged'
+  watched_wallets: string[]
   transaction?: Transaction
 }
 
+export interface FlagReason {
+  rule_id: string
+  rule_version: string
+  score: number
+  severity: 'low' | 'medium' | 'high' | 'critical'
+  message: string
+  evidence?: Record<string, unknown>
+}
+
 export interface Statistics {
   total_transactions: number
   total_flagged: number
@@ -28,14 +43,109 @@ export interface Statistics {
   confirmed_fraud: number
 }
 
+export interface Chain {
+  chain_id: number
+  name: string
+}
+
 export interface WebSocketMessage {
   type: string
   payload: any
 }
 
 export interface AlertPayload {
+  chain_id: number
   tx_hash: string
   risk_score: number
   reasons: string[]
+  reason_details: FlagReason[]
+  confirmed: boolean
   timestamp: string
 }
+
+export interface PendingAlert {
+  chain_id: number
+  tx_hash: string
+  from_address: string
+  to_address: string | null
+  nonce: number
+  risk_score: number
+  reasons: string[]
+  reason_details: FlagReason[]
+  status: 'pending' | 'mined' | 'replaced' | 'dropped'
+  replaced_by?: string
+  block_number?: number
+  seen_at: string
+  resolved_at?: string
+}
+
+export interface BlacklistEntry {
+  id: number
+  chain_id: number | null
+  address: string
+  reason: string
+  source: string
+  category: string
+  severity: 'low' | 'medium' | 'high' | 'critical'
+  expires_at?: string
+  added_at: string
+  updated_at: string
+  deleted_at?: string
+}
+
+export interface BlacklistPage {
+  entries: BlacklistEntry[]
+  total: number
+  limit: number
+  offset: number
+}
+
+export interface BlacklistChange {
+  id: number
+  entry_id: number
+  action: 'added' | 'updated' | 'removed'
+  entry: BlacklistEntry
+  changed_at: string
+}
+
+export interface BlacklistImport {
+  id: number
+  source: string
+  format: 'ofac-xml' | 'ofac-csv' | 'list' | 'json'
+  file_name: string
+  checksum: string
+  chain_id: number | null
+  total: number
+  added: number
+  removed: number
+  unchanged: number
+  invalid: number
+  listed: number
+  pruned: number
+  imported_at: string
+  dry_run?: boolean
+  added_addresses?: string[]
+  removed_addresses?: string[]
+  invalid_addresses?: string[]
+}
+
+export interface AllowlistEntry {
+  id: number
+  chain_id: number | null
+  address: string
+  label: string
+  rules: string[]
+  score_factor: number
+  reason: string
+  added_at: string
+  updated_at: string
+}
+
+export interface MonitoredWallet {
+  id: number
+  chain_id: number | null
+  address: string
+  label: string | null
+  added_at: string
+  last_checked: string | null
+}
@@ -19,7 +19,7 @@ cd backend
 if [ ! -f .env ]; then
     echo "Creating .env file from example..."
     cp .env.example .env
-    echo "NOTE: Please edit backend/.env and add your ALCHEMY_API_KEY"
+    echo "NOTE: Please edit backend/.env and set RPC_URL or add your ALCHEMY_API_KEY"
 fi
 
 echo "Installing Go dependencies..."
@@ -62,6 +62,6 @@ echo ""
 echo "Setup complete"
 echo ""
 echo "Next steps:"
-echo "1. Edit backend/.env and add your ALCHEMY_API_KEY"
+echo "1. Edit backend/.env and set RPC_URL or add your ALCHEMY_API_KEY"
 echo "2. Run 'npm run dev:all' to start all services"
 echo ""